@@ -0,0 +1,600 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"openproject-crawler/internal/config"
+	"openproject-crawler/internal/credential"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/internal/workerpool"
+	"openproject-crawler/pkg/analytics"
+	"openproject-crawler/pkg/sqlitesink"
+	"openproject-crawler/pkg/wpquery"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitAuth        = 3
+	exitPartial     = 4
+	exitInterrupted = 130
+)
+
+type options struct {
+	profile     *config.Profile
+	project     string
+	filters     string
+	format      string
+	timeout     time.Duration
+	incremental bool
+	resume      bool
+	subprojects bool
+	from        time.Time
+	to          time.Time
+	users       []int
+	version     string
+	sinks       []config.Output
+	cassette    *httpclient.Cassette
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, crawler *Crawler, opts *options) (*result, error)
+}
+
+// result is what a command produced. Every sink renders it in its own format;
+// commands without csv or jsonl rows or a store cannot use those formats.
+type result struct {
+	value interface{}
+	table func(w io.Writer) error
+	csv   func(w io.Writer, sink config.Output) error
+	jsonl func(w io.Writer, sink config.Output) error
+	store func(ctx context.Context, db *sqlitesink.Sink) error
+	// dot and mermaid render graphs.
+	dot     func(w io.Writer) error
+	mermaid func(w io.Writer) error
+}
+
+var commands = []command{
+	{"projects list", "List projects visible to the credential", runProjectsList},
+	{"projects tree", "Show projects with their subprojects", runProjectsTree},
+	{"workpackages list", "List work packages, optionally of one project", runWorkPackagesList},
+	{"activities crawl", "Crawl and merge the activities of a project's work packages", runActivitiesCrawl},
+	{"statuses list", "List work package statuses", runStatusesList},
+	{"stats", "Lead time, cycle time and work package counts for a project", runStats},
+	{"time report", "Logged hours per user, work package, activity and week", runTimeReport},
+	{"users list", "List users (requires an admin account)", runUsersList},
+	{"groups list", "List groups and their members", runGroupsList},
+	{"memberships list", "List project members and their roles, optionally of one project", runMembershipsList},
+	{"relations graph", "Dependency graph of a project's work packages, with cycles and the critical path", runRelationsGraph},
+	{"versions list", "List versions, optionally the ones available in one project", runVersionsList},
+	{"version burndown", "Daily scope, done and remaining work of a version, rebuilt from its history", runVersionBurndown},
+}
+
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// partialError marks a command that produced output but did not finish,
+// e.g. because some tasks failed or the crawl was interrupted.
+type partialError struct {
+	err error
+}
+
+func (e *partialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	cmd, rest, err := findCommand(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n\n", err)
+		printUsage(stderr)
+		return exitUsage
+	}
+	if cmd == nil {
+		printUsage(stdout)
+		return exitOK
+	}
+
+	opts, err := parseOptions(cmd.name, rest, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	crawler, err := newCrawlerFromOptions(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitCode(err)
+	}
+
+	res, err := cmd.run(ctx, crawler, opts)
+	if res != nil {
+		if writeErr := writeSinks(ctx, opts.sinks, res, stdout); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+	}
+	return exitCode(err)
+}
+
+func findCommand(args []string) (*command, []string, error) {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return nil, nil, nil
+	}
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == commands[i].name {
+			return &commands[i], args[len(words):], nil
+		}
+	}
+	return nil, nil, &usageError{msg: fmt.Sprintf("unknown command %q", strings.Join(args, " "))}
+}
+
+// parseOptions merges settings with flags taking precedence over environment
+// variables, which take precedence over the selected config profile.
+func parseOptions(name string, args []string, stderr io.Writer) (*options, error) {
+	opts := &options{}
+	var (
+		configPath      string
+		profileName     string
+		url             string
+		credentialsFile string
+		credentialsCmd  string
+		output          string
+		columns         string
+		records         string
+		timezone        string
+		stateDir        string
+		cassetteDir     string
+		cassetteMode    string
+		cassetteMatch   string
+		cassetteIgnore  string
+		from            string
+		to              string
+		users           string
+		startStatuses   string
+		endStatuses     string
+		closePolicy     string
+		concurrency     int
+		pageSize        int
+		rateLimit       float64
+	)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&configPath, "config", "", "config file (env "+config.EnvConfig+", default "+config.DefaultPath()+")")
+	fs.StringVar(&profileName, "profile", "", "config profile to use (env "+config.EnvProfile+")")
+	fs.StringVar(&url, "url", "", "OpenProject API URL ending in /api/v3 (env "+config.EnvURL+")")
+	fs.StringVar(&opts.project, "project", "", "project ID, identifier or name (env "+config.EnvProject+")")
+	fs.StringVar(&opts.filters, "filters", "", "extra work package filters as a JSON array")
+	fs.BoolVar(&opts.subprojects, "include-subprojects", false, "also crawl the work packages of the project's subprojects")
+	fs.StringVar(&opts.format, "format", "json", "output format: "+strings.Join(config.OutputFormats, ", "))
+	fs.StringVar(&output, "output", "", "write output to this file instead of stdout; the database file for sqlite")
+	fs.StringVar(&columns, "columns", "", "comma-separated csv columns, default all")
+	fs.StringVar(&records, "records", "", "rows to export as csv or jsonl: "+strings.Join(config.OutputRecords, ", ")+" (default tasks)")
+	fs.StringVar(&credentialsFile, "credentials-file", "", "file holding an API key or key=value credential lines")
+	fs.StringVar(&credentialsCmd, "credentials-command", "", "command printing an API key or key=value credential lines")
+	fs.StringVar(&timezone, "timezone", "", "IANA time zone for local timestamps (env "+config.EnvTimezone+")")
+	fs.IntVar(&concurrency, "concurrency", workerpool.DefaultSize, "maximum concurrent requests (env "+config.EnvConcurrency+")")
+	fs.IntVar(&pageSize, "page-size", httpclient.DefaultPageSize, "page size for collection requests (env "+config.EnvPageSize+")")
+	fs.Float64Var(&rateLimit, "rate-limit", httpclient.DefaultRequestsPerSecond, "maximum requests per second to the instance")
+	fs.StringVar(&from, "from", "", "first day of time entries or of a burndown to report, YYYY-MM-DD")
+	fs.StringVar(&to, "to", "", "last day of time entries or of a burndown to report, YYYY-MM-DD")
+	fs.StringVar(&users, "user", "", "comma-separated user IDs whose time entries to report")
+	fs.StringVar(&opts.version, "version", "", "version ID, or version name together with --project")
+	fs.StringVar(&startStatuses, "start-statuses", "", "comma-separated statuses that start the cycle time in stats (default \""+strings.Join(analytics.DefaultStartStatuses, ",")+"\")")
+	fs.StringVar(&endStatuses, "end-statuses", "", "comma-separated statuses that end lead and cycle time in stats (default the closed statuses)")
+	fs.StringVar(&closePolicy, "close-policy", "", "which close of a reopened task sets its closed date: "+strings.Join(config.ClosePolicies, " or ")+" (default first)")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "abort the crawl after this long, e.g. 10m")
+	fs.BoolVar(&opts.incremental, "incremental", false, "only fetch work packages updated since the project's last checkpoint")
+	fs.BoolVar(&opts.resume, "resume", false, "skip tasks completed by an interrupted crawl and retry the ones that failed")
+	fs.StringVar(&cassetteDir, "cassette", "", "record HTTP traffic into, or replay it from, this directory")
+	fs.StringVar(&cassetteMode, "cassette-mode", string(httpclient.ModeReplay), "cassette mode: record, replay or passthrough")
+	fs.StringVar(&cassetteMatch, "cassette-match", "method,path,query", "request parts a replayed request must match")
+	fs.StringVar(&cassetteIgnore, "cassette-ignore", "", "comma-separated query parameters to ignore when matching recorded requests")
+	fs.StringVar(&stateDir, "state-dir", "", "checkpoint directory for --incremental and crawl progress (env "+config.EnvStateDir+", default "+config.DefaultStateDir()+")")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: openproject-crawler %s [flags]\n\nFlags:\n", name)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, &usageError{msg: fmt.Sprintf("unexpected arguments: %s", strings.Join(fs.Args(), " "))}
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	profile, err := loadProfile(configPath, profileName)
+	if err != nil {
+		return nil, &usageError{msg: err.Error()}
+	}
+	if set["url"] {
+		profile.APIURL = url
+	}
+	if set["project"] || profile.Project == "" {
+		profile.Project = opts.project
+	}
+	if set["credentials-file"] && set["credentials-command"] {
+		return nil, &usageError{msg: "--credentials-file and --credentials-command are mutually exclusive"}
+	}
+	if set["credentials-file"] {
+		profile.Credentials = config.CredentialSource{File: credentialsFile}
+	}
+	if set["credentials-command"] {
+		profile.Credentials = config.CredentialSource{Command: credentialsCmd}
+	}
+	if set["timezone"] {
+		profile.Timezone = timezone
+	}
+	if set["state-dir"] {
+		profile.StateDir = stateDir
+	}
+	if profile.StateDir == "" {
+		profile.StateDir = config.DefaultStateDir()
+	}
+	if set["concurrency"] || profile.Concurrency == 0 {
+		profile.Concurrency = concurrency
+	}
+	if set["page-size"] || profile.PageSize == 0 {
+		profile.PageSize = pageSize
+	}
+	if set["rate-limit"] || profile.RateLimit == 0 {
+		profile.RateLimit = rateLimit
+	}
+	if set["close-policy"] {
+		if !contains(config.ClosePolicies, closePolicy) {
+			return nil, &usageError{msg: fmt.Sprintf("--close-policy must be one of %s, got %q", strings.Join(config.ClosePolicies, ", "), closePolicy)}
+		}
+		profile.ClosePolicy = closePolicy
+	}
+	if set["start-statuses"] {
+		if profile.Analytics.StartStatuses, err = parseList("--start-statuses", startStatuses); err != nil {
+			return nil, err
+		}
+	}
+	if set["end-statuses"] {
+		if profile.Analytics.EndStatuses, err = parseList("--end-statuses", endStatuses); err != nil {
+			return nil, err
+		}
+	}
+	opts.profile = profile
+	opts.project = profile.Project
+
+	if set["format"] || set["output"] || set["columns"] || set["records"] || len(profile.Outputs) == 0 {
+		sink := config.Output{Format: opts.format, Path: output, Records: records}
+		if columns != "" {
+			sink.Columns = strings.Split(columns, ",")
+			for i := range sink.Columns {
+				sink.Columns[i] = strings.TrimSpace(sink.Columns[i])
+			}
+		}
+		opts.sinks = []config.Output{sink}
+	} else {
+		opts.sinks = profile.Outputs
+	}
+
+	if profile.APIURL == "" {
+		return nil, &usageError{msg: "--url, " + config.EnvURL + " or a config profile api_url is required"}
+	}
+	for _, sink := range opts.sinks {
+		if !contains(config.OutputFormats, sink.Format) {
+			return nil, &usageError{msg: fmt.Sprintf("unsupported format %q", sink.Format)}
+		}
+		if sink.Records != "" && !contains(config.OutputRecords, sink.Records) {
+			return nil, &usageError{msg: fmt.Sprintf("unsupported records %q", sink.Records)}
+		}
+		if len(sink.Columns) > 0 && sink.Format != "csv" {
+			return nil, &usageError{msg: "--columns only applies to --format csv"}
+		}
+		if sink.Format == "sqlite" && (sink.Path == "" || sink.Path == "-") {
+			return nil, &usageError{msg: "--format sqlite requires --output with a database file"}
+		}
+	}
+	if profile.Concurrency < 1 {
+		return nil, &usageError{msg: "--concurrency must be at least 1"}
+	}
+	if profile.RateLimit <= 0 {
+		return nil, &usageError{msg: "--rate-limit must be positive"}
+	}
+	if opts.from, err = parseDate("--from", from); err != nil {
+		return nil, err
+	}
+	if opts.to, err = parseDate("--to", to); err != nil {
+		return nil, err
+	}
+	if !opts.from.IsZero() && !opts.to.IsZero() && opts.to.Before(opts.from) {
+		return nil, &usageError{msg: "--to must not be before --from"}
+	}
+	if users != "" {
+		for _, value := range strings.Split(users, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || id < 1 {
+				return nil, &usageError{msg: fmt.Sprintf("invalid user ID %q in --user", value)}
+			}
+			opts.users = append(opts.users, id)
+		}
+	}
+	if (set["cassette-mode"] || set["cassette-match"] || set["cassette-ignore"]) && !set["cassette"] {
+		return nil, &usageError{msg: "--cassette-mode, --cassette-match and --cassette-ignore require --cassette"}
+	}
+	if cassetteDir != "" {
+		mode, err := httpclient.ParseCassetteMode(cassetteMode)
+		if err != nil {
+			return nil, &usageError{msg: err.Error()}
+		}
+		match, err := parseCassetteMatch(cassetteMatch)
+		if err != nil {
+			return nil, &usageError{msg: err.Error()}
+		}
+		if cassetteIgnore != "" {
+			match.IgnoreParams = strings.Split(cassetteIgnore, ",")
+		}
+		if opts.cassette, err = httpclient.NewCassette(cassetteDir, mode, match); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+func loadProfile(configPath, profileName string) (*config.Profile, error) {
+	path := config.Locate(configPath)
+	if path == "" {
+		if profileName != "" {
+			return nil, fmt.Errorf("--profile %q given but no config file found", profileName)
+		}
+		profile := &config.Profile{}
+		if err := config.ApplyEnv(profile); err != nil {
+			return nil, err
+		}
+		return profile, nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Profile(profileName)
+}
+
+func parseDate(flagName, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, &usageError{msg: fmt.Sprintf("%s must be a date like 2024-03-31, got %q", flagName, value)}
+	}
+	return date, nil
+}
+
+// parseList reads a comma-separated list of names, such as statuses, that
+// must not be empty.
+func parseList(flagName, value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, &usageError{msg: fmt.Sprintf("%s needs at least one name", flagName)}
+	}
+	return names, nil
+}
+
+// parseCassetteMatch reads a list such as "method,path" into match options.
+func parseCassetteMatch(value string) (httpclient.MatchOptions, error) {
+	var match httpclient.MatchOptions
+	for _, part := range strings.Split(value, ",") {
+		switch strings.TrimSpace(part) {
+		case "method":
+			match.Method = true
+		case "path":
+			match.Path = true
+		case "query":
+			match.Query = true
+		case "":
+		default:
+			return match, fmt.Errorf("unknown --cassette-match part %q; use method, path or query", part)
+		}
+	}
+	return match, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuery turns --filters into a work package query, validating every
+// filter before anything is sent.
+func (o *options) parseQuery() (*wpquery.Query, error) {
+	query := wpquery.New()
+	if o.filters == "" {
+		return query, nil
+	}
+	filters, err := wpquery.ParseFilters(o.filters)
+	if err != nil {
+		return nil, &usageError{msg: fmt.Sprintf("invalid --filters: %v", err)}
+	}
+	return query.AddFilters(filters...), nil
+}
+
+func (o *options) requireProject() error {
+	if o.project == "" {
+		return &usageError{msg: "--project, " + config.EnvProject + " or a config profile project is required"}
+	}
+	return nil
+}
+
+func newCrawlerFromOptions(ctx context.Context, opts *options) (*Crawler, error) {
+	var crawler *Crawler
+	var err error
+	if opts.cassette != nil && opts.cassette.GetMode() == httpclient.ModeReplay {
+		// Replays need no credential and no rate limit; nothing leaves the machine.
+		crawler, err = (&Crawler{}).newCrawlerForProfile(opts.profile, nil)
+		if err == nil {
+			for _, client := range crawler.clients() {
+				client.SetRateLimiter(nil)
+			}
+		}
+	} else {
+		crawler, err = (&Crawler{}).NewCrawlerFromProfile(ctx, opts.profile)
+	}
+	if err != nil {
+		var credErr *credentialError
+		if errors.As(err, &credErr) {
+			return nil, err
+		}
+		return nil, &usageError{msg: err.Error()}
+	}
+	if opts.cassette != nil {
+		crawler.SetTransport(opts.cassette)
+	}
+	return crawler, nil
+}
+
+func writeSinks(ctx context.Context, sinks []config.Output, res *result, stdout io.Writer) error {
+	for _, sink := range sinks {
+		if sink.Format == "sqlite" {
+			if err := storeResult(ctx, sink.Path, res); err != nil {
+				return err
+			}
+			continue
+		}
+		out, closeOut, err := openOutput(sink.Path, stdout)
+		if err != nil {
+			return err
+		}
+		err = writeResult(out, sink, res)
+		if closeErr := closeOut(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeResult saves into the database even when ctx was cancelled, so the
+// partial results of an interrupted crawl are kept.
+func storeResult(ctx context.Context, path string, res *result) error {
+	if res.store == nil {
+		return fmt.Errorf("this command does not support sqlite output")
+	}
+	db, err := sqlitesink.Open(path)
+	if err != nil {
+		return err
+	}
+	err = res.store(context.WithoutCancel(ctx), db)
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func openOutput(path string, stdout io.Writer) (io.Writer, func() error, error) {
+	if path == "" || path == "-" {
+		return stdout, func() error { return nil }, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	return file, file.Close, nil
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var usage *usageError
+	var auth *credentialError
+	var status *httpclient.StatusError
+	var authorize *httpclient.AuthError
+	var partial *partialError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.As(err, &auth):
+		return exitAuth
+	case errors.As(err, &status) && (status.StatusCode == http.StatusUnauthorized || status.StatusCode == http.StatusForbidden):
+		return exitAuth
+	case errors.As(err, &authorize):
+		return exitAuth
+	case errors.As(err, &partial):
+		return exitPartial
+	}
+	return exitError
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: openproject-crawler <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	summaries := make(map[string]string, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+		summaries[cmd.name] = cmd.summary
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-20s %s\n", name, summaries[name])
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Settings come from flags, then environment variables, then the profile in %s.\n", config.DefaultPath())
+	fmt.Fprintf(w, "Credentials are read from the profile, --credentials-file, --credentials-command, %s, %s and %s,\n",
+		credential.EnvAPIKey, credential.EnvUsername, credential.EnvPassword)
+	fmt.Fprintf(w, "OAuth2 via %s with %s or %s, or a session cookie in %s.\n",
+		credential.EnvClientID, credential.EnvClientSecret, credential.EnvRefreshToken, credential.EnvSession)
+	fmt.Fprintln(w, "Run 'openproject-crawler <command> -h' for the flags of a command.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes: 0 ok, 1 error, 2 usage, 3 authentication, 4 partial results, 130 interrupted.")
+}
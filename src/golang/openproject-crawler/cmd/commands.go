@@ -0,0 +1,800 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"openproject-crawler/internal/config"
+	"openproject-crawler/pkg/analytics"
+	"openproject-crawler/pkg/checkpoint"
+	"openproject-crawler/pkg/crawlact"
+	"openproject-crawler/pkg/crawlprojects"
+	"openproject-crawler/pkg/crawlrelations"
+	"openproject-crawler/pkg/crawltime"
+	"openproject-crawler/pkg/crawlversions"
+	"openproject-crawler/pkg/export"
+	"openproject-crawler/pkg/model"
+	"openproject-crawler/pkg/sqlitesink"
+	"openproject-crawler/pkg/wpquery"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func runProjectsList(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	projects, err := crawler.GetProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &result{
+		value: projects,
+		table: func(w io.Writer) error {
+			return writeTable(w, []string{"ID", "IDENTIFIER", "NAME", "ACTIVE", "PUBLIC", "PARENT", "STATUS"}, len(projects), func(i int) []interface{} {
+				p := projects[i]
+				parent := ""
+				if parentID, ok := p.ParentID(); ok {
+					parent = fmt.Sprint(parentID)
+				}
+				return []interface{}{p.ID, p.Identifier, p.Name, p.Active, p.Public, parent, p.StatusName()}
+			})
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, projects)
+		},
+		store: func(ctx context.Context, db *sqlitesink.Sink) error {
+			return db.SaveProjects(ctx, projects)
+		},
+	}, nil
+}
+
+func runProjectsTree(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	roots, err := crawler.GetProjectTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &result{
+		value: roots,
+		table: func(w io.Writer) error {
+			var lines [][]interface{}
+			var walk func(nodes []*crawlprojects.ProjectNode, depth int)
+			walk = func(nodes []*crawlprojects.ProjectNode, depth int) {
+				for _, node := range nodes {
+					p := node.Project
+					lines = append(lines, []interface{}{p.ID, p.Identifier, strings.Repeat("  ", depth) + p.Name})
+					walk(node.Children, depth+1)
+				}
+			}
+			walk(roots, 0)
+			return writeTable(w, []string{"ID", "IDENTIFIER", "NAME"}, len(lines), func(i int) []interface{} {
+				return lines[i]
+			})
+		},
+	}, nil
+}
+
+func runWorkPackagesList(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	query, err := opts.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case opts.subprojects:
+		if err := opts.requireProject(); err != nil {
+			return nil, err
+		}
+		projectIDs, err := crawler.resolveProjectIDs(ctx, opts.project, true)
+		if err != nil {
+			return nil, err
+		}
+		query.Project(projectIDs...)
+		crawler.SetProjectName("")
+	case opts.project != "":
+		// The API path takes an ID or identifier, but --project may be a name.
+		project, err := crawler.LookupProject(ctx, opts.project)
+		if err != nil {
+			return nil, err
+		}
+		crawler.SetProjectName(project.Identifier)
+	default:
+		crawler.SetProjectName("")
+	}
+	params, err := query.Params()
+	if err != nil {
+		return nil, err
+	}
+	crawler.SetParams(params)
+
+	workPackages, err := crawler.GetWorkPackages(ctx)
+	if err != nil && len(workPackages) == 0 {
+		return nil, err
+	}
+	res := &result{
+		value: workPackages,
+		table: func(w io.Writer) error {
+			return writeTable(w, []string{"ID", "TYPE", "STATUS", "PRIORITY", "SUBJECT"}, len(workPackages), func(i int) []interface{} {
+				wp := workPackages[i]
+				return []interface{}{wp.ID, wp.TypeName(), wp.StatusName(), wp.PriorityName(), wp.Subject}
+			})
+		},
+		csv: func(w io.Writer, sink config.Output) error {
+			return export.WriteCSV(w, export.WorkPackageColumns, sink.Columns, workPackages)
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, workPackages)
+		},
+		store: func(ctx context.Context, db *sqlitesink.Sink) error {
+			return db.SaveWorkPackages(ctx, workPackages)
+		},
+	}
+	if err != nil {
+		return res, &partialError{err: err}
+	}
+	return res, nil
+}
+
+func runStatusesList(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	if err := crawler.statuses.FetchData(ctx); err != nil {
+		return nil, err
+	}
+	statuses := crawler.statuses.GetStatuses()
+	return &result{
+		value: statuses,
+		table: func(w io.Writer) error {
+			return writeTable(w, []string{"ID", "NAME", "CLOSED", "DEFAULT"}, len(statuses), func(i int) []interface{} {
+				s := statuses[i]
+				return []interface{}{s.ID, s.Name, s.IsClosed, s.IsDefault}
+			})
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, statuses)
+		},
+		store: func(ctx context.Context, db *sqlitesink.Sink) error {
+			return db.SaveStatuses(ctx, statuses)
+		},
+	}, nil
+}
+
+// crawlActivities resolves the project's work packages and crawls their
+// activities. A non-nil error with non-nil tasks means the result is partial.
+func crawlActivities(ctx context.Context, crawler *Crawler, opts *options) ([]model.Task, error) {
+	if err := opts.requireProject(); err != nil {
+		return nil, err
+	}
+	query, err := opts.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := crawler.loadClosedStatuses(ctx); err != nil {
+		log.Printf("Falling back to default closed statuses: %v", err)
+	}
+	if err := crawler.loadCalendar(ctx); err != nil {
+		log.Printf("Falling back to default working calendar: %v", err)
+	}
+
+	scope, err := crawlScope(query, opts.subprojects)
+	if err != nil {
+		return nil, err
+	}
+	state, err := crawlact.OpenState(progressPath(opts.profile.StateDir, opts.project, scope), opts.resume)
+	if err != nil {
+		return nil, err
+	}
+	crawler.SetState(state)
+
+	var tasks []model.Task
+	if opts.incremental {
+		tasks, err = crawler.crawlIncremental(ctx, opts.project, query, opts.subprojects, opts.profile.StateDir, scope)
+	} else {
+		var tasksID []int
+		tasksID, err = crawler.crawlTasksID(ctx, opts.project, query, opts.subprojects)
+		if err != nil {
+			return nil, fmt.Errorf("failed to crawl task IDs for project %q: %w", opts.project, err)
+		}
+		crawler.SettasksID(tasksID)
+		tasks, err = crawler.GetTasksActivities(ctx)
+	}
+	err = finishState(state, err)
+	if err != nil {
+		if tasks == nil {
+			return nil, fmt.Errorf("failed to crawl tasks activities: %w", err)
+		}
+		return tasks, &partialError{err: err}
+	}
+	return tasks, nil
+}
+
+// finishState deletes the state file of a crawl that completed every task,
+// and otherwise keeps it for --resume and reports the failed tasks.
+func finishState(state *crawlact.State, crawlErr error) error {
+	failed := state.FailedIDs()
+	if crawlErr == nil && len(failed) == 0 {
+		return state.Remove()
+	}
+	state.Close()
+
+	reasons := state.Failed()
+	for _, taskID := range failed {
+		log.Printf("Task %d failed: %s", taskID, reasons[taskID])
+	}
+	log.Printf("Progress saved to %s; rerun with --resume to continue", state.Path())
+	if crawlErr == nil {
+		crawlErr = fmt.Errorf("%d tasks failed", len(failed))
+	}
+	return crawlErr
+}
+
+func runActivitiesCrawl(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	tasks, err := crawlActivities(ctx, crawler, opts)
+	if tasks == nil {
+		return nil, err
+	}
+	return &result{
+		value: tasks,
+		table: func(w io.Writer) error {
+			return writeTable(w, []string{"ID", "TYPE", "PRIORITY", "CREATED", "CLOSED", "DURATION", "NAME"}, len(tasks), func(i int) []interface{} {
+				t := tasks[i]
+				return []interface{}{t.TaskInfo.ID, t.TaskInfo.Type, t.TaskInfo.Priority, t.TaskInfo.CreatedDate, t.TaskInfo.ClosedDate, t.TaskInfo.Duration, t.TaskName}
+			})
+		},
+		csv: func(w io.Writer, sink config.Output) error {
+			if sink.Records == "activities" {
+				return export.WriteCSV(w, export.ActivityColumns, sink.Columns, export.FlattenActivities(tasks))
+			}
+			return export.WriteCSV(w, export.TaskColumns, sink.Columns, export.TaskRecords(tasks))
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			if sink.Records == "activities" {
+				return export.WriteJSONLines(w, export.FlattenActivities(tasks))
+			}
+			return export.WriteJSONLines(w, export.TaskRecords(tasks))
+		},
+		store: func(ctx context.Context, db *sqlitesink.Sink) error {
+			return storeCrawl(ctx, db, crawler)
+		},
+	}, err
+}
+
+// storeCrawl saves everything an activities crawl fetched along the way:
+// projects, statuses, the project's work packages, their raw activities and
+// the activities' authors.
+func storeCrawl(ctx context.Context, db *sqlitesink.Sink, crawler *Crawler) error {
+	if err := db.SaveProjects(ctx, crawler.GetProjectsData()); err != nil {
+		return err
+	}
+	if err := db.SaveStatuses(ctx, crawler.statuses.GetStatuses()); err != nil {
+		return err
+	}
+	if err := db.SaveWorkPackages(ctx, crawler.GetWorkPackagesData()); err != nil {
+		return err
+	}
+	if err := db.SaveActivities(ctx, crawler.GetTasksData()); err != nil {
+		return err
+	}
+	return db.SaveUsers(ctx, crawler.GetAuthors())
+}
+
+// progressPath is the state file of a project's activity crawl in scope,
+// next to its incremental checkpoint.
+func progressPath(stateDir, project string, scope checkpoint.Scope) string {
+	checkpointPath := checkpoint.Path(stateDir, project, scope)
+	return filepath.Join(stateDir, strings.TrimSuffix(filepath.Base(checkpointPath), ".json")+".progress.jsonl")
+}
+
+type statsReport struct {
+	Project         string           `json:"project"`
+	Tasks           int              `json:"tasks"`
+	Retries         int              `json:"retries"`
+	TasksByType     map[string]int   `json:"tasksByType"`
+	TasksByPriority map[string]int   `json:"tasksByPriority"`
+	Analytics       analytics.Report `json:"analytics"`
+}
+
+func runStats(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	tasks, crawlErr := crawlActivities(ctx, crawler, opts)
+	if tasks == nil {
+		return nil, crawlErr
+	}
+
+	config := analytics.DefaultConfig()
+	if closed := crawler.GetClosedStatuses(); len(closed) > 0 {
+		config.EndStatuses = closed
+	}
+	if statuses := opts.profile.Analytics.StartStatuses; len(statuses) > 0 {
+		config.StartStatuses = statuses
+	}
+	if statuses := opts.profile.Analytics.EndStatuses; len(statuses) > 0 {
+		config.EndStatuses = statuses
+	}
+	analyzer, err := analytics.NewAnalyzer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// The crawled work packages carry the current type, priority and
+	// creation time; the first activity only has the initial ones.
+	items := analytics.ItemsFromTasks(tasks, crawler.GetWorkPackagesData(), crawler.GetCalendar().GetLocation())
+	report := statsReport{
+		Project:         opts.project,
+		Tasks:           len(tasks),
+		TasksByType:     make(map[string]int),
+		TasksByPriority: make(map[string]int),
+		Analytics:       analyzer.Analyze(items),
+	}
+	for i, task := range tasks {
+		report.Retries += task.TaskInfo.Retries
+		report.TasksByType[items[i].Type]++
+		report.TasksByPriority[items[i].Priority]++
+	}
+
+	return &result{
+		value: report,
+		table: func(w io.Writer) error {
+			return writeStatsTable(w, report)
+		},
+	}, crawlErr
+}
+
+func writeStatsTable(out io.Writer, report statsReport) error {
+	fmt.Fprintf(out, "Project: %s\nTasks: %d\nRetries: %d\n\n", report.Project, report.Tasks, report.Retries)
+
+	groups := map[string]analytics.Summary{"(all)": report.Analytics.Overall}
+	for name, summary := range report.Analytics.ByType {
+		groups["type: "+name] = summary
+	}
+	for name, summary := range report.Analytics.ByPriority {
+		groups["priority: "+name] = summary
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return writeTable(out, []string{"GROUP", "CLOSED", "LEAD P50 (h)", "LEAD P85 (h)", "LEAD P95 (h)", "CYCLE P50 (h)", "CYCLE P85 (h)", "CYCLE P95 (h)"}, len(names), func(i int) []interface{} {
+		s := groups[names[i]]
+		return []interface{}{
+			names[i], s.LeadTime.Count,
+			hours(s.LeadTime, "p50"), hours(s.LeadTime, "p85"), hours(s.LeadTime, "p95"),
+			hours(s.CycleTime, "p50"), hours(s.CycleTime, "p85"), hours(s.CycleTime, "p95"),
+		}
+	})
+}
+
+func hours(dist analytics.Distribution, key string) string {
+	if dist.Count == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", dist.Percentiles[key])
+}
+
+func runUsersList(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	users, err := crawler.users.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &result{
+		value: users,
+		table: func(w io.Writer) error {
+			return writeTable(w, []string{"ID", "LOGIN", "NAME", "EMAIL", "ADMIN", "STATUS"}, len(users), func(i int) []interface{} {
+				u := users[i]
+				return []interface{}{u.ID, u.Login, u.Name, u.Email, u.Admin, u.Status}
+			})
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, users)
+		},
+		store: func(ctx context.Context, db *sqlitesink.Sink) error {
+			return db.SaveUsers(ctx, users)
+		},
+	}, nil
+}
+
+func runGroupsList(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	groups, err := crawler.users.GetGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &result{
+		value: groups,
+		table: func(w io.Writer) error {
+			return writeTable(w, []string{"ID", "NAME", "MEMBERS"}, len(groups), func(i int) []interface{} {
+				g := groups[i]
+				members := make([]string, len(g.Links.Members))
+				for j, member := range g.Links.Members {
+					members[j] = member.Title
+				}
+				return []interface{}{g.ID, g.Name, strings.Join(members, ", ")}
+			})
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, groups)
+		},
+	}, nil
+}
+
+func runMembershipsList(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	var projectIDs []int
+	if opts.project != "" {
+		var err error
+		projectIDs, err = crawler.resolveProjectIDs(ctx, opts.project, opts.subprojects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	memberships, err := crawler.users.GetMemberships(ctx, projectIDs...)
+	if err != nil {
+		return nil, err
+	}
+	return &result{
+		value: memberships,
+		table: func(w io.Writer) error {
+			return writeTable(w, []string{"ID", "PROJECT", "PRINCIPAL", "ROLES"}, len(memberships), func(i int) []interface{} {
+				m := memberships[i]
+				return []interface{}{m.ID, m.ProjectName(), m.PrincipalName(), strings.Join(m.RoleNames(), ", ")}
+			})
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, memberships)
+		},
+	}, nil
+}
+
+// relationsReport is the JSON form of "relations graph". CriticalPath is
+// nil when the work packages follow each other in a cycle.
+type relationsReport struct {
+	Project      string                `json:"project"`
+	Graph        *crawlrelations.Graph `json:"graph"`
+	Cycles       [][]int               `json:"cycles"`
+	CriticalPath *crawlrelations.Path  `json:"criticalPath"`
+}
+
+func runRelationsGraph(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	if err := opts.requireProject(); err != nil {
+		return nil, err
+	}
+	query, err := opts.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	tasksID, err := crawler.crawlTasksID(ctx, opts.project, query, opts.subprojects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to crawl task IDs for project %q: %w", opts.project, err)
+	}
+	relations, crawlErr := crawler.relations.GetRelations(ctx, tasksID)
+	if crawlErr != nil && len(relations) == 0 {
+		return nil, crawlErr
+	}
+
+	graph := crawlrelations.NewGraph(tasksID)
+	graph.AddWorkPackages(crawler.GetWorkPackagesData())
+	graph.AddRelations(relations)
+	report := relationsReport{Project: opts.project, Graph: graph, Cycles: graph.Cycles()}
+	if path, err := graph.CriticalPath(); err != nil {
+		log.Printf("No critical path: %v", err)
+	} else {
+		report.CriticalPath = &path
+	}
+
+	res := &result{
+		value: report,
+		table: func(w io.Writer) error {
+			return writeRelationsTable(w, report)
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, graph.Edges)
+		},
+		dot: func(w io.Writer) error {
+			return crawlrelations.WriteDOT(w, graph)
+		},
+		mermaid: func(w io.Writer) error {
+			return crawlrelations.WriteMermaid(w, graph)
+		},
+	}
+	if crawlErr != nil {
+		return res, &partialError{err: crawlErr}
+	}
+	return res, nil
+}
+
+func writeRelationsTable(out io.Writer, report relationsReport) error {
+	graph := report.Graph
+	label := func(id int) string {
+		node, _ := graph.Node(id)
+		return node.Label()
+	}
+	err := writeTable(out, []string{"FROM", "KIND", "TO", "LAG"}, len(graph.Edges), func(i int) []interface{} {
+		edge := graph.Edges[i]
+		return []interface{}{label(edge.From), edge.Kind, label(edge.To), edge.Lag}
+	})
+	if err != nil {
+		return err
+	}
+	for _, cycle := range report.Cycles {
+		labels := make([]string, len(cycle))
+		for i, id := range cycle {
+			labels[i] = label(id)
+		}
+		fmt.Fprintf(out, "\nCycle: %s\n", strings.Join(labels, " -> "))
+	}
+	if path := report.CriticalPath; path != nil && len(path.WorkPackages) > 0 {
+		labels := make([]string, len(path.WorkPackages))
+		for i, id := range path.WorkPackages {
+			labels[i] = label(id)
+		}
+		fmt.Fprintf(out, "\nCritical path (%d days): %s\n", path.Days, strings.Join(labels, " -> "))
+	}
+	return nil
+}
+
+// timeReport is the JSON form of "time report".
+type timeReport struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	crawltime.Report
+}
+
+func runTimeReport(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	query := crawltime.Query{UserIDs: opts.users, From: opts.from, To: opts.to}
+	if opts.project != "" {
+		projectIDs, err := crawler.resolveProjectIDs(ctx, opts.project, opts.subprojects)
+		if err != nil {
+			return nil, err
+		}
+		query.ProjectIDs = projectIDs
+	}
+	timeEntries, err := crawler.timeEntries.GetTimeEntries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := crawltime.NewEntries(timeEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	report := timeReport{Report: crawltime.Aggregate(entries)}
+	if !opts.from.IsZero() {
+		report.From = opts.from.Format("2006-01-02")
+	}
+	if !opts.to.IsZero() {
+		report.To = opts.to.Format("2006-01-02")
+	}
+	return &result{
+		value: report,
+		table: func(w io.Writer) error {
+			return writeTimeTable(w, report)
+		},
+		csv: func(w io.Writer, sink config.Output) error {
+			return export.WriteCSV(w, export.TimeEntryColumns, sink.Columns, entries)
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, entries)
+		},
+	}, nil
+}
+
+func writeTimeTable(out io.Writer, report timeReport) error {
+	fmt.Fprintf(out, "Time entries: %d\nHours: %.2f\n", report.Entries, report.Hours)
+	sections := []struct {
+		title  string
+		totals []crawltime.Total
+	}{
+		{"USER", report.ByUser},
+		{"WORK PACKAGE", report.ByWorkPackage},
+		{"ACTIVITY", report.ByActivity},
+		{"WEEK", report.ByWeek},
+	}
+	for _, section := range sections {
+		fmt.Fprintln(out)
+		totals := section.totals
+		err := writeTable(out, []string{section.title, "HOURS", "ENTRIES"}, len(totals), func(i int) []interface{} {
+			return []interface{}{totals[i].Key, fmt.Sprintf("%.2f", totals[i].Hours), totals[i].Entries}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runVersionsList(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	var projectIDs []int
+	if opts.project != "" {
+		var err error
+		projectIDs, err = crawler.resolveProjectIDs(ctx, opts.project, opts.subprojects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	versions, err := crawler.versions.GetVersions(ctx, projectIDs...)
+	if err != nil {
+		return nil, err
+	}
+	return &result{
+		value: versions,
+		table: func(w io.Writer) error {
+			return writeTable(w, []string{"ID", "NAME", "PROJECT", "STATUS", "SHARING", "START", "END"}, len(versions), func(i int) []interface{} {
+				v := versions[i]
+				return []interface{}{v.ID, v.Name, v.ProjectName(), v.Status, v.Sharing, v.StartDate, v.EndDate}
+			})
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, versions)
+		},
+	}, nil
+}
+
+// runVersionBurndown replays the history of the version's work packages and
+// of the work packages updated since the version started, as those may have
+// been moved out of it. Without --project these are searched in the version's
+// defining project.
+func runVersionBurndown(ctx context.Context, crawler *Crawler, opts *options) (*result, error) {
+	if opts.version == "" {
+		return nil, &usageError{msg: "--version is required"}
+	}
+	query, err := opts.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	var projectIDs []int
+	if opts.project != "" {
+		if projectIDs, err = crawler.resolveProjectIDs(ctx, opts.project, opts.subprojects); err != nil {
+			return nil, err
+		}
+	}
+	version, err := crawler.versions.LookupVersion(ctx, opts.version, projectIDs...)
+	if err != nil {
+		return nil, err
+	}
+	if projectIDs == nil {
+		if projectID, ok := version.Links.DefiningProject.ID(); ok {
+			projectIDs = []int{projectID}
+		}
+	}
+
+	from, to := opts.from, opts.to
+	if from.IsZero() && version.StartDate != "" {
+		from, _ = time.Parse("2006-01-02", version.StartDate)
+	}
+	if to.IsZero() && version.EndDate != "" {
+		to, _ = time.Parse("2006-01-02", version.EndDate)
+	}
+	if from.IsZero() || to.IsZero() {
+		return nil, &usageError{msg: fmt.Sprintf("version %q has no start or end date; pass --from and --to", version.Name)}
+	}
+
+	crawler.SetProjectName("")
+	queries := []*wpquery.Query{query.Clone().Version(wpquery.Equals, version.ID)}
+	if len(projectIDs) > 0 && !from.IsZero() {
+		queries = append(queries, query.Clone().Project(projectIDs...).Version(wpquery.NotEquals, version.ID).UpdatedBetween(from, time.Time{}))
+	}
+	var workPackages []model.WorkPackage
+	for _, q := range queries {
+		params, err := q.Params()
+		if err != nil {
+			return nil, err
+		}
+		crawler.SetParams(params)
+		found, err := crawler.GetWorkPackages(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to crawl work packages of version %q: %w", version.Name, err)
+		}
+		workPackages = append(workPackages, found...)
+	}
+
+	tasksID := make([]int, len(workPackages))
+	for i, wp := range workPackages {
+		tasksID[i] = wp.ID
+	}
+	crawler.SettasksID(tasksID)
+	tasksData, crawlErr := crawler.FetchTasksData(ctx)
+	if crawlErr != nil && len(tasksData) == 0 && len(tasksID) > 0 {
+		return nil, crawlErr
+	}
+	activities := make(map[int][]model.Activity, len(tasksData))
+	for _, taskActivities := range tasksData {
+		if len(taskActivities) == 0 {
+			continue
+		}
+		if taskID, ok := taskActivities[0].Links.WorkPackage.ID(); ok {
+			activities[taskID] = taskActivities
+		}
+	}
+	items := make([]crawlversions.Item, len(workPackages))
+	for i, wp := range workPackages {
+		items[i] = crawlversions.Item{WorkPackage: wp, Activities: activities[wp.ID]}
+	}
+
+	if err := crawler.loadClosedStatuses(ctx); err != nil {
+		log.Printf("Falling back to default closed statuses: %v", err)
+	}
+	burndown, err := crawlversions.BuildBurndown(version, items, crawler.GetClosedStatuses(), from, to, time.Now(), crawler.GetCalendar().GetLocation())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &result{
+		value: burndown,
+		table: func(w io.Writer) error {
+			return writeBurndownTable(w, burndown)
+		},
+		csv: func(w io.Writer, sink config.Output) error {
+			return export.WriteCSV(w, export.BurndownColumns, sink.Columns, burndown.Days)
+		},
+		jsonl: func(w io.Writer, sink config.Output) error {
+			return export.WriteJSONLines(w, burndown.Days)
+		},
+	}
+	if crawlErr != nil {
+		return res, &partialError{err: crawlErr}
+	}
+	return res, nil
+}
+
+func writeBurndownTable(out io.Writer, burndown crawlversions.Burndown) error {
+	fmt.Fprintf(out, "Version: %s (%s to %s)\nWork packages: %d\n\n", burndown.Version, burndown.From, burndown.To, len(burndown.WorkPackages))
+	amount := func(a *crawlversions.Amount) string {
+		if a == nil {
+			return ""
+		}
+		return fmt.Sprintf("%g / %g pt / %g h", a.Count, a.Points, a.Hours)
+	}
+	return writeTable(out, []string{"DATE", "SCOPE", "DONE", "REMAINING", "IDEAL"}, len(burndown.Days), func(i int) []interface{} {
+		day := burndown.Days[i]
+		return []interface{}{day.Date, amount(day.Scope), amount(day.Done), amount(day.Remaining), amount(&day.Ideal)}
+	})
+}
+
+func writeResult(out io.Writer, sink config.Output, res *result) error {
+	switch sink.Format {
+	case "json":
+		return writeJSON(out, res.value)
+	case "table":
+		return res.table(out)
+	case "csv":
+		if res.csv == nil {
+			return fmt.Errorf("this command does not support csv output")
+		}
+		return res.csv(out, sink)
+	case "jsonl":
+		if res.jsonl == nil {
+			return json.NewEncoder(out).Encode(res.value)
+		}
+		return res.jsonl(out, sink)
+	case "dot":
+		if res.dot == nil {
+			return fmt.Errorf("this command does not support dot output")
+		}
+		return res.dot(out)
+	case "mermaid":
+		if res.mermaid == nil {
+			return fmt.Errorf("this command does not support mermaid output")
+		}
+		return res.mermaid(out)
+	}
+	return fmt.Errorf("unsupported format %q", sink.Format)
+}
+
+func writeJSON(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func writeTable(out io.Writer, header []string, rows int, row func(i int) []interface{}) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for i := 0; i < rows; i++ {
+		values := row(i)
+		cells := make([]string, len(values))
+		for j, value := range values {
+			cells[j] = fmt.Sprintf("%v", value)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
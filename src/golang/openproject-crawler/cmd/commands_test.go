@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"openproject-crawler/internal/config"
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/crawlversions"
+	"testing"
+)
+
+func newTestCrawler(t *testing.T, srv *fakeapi.Server) (*Crawler, *config.Profile) {
+	t.Helper()
+	profile := &config.Profile{APIURL: srv.APIURL(), StateDir: t.TempDir()}
+	crawler, err := (&Crawler{}).newCrawlerForProfile(profile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, client := range crawler.clients() {
+		client.SetRateLimiter(nil)
+		if err := client.SetRetryPolicy(httpclient.RetryPolicy{MaxAttempts: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return crawler, profile
+}
+
+func TestStatsUseWorkPackages(t *testing.T) {
+	fixtures := fakeapi.DefaultFixtures()
+	// Task 102 was created as a Task and has since become a Feature.
+	for i, raw := range fixtures.WorkPackages {
+		var wp map[string]interface{}
+		if err := json.Unmarshal(raw, &wp); err != nil {
+			t.Fatal(err)
+		}
+		if wp["id"] == float64(102) {
+			wp["_links"].(map[string]interface{})["type"] = map[string]string{"href": "/api/v3/types/2", "title": "Feature"}
+			updated, err := json.Marshal(wp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixtures.WorkPackages[i] = updated
+		}
+	}
+	srv := fakeapi.New(fixtures)
+	defer srv.Close()
+
+	crawler, profile := newTestCrawler(t, srv)
+
+	res, err := runStats(context.Background(), crawler, &options{profile: profile, project: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := res.value.(statsReport)
+	if report.TasksByType["Feature"] != 2 || report.TasksByType["Task"] != 0 {
+		t.Errorf("tasks by type = %v, want 2 features", report.TasksByType)
+	}
+	for _, item := range report.Analytics.Items {
+		if item.ID == 102 && item.Type != "Feature" {
+			t.Errorf("task 102 measured as %s, want Feature", item.Type)
+		}
+	}
+}
+
+func TestBurndownWithoutStatuses(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	srv.Inject(fakeapi.Fault{Path: "/statuses", Status: http.StatusInternalServerError})
+	crawler, profile := newTestCrawler(t, srv)
+
+	// Like an activity crawl, the burndown falls back to the default closed
+	// statuses instead of failing.
+	res, err := runVersionBurndown(context.Background(), crawler, &options{profile: profile, version: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burndown := res.value.(crawlversions.Burndown); len(burndown.Days) == 0 {
+		t.Error("burndown has no days")
+	}
+}
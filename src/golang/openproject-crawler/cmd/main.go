@@ -1,20 +1,45 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
+	"math"
+	"net/http"
+	"openproject-crawler/internal/config"
+	"openproject-crawler/internal/core"
 	"openproject-crawler/internal/credential"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/internal/workerpool"
+	"openproject-crawler/pkg/checkpoint"
 	"openproject-crawler/pkg/crawlact"
+	"openproject-crawler/pkg/crawldays"
 	"openproject-crawler/pkg/crawlprojects"
+	"openproject-crawler/pkg/crawlrelations"
+	"openproject-crawler/pkg/crawlstatuses"
+	"openproject-crawler/pkg/crawltime"
+	"openproject-crawler/pkg/crawlusers"
+	"openproject-crawler/pkg/crawlversions"
 	"openproject-crawler/pkg/crawlwp"
+	"openproject-crawler/pkg/model"
+	"openproject-crawler/pkg/workcal"
+	"openproject-crawler/pkg/wpquery"
+	"os"
+	"time"
 )
 
 type Crawler struct {
 	*crawlprojects.CrawlProjects
 	*crawlwp.CrawlWorkPackages
 	*crawlact.CrawlActivities
-	authToken string
+	statuses    *crawlstatuses.CrawlStatuses
+	days        *crawldays.CrawlDays
+	timeEntries *crawltime.CrawlTimeEntries
+	users       *crawlusers.CrawlUsers
+	relations   *crawlrelations.CrawlRelations
+	versions    *crawlversions.CrawlVersions
+	location    *time.Location
+	calendar    config.WorkCalendar
+	authToken   string
 }
 
 func (c *Crawler) NewCrawler(apiURL, username, password string) (*Crawler, error) {
@@ -24,109 +49,349 @@ func (c *Crawler) NewCrawler(apiURL, username, password string) (*Crawler, error
 	}
 	c.authToken = cred.GenerateToken()
 
-	crawlProject, err := crawlprojects.NewCrawlProjects(apiURL, c.authToken)
+	crawler, err := c.NewCrawlerWithProvider(apiURL, credential.NewBasicProvider(c.authToken))
+	if err != nil {
+		return nil, err
+	}
+	crawler.authToken = c.authToken
+	return crawler, nil
+}
+
+// NewCrawlerWithProvider builds a crawler whose requests are authorized by
+// provider, e.g. an OAuth2 or session cookie provider.
+func (c *Crawler) NewCrawlerWithProvider(apiURL string, provider credential.Provider) (*Crawler, error) {
+	crawlProject, err := crawlprojects.NewCrawlProjects(apiURL, "")
+	if err != nil {
+		return nil, err
+	}
+
+	crawlWorkPackages, err := crawlwp.NewCrawlWorkPackages(apiURL, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	crawlAct, err := crawlact.NewCrawlActivities(apiURL, "")
+	if err != nil {
+		return nil, err
+	}
+
+	crawlStatuses, err := crawlstatuses.NewCrawlStatuses(apiURL, "")
+	if err != nil {
+		return nil, err
+	}
+
+	crawlDays, err := crawldays.NewCrawlDays(apiURL, "")
+	if err != nil {
+		return nil, err
+	}
+
+	crawlTime, err := crawltime.NewCrawlTimeEntries(apiURL, "")
+	if err != nil {
+		return nil, err
+	}
+
+	crawlUsers, err := crawlusers.NewCrawlUsers(apiURL, "")
 	if err != nil {
 		return nil, err
 	}
 
-	crawlWorkPackages, err := crawlwp.NewCrawlWorkPackages(apiURL, c.authToken, "")
+	crawlRelations, err := crawlrelations.NewCrawlRelations(apiURL, "")
 	if err != nil {
 		return nil, err
 	}
 
-	crawlAct, err := crawlact.NewCrawlActivities(apiURL, c.authToken)
+	crawlVersions, err := crawlversions.NewCrawlVersions(apiURL, "")
 	if err != nil {
 		return nil, err
 	}
 
-	return &Crawler{
+	pool := workerpool.New(workerpool.DefaultSize)
+	crawlAct.SetPool(pool)
+	crawlRelations.SetPool(pool)
+	crawlAct.SetUserResolver(crawlusers.NewResolver(crawlUsers))
+
+	crawler := &Crawler{
 		CrawlProjects:     crawlProject,
 		CrawlWorkPackages: crawlWorkPackages,
 		CrawlActivities:   crawlAct,
-		authToken:         c.authToken,
-	}, nil
+		statuses:          crawlStatuses,
+		days:              crawlDays,
+		timeEntries:       crawlTime,
+		users:             crawlUsers,
+		relations:         crawlRelations,
+		versions:          crawlVersions,
+	}
+	for _, client := range crawler.clients() {
+		client.SetAuthProvider(provider)
+	}
+	crawler.setRateLimit(httpclient.DefaultRequestsPerSecond)
+	return crawler, nil
 }
 
-func (c *Crawler) crawlProjectsID() (map[int]string, error) {
-	IDs, err := c.GetProjectsID()
+// NewCrawlerFromProfile builds a crawler from a configuration profile,
+// loading its credential and applying its page size, concurrency, rate limit,
+// time zone, close policy and working calendar.
+func (c *Crawler) NewCrawlerFromProfile(ctx context.Context, profile *config.Profile) (*Crawler, error) {
+	provider, err := loadProfileProvider(ctx, profile)
 	if err != nil {
 		return nil, err
 	}
-	return IDs, nil
+	return c.newCrawlerForProfile(profile, provider)
+}
+
+func (c *Crawler) newCrawlerForProfile(profile *config.Profile, provider credential.Provider) (*Crawler, error) {
+	crawler, err := c.NewCrawlerWithProvider(profile.APIURL, provider)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Concurrency > 0 {
+		crawler.setConcurrency(profile.Concurrency)
+	}
+	if profile.PageSize > 0 {
+		if err := crawler.setPageSize(profile.PageSize); err != nil {
+			return nil, err
+		}
+	}
+	if profile.RateLimit > 0 {
+		crawler.setRateLimit(profile.RateLimit)
+	}
+	if profile.Timezone != "" {
+		location, err := time.LoadLocation(profile.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		crawler.location = location
+		crawler.GetCalendar().SetLocation(location)
+	}
+	if profile.ClosePolicy != "" {
+		if err := crawler.SetClosePolicy(core.ClosePolicy(profile.ClosePolicy)); err != nil {
+			return nil, err
+		}
+	}
+	crawler.calendar = profile.Calendar
+	if err := crawler.calendar.Apply(crawler.GetCalendar()); err != nil {
+		return nil, err
+	}
+	return crawler, nil
+}
+
+type credentialError struct {
+	err error
+}
+
+func (e *credentialError) Error() string {
+	return "credentials: " + e.err.Error()
+}
+
+func (e *credentialError) Unwrap() error {
+	return e.err
+}
+
+func loadProfileProvider(ctx context.Context, profile *config.Profile) (credential.Provider, error) {
+	var values credential.Values
+	var err error
+	switch {
+	case profile.Credentials.File != "":
+		values, err = credential.LoadFile(profile.Credentials.File)
+	case profile.Credentials.Command != "":
+		values, err = credential.LoadCommand(ctx, profile.Credentials.Command)
+	case profile.Credentials.Env != "":
+		values, err = credential.LoadEnvVar(profile.Credentials.Env)
+	default:
+		values, err = credential.LoadEnv()
+	}
+	if err != nil {
+		return nil, &credentialError{err: err}
+	}
+	provider, err := credential.NewProvider(values, profile.APIURL)
+	if err != nil {
+		return nil, &credentialError{err: err}
+	}
+	return provider, nil
 }
 
-func (c *Crawler) crawlTasksID(projectName string) ([]int, error) {
-	projectsID, err := c.crawlProjectsID()
+func (c *Crawler) crawlProjectsID(ctx context.Context) (map[int]string, error) {
+	IDs, err := c.GetProjectsID(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return IDs, nil
+}
 
-	validID, found := func(mappedID map[int]string) (int, bool) {
-		for k, v := range mappedID {
-			if v == projectName {
-				return k, true
-			}
+func (c *Crawler) loadClosedStatuses(ctx context.Context) error {
+	if err := c.statuses.FetchData(ctx); err != nil {
+		return err
+	}
+	c.SetClosedStatuses(c.statuses.GetStatuses())
+	return nil
+}
+
+func (c *Crawler) loadCalendar(ctx context.Context) error {
+	calendar := workcal.NewCalendar()
+	if c.location != nil {
+		calendar.SetLocation(c.location)
+	}
+	now := time.Now()
+	if err := c.days.LoadCalendar(ctx, calendar, now.AddDate(-3, 0, 0), now); err != nil {
+		return err
+	}
+	// The profile's settings take precedence over the instance's.
+	if err := c.calendar.Apply(calendar); err != nil {
+		return err
+	}
+	c.SetCalendar(calendar)
+	return nil
+}
+
+func (c *Crawler) setConcurrency(size int) {
+	pool := workerpool.New(size)
+	c.CrawlActivities.SetPool(pool)
+	c.relations.SetPool(pool)
+}
+
+// setRateLimit makes all clients of the crawler share one budget of
+// requestsPerSecond, with bursts of up to a second's worth of requests.
+func (c *Crawler) setRateLimit(requestsPerSecond float64) {
+	limiter := httpclient.NewRateLimiter(requestsPerSecond, int(math.Ceil(requestsPerSecond)))
+	for _, client := range c.clients() {
+		client.SetRateLimiter(limiter)
+	}
+}
+
+func (c *Crawler) clients() []*httpclient.APIClient {
+	return []*httpclient.APIClient{
+		c.CrawlProjects.APIClient,
+		c.CrawlWorkPackages.APIClient,
+		c.CrawlActivities.APIClient,
+		c.statuses.APIClient,
+		c.days.APIClient,
+		c.timeEntries.APIClient,
+		c.users.APIClient,
+		c.relations.APIClient,
+		c.versions.APIClient,
+	}
+}
+
+// SetTransport sends every request of the crawler through transport, e.g.
+// an httpclient.Cassette.
+func (c *Crawler) SetTransport(transport http.RoundTripper) {
+	for _, client := range c.clients() {
+		client.SetTransport(transport)
+	}
+}
+
+func (c *Crawler) setPageSize(size int) error {
+	for _, client := range c.clients() {
+		if err := client.SetPageSize(size); err != nil {
+			return err
 		}
-		return 0, false
-	}(projectsID)
-	if !found {
-		return nil, fmt.Errorf("found no valid project name")
-	}
-
-	params := make(map[string]interface{})
-	filters := []map[string]interface{}{
-		{
-			"project": map[string]interface{}{
-				"operator": "=",
-				"values":   []int{validID},
-			},
-		},
-	}
-	filtersJSON, _ := json.Marshal(filters)
-	params["pageSize"] = "1000"
-	params["filters"] = string(filtersJSON)
-	c.SetParams(params)
+	}
+	return nil
+}
 
-	tasksID, err := c.GetTasksID()
+// resolveProjectIDs finds a project by ID, identifier or name, and with
+// includeSubprojects also returns the IDs of all its subprojects.
+func (c *Crawler) resolveProjectIDs(ctx context.Context, projectRef string, includeSubprojects bool) ([]int, error) {
+	project, err := c.LookupProject(ctx, projectRef)
 	if err != nil {
-		return nil, fmt.Errorf("error: %v", err)
+		return nil, err
 	}
-	return tasksID, nil
+	ids := []int{project.ID}
+	if includeSubprojects {
+		subprojects, err := c.GetSubprojects(ctx, project.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, subproject := range subprojects {
+			ids = append(ids, subproject.ID)
+		}
+	}
+	return ids, nil
 }
 
-func main() {
-	apiURL := "https://myopenproject.com/api/v3"
-	username := "apikey"
-	password := "a6a2081fd40e89612e0d362753d3cf843974cfde7c67821c03b9c851dfc1"
-	projectName := "viclass"
+func (c *Crawler) crawlTasksID(ctx context.Context, projectName string, query *wpquery.Query, includeSubprojects bool) ([]int, error) {
+	projectIDs, err := c.resolveProjectIDs(ctx, projectName, includeSubprojects)
+	if err != nil {
+		return nil, err
+	}
 
-	crawler, err := (&Crawler{}).NewCrawler(apiURL, username, password)
+	params, err := query.Clone().Project(projectIDs...).Params()
 	if err != nil {
-		log.Fatalf("Failed to create crawler: %v", err)
+		return nil, err
 	}
+	c.SetParams(params)
 
-	projectsID, err := crawler.crawlProjectsID()
+	tasksID, err := c.GetTasksID(ctx)
 	if err != nil {
-		log.Fatalf("Failed to crawl project IDs: %v", err)
+		return tasksID, fmt.Errorf("error: %w", err)
 	}
+	return tasksID, nil
+}
 
-	fmt.Println("Project IDs:", projectsID)
+// crawlScope is the checkpoint scope of crawling a project with query.
+func crawlScope(query *wpquery.Query, includeSubprojects bool) (checkpoint.Scope, error) {
+	params, err := query.Params()
+	if err != nil {
+		return checkpoint.Scope{}, err
+	}
+	filters, _ := params["filters"].(string)
+	return checkpoint.Scope{Filters: filters, Subprojects: includeSubprojects}, nil
+}
 
-	tasksID, err := crawler.crawlTasksID(projectName)
+// crawlIncremental fetches only the work packages updated since the project's
+// checkpoint in stateDir, refreshes their activities and merges them into the
+// activities kept from earlier runs. Each scope of filters and subprojects
+// has its own checkpoint, which drops work packages that left the scope. The
+// checkpoint only advances when every fetch succeeded, so failed or
+// interrupted runs are retried next time.
+func (c *Crawler) crawlIncremental(ctx context.Context, projectName string, query *wpquery.Query, includeSubprojects bool, stateDir string, scope checkpoint.Scope) ([]model.Task, error) {
+	state, err := checkpoint.Load(stateDir, projectName, scope)
 	if err != nil {
-		log.Fatalf("Failed to crawl task IDs for project '%s': %v", projectName, err)
+		return nil, err
 	}
 
-	crawler.SettasksID(tasksID)
-	tasksActivities, err := crawler.GetTasksActivities()
+	var scopeIDs []int
+	if !state.IsZero() {
+		// Only updated work packages are fetched below, so list the IDs of the
+		// whole scope to drop the ones that left it.
+		if scopeIDs, err = c.crawlTasksID(ctx, projectName, query.Clone().Select("id"), includeSubprojects); err != nil {
+			return nil, fmt.Errorf("failed to crawl task IDs for project %q: %w", projectName, err)
+		}
+	}
+
+	query = query.Clone()
+	if !state.IsZero() {
+		// The bound is inclusive, so packages updated at the checkpoint itself
+		// are fetched again rather than missed.
+		query.UpdatedBetween(state.UpdatedAt, time.Now())
+	}
+	tasksID, err := c.crawlTasksID(ctx, projectName, query, includeSubprojects)
 	if err != nil {
-		log.Fatalf("Failed to crawl tasks activities: %v", err)
+		return nil, fmt.Errorf("failed to crawl task IDs for project %q: %w", projectName, err)
+	}
+	if scopeIDs == nil {
+		scopeIDs = tasksID
 	}
 
-	jsonData, err := json.MarshalIndent(tasksActivities, "", "  ")
+	c.SettasksID(tasksID)
+	fetched, fetchErr := c.FetchTasksData(ctx)
+	state.Merge(c.GetWorkPackagesData(), fetched, fetchErr == nil)
+	state.Prune(scopeIDs)
+	if err := state.Save(stateDir); err != nil {
+		return nil, err
+	}
+
+	c.SetTasksData(state.TasksData())
+	tasks, err := c.MergeTasksData(ctx)
 	if err != nil {
-		log.Fatalf("Failed to marshal task activities to JSON: %v", err)
+		return nil, err
 	}
+	if fetchErr != nil {
+		return tasks, fmt.Errorf("incremental crawl incomplete: %w", fetchErr)
+	}
+	return tasks, nil
+}
 
-	fmt.Println(string(jsonData))
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
 }
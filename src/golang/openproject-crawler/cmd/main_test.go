@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"openproject-crawler/internal/config"
+	"openproject-crawler/internal/credential"
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/internal/workerpool"
+	"openproject-crawler/pkg/wpquery"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// isolateEnv clears the OPENPROJECT_* variables and hides any user config
+// and credential.
+func isolateEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		config.EnvConfig, config.EnvProfile, config.EnvURL, config.EnvProject, config.EnvPageSize, config.EnvConcurrency, config.EnvTimezone, config.EnvStateDir,
+		credential.EnvAPIKey, credential.EnvUsername, credential.EnvPassword, credential.EnvClientID, credential.EnvClientSecret, credential.EnvRefreshToken, credential.EnvSession,
+	} {
+		t.Setenv(name, "")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+}
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		name string
+		rest []string
+		ok   bool
+	}{
+		{nil, "", nil, true},
+		{[]string{"--help"}, "", nil, true},
+		{[]string{"projects", "list", "--format", "table"}, "projects list", []string{"--format", "table"}, true},
+		{[]string{"stats"}, "stats", []string{}, true},
+		{[]string{"version", "burndown", "--version", "3"}, "version burndown", []string{"--version", "3"}, true},
+		{[]string{"versions", "list"}, "versions list", []string{}, true},
+		{[]string{"projects"}, "", nil, false},
+		{[]string{"list", "projects"}, "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			cmd, rest, err := findCommand(tt.args)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v", err)
+			}
+			var name string
+			if cmd != nil {
+				name = cmd.name
+			}
+			if name != tt.name || !reflect.DeepEqual(rest, tt.rest) {
+				t.Errorf("findCommand = %q %q, want %q %q", name, rest, tt.name, tt.rest)
+			}
+			if err != nil && exitCode(err) != exitUsage {
+				t.Errorf("exit code = %d, want %d", exitCode(err), exitUsage)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, 0},
+		{"failure", errors.New("failed to fetch projects"), 1},
+		{"not found", &httpclient.StatusError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}, 1},
+		{"timeout", context.DeadlineExceeded, 1},
+		{"usage", &usageError{msg: "--project is required"}, 2},
+		{"wrapped usage", fmt.Errorf("stats: %w", &usageError{msg: "bad"}), 2},
+		{"credentials", &credentialError{err: errors.New("OPENPROJECT_API_KEY is not set")}, 3},
+		{"unauthorized", fmt.Errorf("failed to fetch URL: %w", &httpclient.StatusError{StatusCode: http.StatusUnauthorized}), 3},
+		{"forbidden", &httpclient.StatusError{StatusCode: http.StatusForbidden}, 3},
+		{"authorize", &httpclient.AuthError{Err: errors.New("invalid_client")}, 3},
+		{"partial", &partialError{err: errors.New("1 of 3 tasks failed")}, 4},
+		{"partial timeout", &partialError{err: context.DeadlineExceeded}, 4},
+		{"interrupted", context.Canceled, 130},
+		{"interrupted partial", &partialError{err: fmt.Errorf("activity crawl interrupted: %w", context.Canceled)}, 130},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	isolateEnv(t)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte(`profiles:
+  prod:
+    api_url: https://openproject.example.com/api/v3
+    project: from-profile
+    page_size: 200
+    rate_limit: 5
+    outputs:
+      - format: csv
+        path: tasks.csv
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		project     string
+		pageSize    int
+		concurrency int
+		rateLimit   float64
+		sinks       []config.Output
+	}{
+		{
+			"profile", nil, nil,
+			"from-profile", 200, workerpool.DefaultSize, 5, []config.Output{{Format: "csv", Path: "tasks.csv"}},
+		},
+		{
+			"environment over profile", nil, map[string]string{config.EnvProject: "from-env", config.EnvConcurrency: "3"},
+			"from-env", 200, 3, 5, []config.Output{{Format: "csv", Path: "tasks.csv"}},
+		},
+		{
+			"flags over environment", []string{"--project", "from-flag", "--page-size", "50", "--rate-limit", "0.5", "--format", "table"}, map[string]string{config.EnvProject: "from-env"},
+			"from-flag", 50, workerpool.DefaultSize, 0.5, []config.Output{{Format: "table"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			opts, err := parseOptions("activities crawl", append([]string{"--config", configPath}, tt.args...), io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			profile := opts.profile
+			if opts.project != tt.project || profile.PageSize != tt.pageSize || profile.Concurrency != tt.concurrency || profile.RateLimit != tt.rateLimit {
+				t.Errorf("project, page size, concurrency, rate limit = %q, %d, %d, %v; want %q, %d, %d, %v",
+					opts.project, profile.PageSize, profile.Concurrency, profile.RateLimit, tt.project, tt.pageSize, tt.concurrency, tt.rateLimit)
+			}
+			if !reflect.DeepEqual(opts.sinks, tt.sinks) {
+				t.Errorf("sinks = %+v, want %+v", opts.sinks, tt.sinks)
+			}
+		})
+	}
+
+	t.Run("defaults without a config", func(t *testing.T) {
+		opts, err := parseOptions("projects list", []string{"--url", "https://openproject.example.com/api/v3"}, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		profile := opts.profile
+		if profile.PageSize != httpclient.DefaultPageSize || profile.Concurrency != workerpool.DefaultSize || profile.RateLimit != httpclient.DefaultRequestsPerSecond {
+			t.Errorf("profile = %+v", profile)
+		}
+		if want := []config.Output{{Format: "json"}}; !reflect.DeepEqual(opts.sinks, want) {
+			t.Errorf("sinks = %+v, want %+v", opts.sinks, want)
+		}
+	})
+}
+
+func TestParseOptionsUsage(t *testing.T) {
+	isolateEnv(t)
+	const url = "https://openproject.example.com/api/v3"
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{"no url", nil, "--url, OPENPROJECT_URL or a config profile api_url is required"},
+		{"unknown format", []string{"--url", url, "--format", "xml"}, `unsupported format "xml"`},
+		{"columns without csv", []string{"--url", url, "--columns", "id"}, "--columns only applies to --format csv"},
+		{"sqlite to stdout", []string{"--url", url, "--format", "sqlite"}, "--format sqlite requires --output"},
+		{"zero concurrency", []string{"--url", url, "--concurrency", "0"}, "--concurrency must be at least 1"},
+		{"zero rate limit", []string{"--url", url, "--rate-limit", "0"}, "--rate-limit must be positive"},
+		{"close policy", []string{"--url", url, "--close-policy", "middle"}, `--close-policy must be one of first, last, got "middle"`},
+		{"bad date", []string{"--url", url, "--from", "31.03.2024"}, "--from must be a date like 2024-03-31"},
+		{"extra argument", []string{"--url", url, "prod"}, "unexpected arguments: prod"},
+		{"missing profile", []string{"--profile", "prod"}, `--profile "prod" given but no config file found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseOptions("activities crawl", tt.args, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			if got := exitCode(err); got != exitUsage {
+				t.Errorf("exit code = %d, want %d", got, exitUsage)
+			}
+		})
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	isolateEnv(t)
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"usage", nil, exitOK},
+		{"help flag", []string{"stats", "-h"}, exitOK},
+		{"unknown command", []string{"crawl"}, exitUsage},
+		{"bad flag", []string{"projects", "list", "--no-such-flag"}, exitUsage},
+		{"no credentials", []string{"projects", "list", "--url", "https://openproject.example.com/api/v3"}, exitAuth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(tt.args, io.Discard, io.Discard); got != tt.want {
+				t.Errorf("run(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlIncrementalDropsLeftWorkPackages(t *testing.T) {
+	stateDir := t.TempDir()
+	query := wpquery.New()
+	scope, err := crawlScope(query, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crawl := func(fixtures *fakeapi.Fixtures) []int {
+		t.Helper()
+		srv := fakeapi.New(fixtures)
+		defer srv.Close()
+		crawler, _ := newTestCrawler(t, srv)
+		tasks, err := crawler.crawlIncremental(context.Background(), "demo", query, false, stateDir, scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, task := range tasks {
+			ids = append(ids, task.TaskInfo.ID)
+		}
+		sort.Ints(ids)
+		return ids
+	}
+
+	if ids, want := crawl(nil), []int{101, 102, 103}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("first crawl = %v, want %v", ids, want)
+	}
+	// Work package 102 is deleted before the next run.
+	fixtures := fakeapi.DefaultFixtures()
+	for i, raw := range fixtures.WorkPackages {
+		if strings.Contains(string(raw), `"id": 102,`) {
+			fixtures.WorkPackages = append(fixtures.WorkPackages[:i], fixtures.WorkPackages[i+1:]...)
+			break
+		}
+	}
+	delete(fixtures.Activities, 102)
+	if ids, want := crawl(fixtures), []int{101, 103}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("second crawl = %v, want %v", ids, want)
+	}
+}
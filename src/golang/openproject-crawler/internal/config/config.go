@@ -0,0 +1,444 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"openproject-crawler/pkg/workcal"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvConfig      = "OPENPROJECT_CONFIG"
+	EnvProfile     = "OPENPROJECT_PROFILE"
+	EnvURL         = "OPENPROJECT_URL"
+	EnvProject     = "OPENPROJECT_PROJECT"
+	EnvPageSize    = "OPENPROJECT_PAGE_SIZE"
+	EnvConcurrency = "OPENPROJECT_CONCURRENCY"
+	EnvTimezone    = "OPENPROJECT_TIMEZONE"
+	EnvStateDir    = "OPENPROJECT_STATE_DIR"
+)
+
+var (
+	OutputFormats = []string{"json", "table", "csv", "jsonl", "sqlite", "dot", "mermaid"}
+	// OutputRecords are the row kinds a crawl of task activities can export
+	// as csv or jsonl: one row per task, or one row per activity change.
+	OutputRecords = []string{"tasks", "activities"}
+	// ClosePolicies choose which close of a reopened task counts: the first
+	// or the last.
+	ClosePolicies = []string{"first", "last"}
+)
+
+type Config struct {
+	DefaultProfile string              `yaml:"default_profile"`
+	Profiles       map[string]*Profile `yaml:"profiles"`
+	path           string
+	root           *yaml.Node
+}
+
+type Profile struct {
+	Name        string           `yaml:"-"`
+	APIURL      string           `yaml:"api_url"`
+	Credentials CredentialSource `yaml:"credentials"`
+	Project     string           `yaml:"project"`
+	PageSize    int              `yaml:"page_size"`
+	Concurrency int              `yaml:"concurrency"`
+	RateLimit   float64          `yaml:"rate_limit"`
+	Timezone    string           `yaml:"timezone"`
+	StateDir    string           `yaml:"state_dir"`
+	ClosePolicy string           `yaml:"close_policy"`
+	Outputs     []Output         `yaml:"outputs"`
+	Analytics   Analytics        `yaml:"analytics"`
+	Calendar    WorkCalendar     `yaml:"work_calendar"`
+}
+
+// WorkCalendar adjusts the working calendar business durations are measured
+// with. WorkingDays replaces the instance's working week, Holidays (dates
+// like 2024-12-24 mapped to a name) add to its non-working days, and the
+// working day starts at DayStart ("09:00") and lasts HoursPerDay hours.
+type WorkCalendar struct {
+	WorkingDays []string          `yaml:"working_days"`
+	DayStart    string            `yaml:"day_start"`
+	HoursPerDay float64           `yaml:"hours_per_day"`
+	Holidays    map[string]string `yaml:"holidays"`
+}
+
+// Apply sets the calendar's working days, hours and holidays; unset fields
+// leave the calendar as it is.
+func (w WorkCalendar) Apply(calendar *workcal.Calendar) error {
+	if len(w.WorkingDays) > 0 {
+		days := make([]time.Weekday, len(w.WorkingDays))
+		for i, name := range w.WorkingDays {
+			day, err := workcal.ParseWeekday(name)
+			if err != nil {
+				return err
+			}
+			days[i] = day
+		}
+		calendar.SetWorkingDays(days...)
+	}
+	if w.DayStart != "" || w.HoursPerDay > 0 {
+		start := 9 * time.Hour
+		if w.DayStart != "" {
+			clock, err := time.Parse("15:04", w.DayStart)
+			if err != nil {
+				return fmt.Errorf("day start must be a time like 09:00, got %q", w.DayStart)
+			}
+			start = time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute
+		}
+		hours := calendar.HoursPerDay()
+		if w.HoursPerDay > 0 {
+			hours = w.HoursPerDay
+		}
+		if err := calendar.SetWorkingHours(start, start+time.Duration(hours*float64(time.Hour))); err != nil {
+			return err
+		}
+	}
+	for value, name := range w.Holidays {
+		date, err := time.ParseInLocation("2006-01-02", value, calendar.GetLocation())
+		if err != nil {
+			return fmt.Errorf("holiday must be a date like 2024-12-24, got %q", value)
+		}
+		calendar.AddHoliday(date, name)
+	}
+	return nil
+}
+
+// Analytics names the statuses stats measures with: cycle time starts at the
+// first start status, and lead and cycle time end at an end status. Empty
+// lists keep the defaults; end statuses default to the closed statuses.
+type Analytics struct {
+	StartStatuses []string `yaml:"start_statuses"`
+	EndStatuses   []string `yaml:"end_statuses"`
+}
+
+// CredentialSource names where a profile's credential comes from: Env is the
+// name of an environment variable holding an API key, File a credential file
+// and Command a helper printing the credential file format to stdout.
+type CredentialSource struct {
+	Env     string `yaml:"env"`
+	File    string `yaml:"file"`
+	Command string `yaml:"command"`
+}
+
+type Output struct {
+	Format  string   `yaml:"format"`
+	Path    string   `yaml:"path"`
+	Columns []string `yaml:"columns"`
+	Records string   `yaml:"records"`
+}
+
+// ValidationError points at the offending key, e.g. "profiles.prod.page_size".
+type ValidationError struct {
+	File string
+	Key  string
+	Line int
+	Msg  string
+}
+
+func (e *ValidationError) Error() string {
+	location := e.Key
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s (line %d)", e.Key, e.Line)
+	}
+	if e.File != "" {
+		location = e.File + ": " + location
+	}
+	return location + ": " + e.Msg
+}
+
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "openproject-crawler", "config.yaml")
+}
+
+// DefaultStateDir is where incremental crawls keep their checkpoints.
+func DefaultStateDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "openproject-crawler", "checkpoints")
+}
+
+// Locate returns the config file to use: path if given, then
+// OPENPROJECT_CONFIG, then DefaultPath if it exists. An empty result means
+// no config file.
+func Locate(path string) string {
+	if path != "" {
+		return path
+	}
+	if path = os.Getenv(EnvConfig); path != "" {
+		return path
+	}
+	if path = DefaultPath(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Parse(path, content)
+}
+
+func Parse(path string, content []byte) (*Config, error) {
+	cfg := &Config{path: path, root: &yaml.Node{}}
+	if err := yaml.Unmarshal(content, cfg.root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name, profile := range cfg.Profiles {
+		if profile == nil {
+			profile = &Profile{}
+			cfg.Profiles[name] = profile
+		}
+		profile.Name = name
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns a copy of the named profile with environment overrides
+// applied. Without a name it uses OPENPROJECT_PROFILE, then default_profile,
+// then the only profile if there is exactly one.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" && len(c.Profiles) == 1 {
+		name = c.ProfileNames()[0]
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%s: no profile selected; choose one of %s", c.path, strings.Join(c.ProfileNames(), ", "))
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown profile %q; choose one of %s", c.path, name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	selected := *profile
+	selected.Outputs = append([]Output(nil), profile.Outputs...)
+	if err := ApplyEnv(&selected); err != nil {
+		return nil, err
+	}
+	return &selected, nil
+}
+
+// ApplyEnv overrides profile values with OPENPROJECT_URL, OPENPROJECT_PROJECT,
+// OPENPROJECT_PAGE_SIZE, OPENPROJECT_CONCURRENCY, OPENPROJECT_TIMEZONE and
+// OPENPROJECT_STATE_DIR.
+func ApplyEnv(profile *Profile) error {
+	if value := os.Getenv(EnvURL); value != "" {
+		profile.APIURL = value
+	}
+	if value := os.Getenv(EnvProject); value != "" {
+		profile.Project = value
+	}
+	if value := os.Getenv(EnvTimezone); value != "" {
+		profile.Timezone = value
+	}
+	if value := os.Getenv(EnvStateDir); value != "" {
+		profile.StateDir = value
+	}
+	for name, target := range map[string]*int{EnvPageSize: &profile.PageSize, EnvConcurrency: &profile.Concurrency} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 1 {
+			return fmt.Errorf("%s: must be a positive integer, got %q", name, value)
+		}
+		*target = number
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if c.DefaultProfile != "" {
+		if _, ok := c.Profiles[c.DefaultProfile]; !ok {
+			return c.invalid("unknown profile "+strconv.Quote(c.DefaultProfile), "default_profile")
+		}
+	}
+	for _, name := range c.ProfileNames() {
+		profile := c.Profiles[name]
+		key := func(field ...string) []string {
+			return append([]string{"profiles", name}, field...)
+		}
+
+		if profile.APIURL == "" {
+			return c.invalid("is required", key("api_url")...)
+		}
+		if parsed, err := url.Parse(profile.APIURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return c.invalid("must be an absolute URL such as https://host/api/v3", key("api_url")...)
+		}
+		sources := 0
+		for _, source := range []string{profile.Credentials.Env, profile.Credentials.File, profile.Credentials.Command} {
+			if source != "" {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return c.invalid("set only one of env, file or command", key("credentials")...)
+		}
+		if profile.PageSize < 0 {
+			return c.invalid("must be positive", key("page_size")...)
+		}
+		if profile.Concurrency < 0 {
+			return c.invalid("must be positive", key("concurrency")...)
+		}
+		if profile.RateLimit < 0 {
+			return c.invalid("must be positive", key("rate_limit")...)
+		}
+		if profile.Timezone != "" {
+			if _, err := time.LoadLocation(profile.Timezone); err != nil {
+				return c.invalid("unknown time zone "+strconv.Quote(profile.Timezone), key("timezone")...)
+			}
+		}
+		if profile.ClosePolicy != "" && !contains(ClosePolicies, profile.ClosePolicy) {
+			return c.invalid(fmt.Sprintf("must be one of %s", strings.Join(ClosePolicies, ", ")), key("close_policy")...)
+		}
+		if profile.Calendar.HoursPerDay < 0 {
+			return c.invalid("must be positive", key("work_calendar", "hours_per_day")...)
+		}
+		for i, name := range profile.Calendar.WorkingDays {
+			if _, err := workcal.ParseWeekday(name); err != nil {
+				return c.invalid(err.Error(), key("work_calendar", "working_days", strconv.Itoa(i))...)
+			}
+		}
+		for _, date := range sortedKeys(profile.Calendar.Holidays) {
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return c.invalid("must be a date like 2024-12-24", key("work_calendar", "holidays", date)...)
+			}
+		}
+		if err := profile.Calendar.Apply(workcal.NewCalendar()); err != nil {
+			return c.invalid(err.Error(), key("work_calendar")...)
+		}
+		for i, status := range profile.Analytics.StartStatuses {
+			if strings.TrimSpace(status) == "" {
+				return c.invalid("must not be empty", key("analytics", "start_statuses", strconv.Itoa(i))...)
+			}
+		}
+		for i, status := range profile.Analytics.EndStatuses {
+			if strings.TrimSpace(status) == "" {
+				return c.invalid("must not be empty", key("analytics", "end_statuses", strconv.Itoa(i))...)
+			}
+		}
+		for i, output := range profile.Outputs {
+			index := strconv.Itoa(i)
+			if !contains(OutputFormats, output.Format) {
+				return c.invalid(fmt.Sprintf("must be one of %s", strings.Join(OutputFormats, ", ")), key("outputs", index, "format")...)
+			}
+			if output.Records != "" && !contains(OutputRecords, output.Records) {
+				return c.invalid(fmt.Sprintf("must be one of %s", strings.Join(OutputRecords, ", ")), key("outputs", index, "records")...)
+			}
+			if output.Format == "sqlite" && output.Path == "" {
+				return c.invalid("is required for the sqlite format", key("outputs", index, "path")...)
+			}
+			if len(output.Columns) > 0 && output.Format != "csv" {
+				return c.invalid("only applies to the csv format", key("outputs", index, "columns")...)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) invalid(msg string, path ...string) error {
+	return &ValidationError{
+		File: c.path,
+		Key:  strings.Join(path, "."),
+		Line: lineOf(c.root, path),
+		Msg:  msg,
+	}
+}
+
+// lineOf finds the line of the deepest node along path that exists, so a
+// missing key is reported at its parent.
+func lineOf(node *yaml.Node, path []string) int {
+	if node == nil {
+		return 0
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := node.Line
+	for _, key := range path {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(key); err == nil && index < len(node.Content) {
+				next = node.Content[index]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+	return line
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"openproject-crawler/pkg/workcal"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkCalendar(t *testing.T) {
+	const base = `profiles:
+  prod:
+    api_url: https://openproject.example.com/api/v3
+    work_calendar:
+`
+	tests := []struct {
+		name     string
+		calendar string
+		days     []time.Weekday
+		hours    float64
+		holidays map[string]string
+		err      string
+	}{
+		{"defaults", "      {}\n", []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, 8, map[string]string{}, ""},
+		{
+			"custom",
+			"      working_days: [sunday, mon, Tuesday, wednesday, thursday]\n      day_start: \"07:30\"\n      hours_per_day: 6.5\n      holidays:\n        2024-12-24: Christmas Eve\n",
+			[]time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday},
+			6.5,
+			map[string]string{"2024-12-24": "Christmas Eve"},
+			"",
+		},
+		{"unknown weekday", "      working_days: [monday, funday]\n", nil, 0, nil, `work_calendar.working_days.1 (line 5): unknown weekday "funday"`},
+		{"bad holiday", "      holidays:\n        24.12.2024: Christmas Eve\n", nil, 0, nil, "work_calendar.holidays.24.12.2024"},
+		{"bad day start", "      day_start: 9am\n", nil, 0, nil, `day start must be a time like 09:00, got "9am"`},
+		{"past midnight", "      day_start: \"20:00\"\n      hours_per_day: 6\n", nil, 0, nil, "invalid working hours"},
+		{"negative hours", "      hours_per_day: -1\n", nil, 0, nil, "work_calendar.hours_per_day (line 5): must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse("config.yaml", []byte(base+tt.calendar))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			profile, err := cfg.Profile("prod")
+			if err != nil {
+				t.Fatal(err)
+			}
+			calendar := workcal.NewCalendar()
+			if err := profile.Calendar.Apply(calendar); err != nil {
+				t.Fatal(err)
+			}
+			if got := calendar.GetWorkingDays(); !reflect.DeepEqual(got, tt.days) {
+				t.Errorf("working days = %v, want %v", got, tt.days)
+			}
+			if got := calendar.HoursPerDay(); got != tt.hours {
+				t.Errorf("hours per day = %v, want %v", got, tt.hours)
+			}
+			if got := calendar.Holidays(); !reflect.DeepEqual(got, tt.holidays) {
+				t.Errorf("holidays = %v, want %v", got, tt.holidays)
+			}
+		})
+	}
+}
+
+func TestClosePolicy(t *testing.T) {
+	const base = `profiles:
+  prod:
+    api_url: https://openproject.example.com/api/v3
+`
+	if _, err := Parse("config.yaml", []byte(base+"    close_policy: last\n")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Parse("config.yaml", []byte(base+"    close_policy: middle\n"))
+	if want := "close_policy (line 4): must be one of first, last"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	const base = `profiles:
+  prod:
+    api_url: https://openproject.example.com/api/v3
+`
+	cfg, err := Parse("config.yaml", []byte(base+"    rate_limit: 2.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile, err := cfg.Profile("prod"); err != nil || profile.RateLimit != 2.5 {
+		t.Errorf("rate limit = %v, %v; want 2.5", profile, err)
+	}
+	_, err = Parse("config.yaml", []byte(base+"    rate_limit: -1\n"))
+	if want := "rate_limit (line 4): must be positive"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
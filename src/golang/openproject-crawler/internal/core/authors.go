@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"sync"
+)
+
+// UserResolver looks up the user behind an activity's user link.
+type UserResolver interface {
+	ResolveUser(ctx context.Context, id int) (model.User, error)
+}
+
+func (dp *DataParser) GetUserResolver() UserResolver {
+	return dp.users
+}
+
+// SetUserResolver makes ResolveAuthors look up activity authors through
+// users. Without a resolver, authors only carry the name from the link.
+func (dp *DataParser) SetUserResolver(users UserResolver) {
+	dp.users = users
+}
+
+// ResolveAuthors looks up the author of every activity in the data input,
+// so that MergeData can add their names and logins. Authors that cannot be
+// resolved, e.g. deleted users, keep the name from the activity's link.
+func (dp *DataParser) ResolveAuthors(ctx context.Context) error {
+	if dp.users == nil {
+		return nil
+	}
+	ids := make(map[int]bool)
+	for _, item := range dp.dataInput {
+		for _, activity := range item {
+			if id, ok := activity.Links.User.ID(); ok {
+				if _, known := dp.authors[id]; !known {
+					ids[id] = true
+				}
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for id := range ids {
+		if err := dp.pool.Go(ctx, &wg, func() {
+			user, err := dp.users.ResolveUser(ctx, id)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			dp.authors[id] = user
+			mu.Unlock()
+		}); err != nil {
+			wg.Wait()
+			return err
+		}
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// GetAuthors returns the users ResolveAuthors found, ordered by ID.
+func (dp *DataParser) GetAuthors() []model.User {
+	users := make([]model.User, 0, len(dp.authors))
+	for _, user := range dp.authors {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users
+}
+
+func (dp *DataParser) author(link model.Link) *model.Author {
+	id, ok := link.ID()
+	if !ok {
+		return nil
+	}
+	author := &model.Author{ID: id, Name: link.Title}
+	if user, ok := dp.authors[id]; ok {
+		author.Name = user.Name
+		author.Login = user.Login
+	}
+	return author
+}
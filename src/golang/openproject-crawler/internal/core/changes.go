@@ -0,0 +1,146 @@
+package core
+
+import (
+	"html"
+	"openproject-crawler/pkg/model"
+	"regexp"
+	"strings"
+)
+
+var fieldKeys = map[string]string{
+	"status":                  "status",
+	"type":                    "type",
+	"project":                 "project",
+	"subject":                 "subject",
+	"description":             "description",
+	"priority":                "priority",
+	"assignee":                "assignee",
+	"accountable":             "responsible",
+	"responsible":             "responsible",
+	"author":                  "author",
+	"version":                 "version",
+	"category":                "category",
+	"parent":                  "parent",
+	"start date":              "startDate",
+	"finish date":             "dueDate",
+	"due date":                "dueDate",
+	"date":                    "date",
+	"duration":                "duration",
+	"estimated time":          "estimatedTime",
+	"work":                    "estimatedTime",
+	"remaining hours":         "remainingTime",
+	"remaining work":          "remainingTime",
+	"spent time":              "spentTime",
+	"story points":            "storyPoints",
+	"% complete":              "percentageDone",
+	"progress (%)":            "percentageDone",
+	"scheduling mode":         "scheduleManually",
+	"ignore non working days": "ignoreNonWorkingDays",
+}
+
+var (
+	changedFromPattern = regexp.MustCompile(`^(.+?) changed from (.*?) to (.*)$`)
+	setToPattern       = regexp.MustCompile(`^(.+?) set to (.*)$`)
+	deletedPattern     = regexp.MustCompile(`^(.+?) deleted(?: \((.*)\))?$`)
+	textDiffPattern    = regexp.MustCompile(`^(.+?) (changed|set) \(.*\)$`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]*>`)
+	htmlFieldPattern   = regexp.MustCompile(`(?s)^\s*<strong>(.*?)</strong>(.*)$`)
+	htmlValuePattern   = regexp.MustCompile(`(?s)<i[^>]*>(.*?)</i>`)
+)
+
+// ParseDetail turns one activity detail into a structured change. The html
+// variant is tried first, since it marks up the values and so keeps a value
+// containing " to " intact; the raw text is used when html is empty or does
+// not follow a known pattern.
+func ParseDetail(detail model.Formattable) (model.FieldChange, bool) {
+	if detail.HTML != "" {
+		if change, ok := parseHTMLDetail(detail.HTML); ok {
+			return change, true
+		}
+	}
+	return parseRawDetail(strings.TrimSpace(detail.Raw))
+}
+
+func ParseDetails(details []model.Formattable) []model.FieldChange {
+	changes := []model.FieldChange{}
+	for _, detail := range details {
+		if change, ok := ParseDetail(detail); ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func parseRawDetail(raw string) (model.FieldChange, bool) {
+	if raw == "" {
+		return model.FieldChange{}, false
+	}
+	if m := textDiffPattern.FindStringSubmatch(raw); m != nil {
+		kind := model.ChangeChanged
+		if m[2] == "set" {
+			kind = model.ChangeSet
+		}
+		return newFieldChange(m[1], "", "", kind), true
+	}
+	if m := changedFromPattern.FindStringSubmatch(raw); m != nil {
+		return newFieldChange(m[1], m[2], m[3], model.ChangeChanged), true
+	}
+	if m := setToPattern.FindStringSubmatch(raw); m != nil {
+		return newFieldChange(m[1], "", m[2], model.ChangeSet), true
+	}
+	if m := deletedPattern.FindStringSubmatch(raw); m != nil {
+		return newFieldChange(m[1], m[2], "", model.ChangeDeleted), true
+	}
+	return model.FieldChange{}, false
+}
+
+func parseHTMLDetail(text string) (model.FieldChange, bool) {
+	m := htmlFieldPattern.FindStringSubmatch(text)
+	if m == nil {
+		return parseRawDetail(stripHTML(text))
+	}
+	label := stripHTML(m[1])
+	rest := m[2]
+
+	var values []string
+	for _, value := range htmlValuePattern.FindAllStringSubmatch(rest, -1) {
+		values = append(values, stripHTML(value[1]))
+	}
+	verb := stripHTML(htmlValuePattern.ReplaceAllString(rest, ""))
+
+	switch {
+	case strings.HasPrefix(verb, "changed from") && len(values) == 2:
+		return newFieldChange(label, values[0], values[1], model.ChangeChanged), true
+	case strings.HasPrefix(verb, "set to") && len(values) == 1:
+		return newFieldChange(label, "", values[0], model.ChangeSet), true
+	case strings.HasPrefix(verb, "deleted"):
+		from := ""
+		if len(values) > 0 {
+			from = values[0]
+		}
+		return newFieldChange(label, from, "", model.ChangeDeleted), true
+	}
+	return parseRawDetail(label + " " + stripHTML(rest))
+}
+
+func newFieldChange(label, from, to string, kind model.ChangeKind) model.FieldChange {
+	label = strings.TrimSpace(label)
+	change := model.FieldChange{
+		Field: label,
+		Label: label,
+		From:  strings.TrimSpace(from),
+		To:    strings.TrimSpace(to),
+		Kind:  kind,
+	}
+	if key, ok := fieldKeys[strings.ToLower(label)]; ok {
+		change.Field = key
+	} else {
+		change.Custom = true
+	}
+	return change
+}
+
+func stripHTML(text string) string {
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
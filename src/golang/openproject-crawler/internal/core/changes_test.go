@@ -0,0 +1,84 @@
+package core
+
+import (
+	"openproject-crawler/pkg/model"
+	"reflect"
+	"testing"
+)
+
+func TestParseDetail(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail model.Formattable
+		want   model.FieldChange
+		ok     bool
+	}{
+		{
+			"value containing to",
+			model.Formattable{
+				Raw:  "Subject changed from Move A to B to C",
+				HTML: "<strong>Subject</strong> changed from <i>Move A to B</i> <strong>to</strong> <i>C</i>",
+			},
+			model.FieldChange{Field: "subject", Label: "Subject", From: "Move A to B", To: "C", Kind: model.ChangeChanged},
+			true,
+		},
+		{
+			"raw only",
+			model.Formattable{Raw: "Status changed from New to In progress"},
+			model.FieldChange{Field: "status", Label: "Status", From: "New", To: "In progress", Kind: model.ChangeChanged},
+			true,
+		},
+		{
+			"set in html",
+			model.Formattable{Raw: "Assignee set to Ada Admin", HTML: "<strong>Assignee</strong> set to <i title=\"Ada Admin\">Ada Admin</i>"},
+			model.FieldChange{Field: "assignee", Label: "Assignee", To: "Ada Admin", Kind: model.ChangeSet},
+			true,
+		},
+		{
+			"set in raw",
+			model.Formattable{Raw: "Type set to Bug"},
+			model.FieldChange{Field: "type", Label: "Type", To: "Bug", Kind: model.ChangeSet},
+			true,
+		},
+		{
+			"deleted in html",
+			model.Formattable{Raw: "Category deleted (UI)", HTML: "<strong>Category</strong> deleted (<strike><i>UI</i></strike>)"},
+			model.FieldChange{Field: "category", Label: "Category", From: "UI", Kind: model.ChangeDeleted},
+			true,
+		},
+		{
+			"deleted in raw",
+			model.Formattable{Raw: "Version deleted (Sprint 1)"},
+			model.FieldChange{Field: "version", Label: "Version", From: "Sprint 1", Kind: model.ChangeDeleted},
+			true,
+		},
+		{
+			"text diff",
+			model.Formattable{Raw: "Description changed (https://example.com/journals/1/diff/description)", HTML: "<strong>Description</strong> changed (<a href=\"/journals/1/diff/description\">Details</a>)"},
+			model.FieldChange{Field: "description", Label: "Description", Kind: model.ChangeChanged},
+			true,
+		},
+		{
+			"custom field with entities",
+			model.Formattable{HTML: "<strong>Team</strong> changed from <i>R&amp;D</i> <strong>to</strong> <i>Sales &amp; Ops</i>"},
+			model.FieldChange{Field: "Team", Label: "Team", From: "R&D", To: "Sales & Ops", Kind: model.ChangeChanged, Custom: true},
+			true,
+		},
+		{
+			"html without a change",
+			model.Formattable{Raw: "Status changed from New to Closed", HTML: "<p>Closed by the release script</p>"},
+			model.FieldChange{Field: "status", Label: "Status", From: "New", To: "Closed", Kind: model.ChangeChanged},
+			true,
+		},
+		{"comment", model.Formattable{Raw: "Looks good", HTML: "<p>Looks good</p>"}, model.FieldChange{}, false},
+		{"empty", model.Formattable{}, model.FieldChange{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, ok := ParseDetail(tt.detail)
+			if ok != tt.ok || !reflect.DeepEqual(change, tt.want) {
+				t.Errorf("ParseDetail = %+v, %v; want %+v, %v", change, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
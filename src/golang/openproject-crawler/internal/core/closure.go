@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"openproject-crawler/pkg/model"
+	"sort"
+)
+
+type ClosePolicy string
+
+const (
+	FirstClose ClosePolicy = "first"
+	LastClose  ClosePolicy = "last"
+)
+
+// CloseRuleDefault marks closures detected by the built-in "Closed" status
+// name; CloseRuleIsClosed marks closures detected via the isClosed flag of
+// the statuses fetched from /statuses.
+const (
+	CloseRuleDefault  = "default"
+	CloseRuleIsClosed = "isClosed"
+)
+
+var defaultClosedStatuses = []string{"Closed"}
+
+type closureDetector struct {
+	closed map[string]bool
+	rule   string
+	policy ClosePolicy
+}
+
+type closure struct {
+	closedDate string
+	status     string
+	reopens    int
+}
+
+func newClosureDetector() closureDetector {
+	closed := make(map[string]bool, len(defaultClosedStatuses))
+	for _, name := range defaultClosedStatuses {
+		closed[name] = true
+	}
+	return closureDetector{
+		closed: closed,
+		rule:   CloseRuleDefault,
+		policy: FirstClose,
+	}
+}
+
+// detect walks the status changes in order, starting from the status the
+// task was created in at createdDate; a task created in a closed status is
+// closed from the start. With FirstClose the first move into a closed status
+// wins; with LastClose the latest one does and a reopen afterwards leaves the
+// task without a closed date.
+func (d closureDetector) detect(createdDate, createdStatus string, activities []model.TaskActivity) closure {
+	var result closure
+	wasClosed := d.closed[createdStatus]
+	if wasClosed {
+		result.closedDate = createdDate
+		result.status = createdStatus
+	}
+	for _, activity := range activities {
+		for _, change := range activity.Changes {
+			if change.Field != "status" || change.Kind == model.ChangeDeleted {
+				continue
+			}
+			if change.From != "" {
+				wasClosed = d.closed[change.From]
+			}
+			isClosed := d.closed[change.To]
+			switch {
+			case isClosed && !wasClosed:
+				if result.closedDate == "" || d.policy == LastClose {
+					result.closedDate = activity.DateTime
+					result.status = change.To
+				}
+			case !isClosed && wasClosed:
+				result.reopens++
+				if d.policy == LastClose {
+					result.closedDate = ""
+					result.status = ""
+				}
+			}
+			wasClosed = isClosed
+		}
+	}
+	return result
+}
+
+// SetClosedStatuses switches closure detection to the statuses flagged
+// isClosed. An empty list restores the default "Closed" status.
+func (dp *DataParser) SetClosedStatuses(statuses []model.Status) {
+	if len(statuses) == 0 {
+		policy := dp.closure.policy
+		dp.closure = newClosureDetector()
+		dp.closure.policy = policy
+		return
+	}
+	closed := make(map[string]bool)
+	for _, status := range statuses {
+		if status.IsClosed {
+			closed[status.Name] = true
+		}
+	}
+	dp.closure.closed = closed
+	dp.closure.rule = CloseRuleIsClosed
+}
+
+func (dp *DataParser) GetClosedStatuses() []string {
+	names := make([]string, 0, len(dp.closure.closed))
+	for name := range dp.closure.closed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (dp *DataParser) GetClosePolicy() ClosePolicy {
+	return dp.closure.policy
+}
+
+func (dp *DataParser) SetClosePolicy(policy ClosePolicy) error {
+	switch policy {
+	case FirstClose, LastClose:
+		dp.closure.policy = policy
+		return nil
+	}
+	return fmt.Errorf("unknown close policy %q", policy)
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"openproject-crawler/pkg/model"
+	"testing"
+	"time"
+)
+
+func statusActivity(dateTime, from, to string) model.TaskActivity {
+	kind := model.ChangeChanged
+	if from == "" {
+		kind = model.ChangeSet
+	}
+	return model.TaskActivity{
+		DateTime: dateTime,
+		Changes:  []model.FieldChange{{Field: "status", From: from, To: to, Kind: kind}},
+	}
+}
+
+func TestDetectClosure(t *testing.T) {
+	const created = "2024-03-04 09:00:00"
+	reopened := []model.TaskActivity{
+		statusActivity("2024-03-05 09:00:00", "New", "Closed"),
+		statusActivity("2024-03-06 09:00:00", "Closed", "In progress"),
+		statusActivity("2024-03-07 09:00:00", "In progress", "Closed"),
+	}
+	tests := []struct {
+		name       string
+		policy     ClosePolicy
+		status     string
+		activities []model.TaskActivity
+		closedDate string
+		reopens    int
+	}{
+		{"never closed", FirstClose, "New", []model.TaskActivity{statusActivity("2024-03-05 09:00:00", "New", "In progress")}, "", 0},
+		{"first close", FirstClose, "New", reopened, "2024-03-05 09:00:00", 1},
+		{"last close", LastClose, "New", reopened, "2024-03-07 09:00:00", 1},
+		{"reopened and open", LastClose, "New", reopened[:2], "", 1},
+		{"created closed", FirstClose, "Closed", nil, created, 0},
+		{"created closed, last close", LastClose, "Closed", nil, created, 0},
+		{"created closed and reopened", FirstClose, "Closed", []model.TaskActivity{statusActivity("2024-03-05 09:00:00", "", "In progress")}, created, 1},
+		{"created closed, reopened, last close", LastClose, "Closed", []model.TaskActivity{statusActivity("2024-03-05 09:00:00", "", "In progress")}, "", 1},
+		{"closed without from", FirstClose, "", []model.TaskActivity{statusActivity("2024-03-05 09:00:00", "", "Closed")}, "2024-03-05 09:00:00", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := newClosureDetector()
+			detector.policy = tt.policy
+			result := detector.detect(created, tt.status, tt.activities)
+			if result.closedDate != tt.closedDate || result.reopens != tt.reopens {
+				t.Errorf("closed at %q with %d reopens, want %q with %d", result.closedDate, result.reopens, tt.closedDate, tt.reopens)
+			}
+		})
+	}
+}
+
+func TestSetClosePolicy(t *testing.T) {
+	dp := NewDataParser(nil)
+	if got := dp.GetClosePolicy(); got != FirstClose {
+		t.Errorf("default policy = %q, want first", got)
+	}
+	if err := dp.SetClosePolicy(LastClose); err != nil {
+		t.Fatal(err)
+	}
+	// Falling back to the default statuses keeps the policy.
+	dp.SetClosedStatuses(nil)
+	if got := dp.GetClosePolicy(); got != LastClose {
+		t.Errorf("policy = %q after resetting statuses, want last", got)
+	}
+	if err := dp.SetClosePolicy("middle"); err == nil {
+		t.Error("unknown policy accepted")
+	}
+}
+
+func TestParseItemCreatedClosed(t *testing.T) {
+	dp := NewDataParser(nil)
+	dp.GetCalendar().SetLocation(time.UTC)
+	dp.SetClosedStatuses([]model.Status{{Name: "Closed", IsClosed: true}, {Name: "Done", IsClosed: true}})
+
+	created := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	creation := model.Activity{
+		Type:      model.ActivityType,
+		CreatedAt: created,
+		Details: []model.Formattable{
+			{Raw: "Subject set to Imported"},
+			{Raw: "Status set to Done"},
+		},
+	}
+	creation.Links.WorkPackage = model.Link{Href: "/api/v3/work_packages/7"}
+	comment := model.Activity{
+		Type:      model.ActivityType,
+		CreatedAt: created.Add(time.Hour),
+		Details:   []model.Formattable{{Raw: "Priority changed from Normal to High"}},
+	}
+	comment.Links.WorkPackage = creation.Links.WorkPackage
+
+	task, err := dp.parseItem([]model.Activity{creation, comment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.TaskInfo.ClosedDate != "2024-03-04 09:00:00" || task.TaskInfo.ClosedStatus != "Done" {
+		t.Errorf("closed %q in %q, want at creation in Done", task.TaskInfo.ClosedDate, task.TaskInfo.ClosedStatus)
+	}
+	if task.TaskInfo.Durations == nil || task.TaskInfo.Durations.CalendarHours != 0 {
+		t.Errorf("durations = %+v, want zero", task.TaskInfo.Durations)
+	}
+}
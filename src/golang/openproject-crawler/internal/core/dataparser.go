@@ -1,23 +1,37 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"openproject-crawler/internal/workerpool"
+	"openproject-crawler/pkg/model"
+	"openproject-crawler/pkg/workcal"
 	"strings"
 	"sync"
 	"time"
 )
 
 type DataParser struct {
-	dataInput     [][]map[string]interface{}
+	dataInput     [][]model.Activity
+	pool          *workerpool.Pool
+	closure       closureDetector
+	calendar      *workcal.Calendar
+	users         UserResolver
+	authors       map[int]model.User
 	TextFiltering map[string]string
 }
 
-func NewDataParser(dataInput [][]map[string]interface{}) *DataParser {
+func NewDataParser(dataInput [][]model.Activity) *DataParser {
 	if dataInput == nil {
-		dataInput = [][]map[string]interface{}{}
+		dataInput = [][]model.Activity{}
 	}
 	return &DataParser{
 		dataInput: dataInput,
+		pool:      workerpool.New(workerpool.DefaultSize),
+		closure:   newClosureDetector(),
+		calendar:  workcal.NewCalendar(),
+		authors:   make(map[int]model.User),
 		TextFiltering: map[string]string{
 			"type":     "Type set to ",
 			"project":  "Project set to ",
@@ -27,168 +41,162 @@ func NewDataParser(dataInput [][]map[string]interface{}) *DataParser {
 	}
 }
 
-func (dp *DataParser) GetDataInput() [][]map[string]interface{} {
+func (dp *DataParser) GetDataInput() [][]model.Activity {
 	return dp.dataInput
 }
 
-func (dp *DataParser) SetDataInput(value [][]map[string]interface{}) {
+func (dp *DataParser) SetDataInput(value [][]model.Activity) {
 	dp.dataInput = value
 }
 
-func convertTime(timestamp string) (string, error) {
-	datetimeObj, err := time.Parse(time.RFC3339Nano, timestamp)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse timestamp: %v", err)
+func (dp *DataParser) GetPool() *workerpool.Pool {
+	return dp.pool
+}
+
+func (dp *DataParser) SetPool(pool *workerpool.Pool) {
+	dp.pool = pool
+}
+
+func (dp *DataParser) GetCalendar() *workcal.Calendar {
+	return dp.calendar
+}
+
+func (dp *DataParser) SetCalendar(calendar *workcal.Calendar) {
+	dp.calendar = calendar
+}
+
+func convertTime(timestamp time.Time, location *time.Location) (string, error) {
+	if timestamp.IsZero() {
+		return "", fmt.Errorf("failed to parse timestamp: empty time")
 	}
-	localTimezone := time.Now().Location()
-	localDatetimeObj := datetimeObj.In(localTimezone)
-	return localDatetimeObj.Format("2006-01-02 15:04:05"), nil
+	return timestamp.In(location).Format(model.DateTimeLayout), nil
 }
 
-func calculateDuration(start, end string) (int, error) {
-	const layout = "2006-01-02 15:04:05"
-	startDate, err := time.Parse(layout, start)
+func (dp *DataParser) calculateDuration(start, end string) (model.Durations, error) {
+	location := dp.calendar.GetLocation()
+	startDate, err := time.ParseInLocation(model.DateTimeLayout, start, location)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse start date: %v", err)
+		return model.Durations{}, fmt.Errorf("failed to parse start date: %v", err)
 	}
-	endDate, err := time.Parse(layout, end)
+	endDate, err := time.ParseInLocation(model.DateTimeLayout, end, location)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse end date: %v", err)
+		return model.Durations{}, fmt.Errorf("failed to parse end date: %v", err)
 	}
 	duration := endDate.Sub(startDate)
-	return int(duration.Hours() / 24), nil
+	return model.Durations{
+		CalendarDays:  round2(duration.Hours() / 24),
+		CalendarHours: round2(duration.Hours()),
+		BusinessDays:  round2(dp.calendar.BusinessDays(startDate, endDate)),
+		BusinessHours: round2(dp.calendar.WorkingDuration(startDate, endDate).Hours()),
+	}, nil
 }
 
-func parseActivity(element map[string]interface{}) (map[string]interface{}, string, error) {
-	var closedDate string
-	activity := map[string]interface{}{
-		"dateTime": "",
-		"action":   []string{},
-	}
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
 
-	createdAt, ok := element["createdAt"].(string)
-	if !ok {
-		return nil, "", fmt.Errorf("missing or invalid 'createdAt' field")
+func parseActivity(element model.Activity, location *time.Location) (model.TaskActivity, error) {
+	activity := model.TaskActivity{
+		ID:      element.ID,
+		Action:  []string{},
+		Changes: []model.FieldChange{},
 	}
 
-	dateTime, err := convertTime(createdAt)
+	dateTime, err := convertTime(element.CreatedAt, location)
 	if err != nil {
-		return nil, "", err
-	}
-	activity["dateTime"] = dateTime
-
-	details, ok := element["details"].([]interface{})
-	if !ok {
-		return nil, "", fmt.Errorf("missing or invalid 'details' field")
+		return activity, fmt.Errorf("missing or invalid 'createdAt' field: %w", err)
 	}
+	activity.DateTime = dateTime
 
-	for _, detail := range details {
-		detailMap, ok := detail.(map[string]interface{})
-		if !ok {
-			continue
+	for _, detail := range element.Details {
+		if detail.Raw != "" {
+			activity.Action = append(activity.Action, detail.Raw)
 		}
-		raw, rawOk := detailMap["raw"].(string)
-		if rawOk {
-			activity["action"] = append(activity["action"].([]string), raw)
-			if raw == "Status changed from In progress to Closed" {
-				closedDate = dateTime
-			}
+		if change, ok := ParseDetail(detail); ok {
+			activity.Changes = append(activity.Changes, change)
 		}
 	}
-	return activity, closedDate, nil
+	return activity, nil
 }
 
-func (dp *DataParser) parseTasksDetails(item interface{}) (map[string]string, error) {
+func (dp *DataParser) parseTasksDetails(details []model.Formattable) map[string]string {
 	taskInfo := make(map[string]string)
-	details, ok := item.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid 'details' field")
-	}
 	for _, detail := range details {
-		raw, ok := detail.(map[string]interface{})["raw"].(string)
-		if !ok {
-			continue
-		}
 		for key, prefix := range dp.TextFiltering {
-			if strings.Contains(raw, prefix) {
-				taskInfo[key] = strings.Replace(raw, prefix, "", 1)
+			if strings.Contains(detail.Raw, prefix) {
+				taskInfo[key] = strings.Replace(detail.Raw, prefix, "", 1)
 			}
 		}
 	}
-	return taskInfo, nil
-}
-
-func (dp *DataParser) parseItem(item []map[string]interface{}) (map[string]interface{}, error) {
-	var createdDate string
-	var duration int
-	mappedData := map[string]interface{}{
-		"taskName": nil,
-		"taskInfo": map[string]interface{}{
-			"id":          nil,
-			"project":     nil,
-			"type":        nil,
-			"priority":    nil,
-			"createdDate": nil,
-			"closedDate":  nil,
-			"duration":    nil,
-		},
-		"taskActivities": []map[string]interface{}{},
+	return taskInfo
+}
+
+func (dp *DataParser) parseItem(item []model.Activity) (model.Task, error) {
+	var createdDate, createdStatus string
+	location := dp.calendar.GetLocation()
+	task := model.Task{
+		TaskActivities: []model.TaskActivity{},
 	}
 
 	for index, val := range item {
 		if index == 0 {
-			href, ok := val["_links"].(map[string]interface{})["workPackage"].(map[string]interface{})["href"].(string)
-			if !ok {
-				return nil, fmt.Errorf("missing or invalid 'href' field")
-			}
-			taskID := strings.Split(href, "/")[4]
-
-			createdAt, ok := val["createdAt"].(string)
+			taskID, ok := val.Links.WorkPackage.ID()
 			if !ok {
-				return nil, fmt.Errorf("missing or invalid 'createdAt' field")
+				return task, fmt.Errorf("missing or invalid 'href' field")
 			}
 
 			var err error
-			createdDate, err = convertTime(createdAt)
+			createdDate, err = convertTime(val.CreatedAt, location)
 			if err != nil {
-				return nil, err
+				return task, fmt.Errorf("missing or invalid 'createdAt' field: %w", err)
 			}
 
-			tasksInfo, err := dp.parseTasksDetails(val["details"])
-			if err != nil {
-				return nil, err
+			tasksInfo := dp.parseTasksDetails(val.Details)
+			for _, change := range ParseDetails(val.Details) {
+				if change.Field == "status" && change.Kind != model.ChangeDeleted {
+					createdStatus = change.To
+				}
 			}
 
-			mappedData["taskName"] = tasksInfo["subject"]
-			mappedData["taskInfo"].(map[string]interface{})["id"] = taskID
-			mappedData["taskInfo"].(map[string]interface{})["project"] = tasksInfo["project"]
-			mappedData["taskInfo"].(map[string]interface{})["type"] = tasksInfo["type"]
-			mappedData["taskInfo"].(map[string]interface{})["priority"] = tasksInfo["priority"]
-			mappedData["taskInfo"].(map[string]interface{})["createdDate"] = createdDate
+			task.TaskName = tasksInfo["subject"]
+			task.TaskInfo.ID = taskID
+			task.TaskInfo.Project = tasksInfo["project"]
+			task.TaskInfo.Type = tasksInfo["type"]
+			task.TaskInfo.Priority = tasksInfo["priority"]
+			task.TaskInfo.CreatedDate = createdDate
 		} else {
-			if val["_type"].(string) == "Activity" {
-				activities, closedDate, err := parseActivity(val)
+			// A status changed together with a comment arrives as a comment
+			// journal; one without details changed nothing.
+			if val.Type == model.ActivityType || (val.Type == model.ActivityCommentType && len(val.Details) > 0) {
+				activity, err := parseActivity(val, location)
 				if err != nil {
-					return nil, err
+					return task, err
 				}
-				if closedDate != "" {
-					duration, err = calculateDuration(createdDate, closedDate)
-					if err != nil {
-						return nil, err
-					}
-					mappedData["taskInfo"].(map[string]interface{})["duration"] = fmt.Sprintf("%v days", duration)
-					mappedData["taskInfo"].(map[string]interface{})["closedDate"] = closedDate
-				}
-				taskActivities := mappedData["taskActivities"].([]map[string]interface{})
-				mappedData["taskActivities"] = append(taskActivities, activities)
+				activity.Author = dp.author(val.Links.User)
+				task.TaskActivities = append(task.TaskActivities, activity)
 			}
 		}
 	}
-	return mappedData, nil
+
+	task.StatusTimeline = BuildStatusTimeline(item, time.Now().In(location))
+
+	closed := dp.closure.detect(createdDate, createdStatus, task.TaskActivities)
+	task.TaskInfo.ReopenCount = closed.reopens
+	if closed.closedDate != "" {
+		durations, err := dp.calculateDuration(createdDate, closed.closedDate)
+		if err != nil {
+			return task, err
+		}
+		task.TaskInfo.Duration = fmt.Sprintf("%v days", durations.CalendarDays)
+		task.TaskInfo.Durations = &durations
+		task.TaskInfo.ClosedDate = closed.closedDate
+		task.TaskInfo.ClosedStatus = closed.status
+		task.TaskInfo.CloseRule = dp.closure.rule
+	}
+	return task, nil
 }
 
-func (dp *DataParser) processItem(item []map[string]interface{}, wg *sync.WaitGroup, resultChan chan<- map[string]interface{}, errChan chan<- error) {
-	defer wg.Done()
+func (dp *DataParser) processItem(item []model.Activity, resultChan chan<- model.Task, errChan chan<- error) {
 	parsedItem, err := dp.parseItem(item)
 	if err != nil {
 		errChan <- err
@@ -197,15 +205,22 @@ func (dp *DataParser) processItem(item []map[string]interface{}, wg *sync.WaitGr
 	resultChan <- parsedItem
 }
 
-func (dp *DataParser) MergeData() ([]map[string]interface{}, error) {
+// MergeData parses every task concurrently. If ctx is cancelled, the tasks
+// parsed so far are returned together with the context error.
+func (dp *DataParser) MergeData(ctx context.Context) ([]model.Task, error) {
 	var wg sync.WaitGroup
-	result := []map[string]interface{}{}
-	resultChan := make(chan map[string]interface{}, len(dp.dataInput))
+	result := []model.Task{}
+	resultChan := make(chan model.Task, len(dp.dataInput))
 	errChan := make(chan error, len(dp.dataInput))
 
+	var ctxErr error
 	for _, item := range dp.dataInput {
-		wg.Add(1)
-		go dp.processItem(item, &wg, resultChan, errChan)
+		ctxErr = dp.pool.Go(ctx, &wg, func() {
+			dp.processItem(item, resultChan, errChan)
+		})
+		if ctxErr != nil {
+			break
+		}
 	}
 
 	wg.Wait()
@@ -222,5 +237,8 @@ func (dp *DataParser) MergeData() ([]map[string]interface{}, error) {
 		}
 	}
 
+	if ctxErr != nil {
+		return result, ctxErr
+	}
 	return result, nil
 }
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"openproject-crawler/pkg/model"
+	"time"
+)
+
+// BuildStatusTimeline reconstructs the ordered status intervals of one work
+// package from its activities, oldest first. The status still current is
+// measured up to asOf, and timestamps are given in asOf's location.
+func BuildStatusTimeline(activities []model.Activity, asOf time.Time) []model.StatusInterval {
+	timeline := []model.StatusInterval{}
+	if len(activities) == 0 {
+		return timeline
+	}
+	location := asOf.Location()
+	createdAt := activities[0].CreatedAt.In(location)
+
+	for _, activity := range activities {
+		changedAt := activity.CreatedAt.In(location)
+		for _, change := range ParseDetails(activity.Details) {
+			if change.Field != "status" || change.Kind == model.ChangeDeleted || change.To == "" {
+				continue
+			}
+			if len(timeline) == 0 && change.From != "" && changedAt.After(createdAt) {
+				timeline = append(timeline, model.StatusInterval{
+					Status:    change.From,
+					EnteredAt: createdAt,
+				})
+			}
+			if last := len(timeline) - 1; last >= 0 {
+				if timeline[last].Status == change.To {
+					continue
+				}
+				leftAt := changedAt
+				timeline[last].LeftAt = &leftAt
+			}
+			timeline = append(timeline, model.StatusInterval{
+				Status:    change.To,
+				EnteredAt: changedAt,
+			})
+		}
+	}
+
+	for i := range timeline {
+		end := asOf
+		if timeline[i].LeftAt != nil {
+			end = *timeline[i].LeftAt
+		}
+		if end.After(timeline[i].EnteredAt) {
+			timeline[i].DurationSeconds = int64(end.Sub(timeline[i].EnteredAt) / time.Second)
+		}
+	}
+	return timeline
+}
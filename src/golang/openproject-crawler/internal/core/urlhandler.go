@@ -66,8 +66,12 @@ func (u *URLHandler) SetURIPath(uri string) {
 }
 
 func (u *URLHandler) GetFullURL() string {
-	if u.uriPath != "" {
-		return strings.TrimRight(u.baseURL, "/") + "/" + strings.TrimLeft(u.uriPath, "/")
+	return u.ResolveURL(u.uriPath)
+}
+
+func (u *URLHandler) ResolveURL(uri string) string {
+	if uri != "" {
+		return strings.TrimRight(u.baseURL, "/") + "/" + strings.TrimLeft(uri, "/")
 	}
 	return u.baseURL
 }
@@ -1,8 +1,15 @@
 package credential
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 type Credential struct {
@@ -38,3 +45,173 @@ func (c *Credential) GenerateToken() string {
 	base64Token := base64.StdEncoding.EncodeToString([]byte(credential))
 	return base64Token
 }
+
+const (
+	APIKeyUsername   = "apikey"
+	EnvAPIKey        = "OPENPROJECT_API_KEY"
+	EnvUsername      = "OPENPROJECT_USERNAME"
+	EnvPassword      = "OPENPROJECT_PASSWORD"
+	EnvClientID      = "OPENPROJECT_OAUTH_CLIENT_ID"
+	EnvClientSecret  = "OPENPROJECT_OAUTH_CLIENT_SECRET"
+	EnvRefreshToken  = "OPENPROJECT_OAUTH_REFRESH_TOKEN"
+	EnvSession       = "OPENPROJECT_SESSION"
+	KeyAPIKey        = "api_key"
+	KeyUsername      = "username"
+	KeyPassword      = "password"
+	KeyClientID      = "client_id"
+	KeyClientSecret  = "client_secret"
+	KeyRefreshToken  = "refresh_token"
+	KeyScope         = "scope"
+	KeyTokenURL      = "token_url"
+	KeySession       = "session"
+	KeySessionCookie = "session_cookie"
+)
+
+var envKeys = map[string]string{
+	EnvAPIKey:       KeyAPIKey,
+	EnvUsername:     KeyUsername,
+	EnvPassword:     KeyPassword,
+	EnvClientID:     KeyClientID,
+	EnvClientSecret: KeyClientSecret,
+	EnvRefreshToken: KeyRefreshToken,
+	EnvSession:      KeySession,
+}
+
+// Values holds credential settings by key, e.g. "api_key" or "client_id",
+// as read from the environment, a file or a command helper.
+type Values map[string]string
+
+// Credential returns the Basic auth credential: the API key if set,
+// otherwise the username and password.
+func (v Values) Credential() (*Credential, error) {
+	if apiKey := v[KeyAPIKey]; apiKey != "" {
+		return SetCredential(APIKeyUsername, apiKey)
+	}
+	return SetCredential(v[KeyUsername], v[KeyPassword])
+}
+
+// LoadEnv reads the OPENPROJECT_* credential variables that are set.
+func LoadEnv() (Values, error) {
+	values := make(Values)
+	for name, key := range envKeys {
+		if value := os.Getenv(name); value != "" {
+			values[key] = value
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("neither %s, %s/%s, %s nor %s is set", EnvAPIKey, EnvUsername, EnvPassword, EnvClientID, EnvSession)
+	}
+	return values, nil
+}
+
+// LoadEnvVar reads an API key from the named environment variable.
+func LoadEnvVar(name string) (Values, error) {
+	apiKey := os.Getenv(name)
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s is not set", name)
+	}
+	return Values{KeyAPIKey: apiKey}, nil
+}
+
+// LoadFile reads a credential file holding either a bare API key or
+// "key=value" lines such as "api_key=", "username=" or "client_id=".
+// Blank lines and lines starting with # are ignored. A leading ~/ is
+// expanded to the home directory.
+func LoadFile(path string) (Values, error) {
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", path, err)
+		}
+		path = filepath.Join(home, rest)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential file: %w", err)
+	}
+	values, err := parseValues(content)
+	if err != nil {
+		return nil, fmt.Errorf("credential file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// LoadCommand runs a credential helper and parses its standard output like a
+// credential file. The command line is split on spaces without a shell.
+func LoadCommand(ctx context.Context, command string) (Values, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("credential command was empty")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("credential command %q failed: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("credential command %q failed: %w", args[0], err)
+	}
+	values, err := parseValues(output)
+	if err != nil {
+		return nil, fmt.Errorf("credential command %q: %w", args[0], err)
+	}
+	return values, nil
+}
+
+func parseValues(content []byte) (Values, error) {
+	values := make(Values)
+	var bare []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			bare = append(bare, line)
+			continue
+		}
+		values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+
+	if len(values) == 0 && len(bare) == 1 {
+		return Values{KeyAPIKey: bare[0]}, nil
+	}
+	if len(bare) > 0 {
+		return nil, fmt.Errorf("unexpected line %q", bare[0])
+	}
+	if len(values) == 0 {
+		return nil, errors.New("no credential found")
+	}
+	return values, nil
+}
+
+// FromEnv reads OPENPROJECT_API_KEY, or OPENPROJECT_USERNAME and
+// OPENPROJECT_PASSWORD when no API key is set.
+func FromEnv() (*Credential, error) {
+	values, err := LoadEnv()
+	if err != nil {
+		return nil, err
+	}
+	return values.Credential()
+}
+
+// FromEnvVar reads an API key from the named environment variable.
+func FromEnvVar(name string) (*Credential, error) {
+	values, err := LoadEnvVar(name)
+	if err != nil {
+		return nil, err
+	}
+	return values.Credential()
+}
+
+// FromFile reads the Basic auth credential from a credential file.
+func FromFile(path string) (*Credential, error) {
+	values, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return values.Credential()
+}
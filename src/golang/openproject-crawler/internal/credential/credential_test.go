@@ -0,0 +1,114 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Values
+		err     string
+	}{
+		{"bare api key", "abc123\n", Values{KeyAPIKey: "abc123"}, ""},
+		{"key value lines", "# prod\nUsername = ada\n\npassword=s3=cret\n", Values{KeyUsername: "ada", KeyPassword: "s3=cret"}, ""},
+		{"oauth2 client", "client_id=crawler\nclient_secret=s3cret\n", Values{KeyClientID: "crawler", KeyClientSecret: "s3cret"}, ""},
+		{"two bare lines", "abc\ndef\n", nil, `unexpected line "abc"`},
+		{"bare line among values", "username=ada\nhunter2\n", nil, `unexpected line "hunter2"`},
+		{"only comments", "# nothing here\n\n", nil, "no credential found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := parseValues([]byte(tt.content))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("values = %v, want %v", values, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "prod.key"), []byte("api_key=abc123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := LoadFile("~/prod.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[KeyAPIKey] != "abc123" {
+		t.Errorf("values = %v", values)
+	}
+	if _, err := LoadFile(filepath.Join(home, "missing.key")); err == nil || !strings.Contains(err.Error(), "failed to read credential file") {
+		t.Errorf("err = %v for a missing file", err)
+	}
+}
+
+func TestLoadCommand(t *testing.T) {
+	ctx := context.Background()
+	values, err := LoadCommand(ctx, "echo client_id=crawler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[KeyClientID] != "crawler" {
+		t.Errorf("values = %v", values)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		err     string
+	}{
+		{"empty", "  ", "credential command was empty"},
+		{"failing", "ls " + filepath.Join(t.TempDir(), "missing"), `credential command "ls" failed`},
+		{"no output", "true", `credential command "true": no credential found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadCommand(ctx, tt.command); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValuesCredential(t *testing.T) {
+	tests := []struct {
+		name   string
+		values Values
+		token  string
+		ok     bool
+	}{
+		{"api key wins", Values{KeyAPIKey: "abc123", KeyUsername: "ada", KeyPassword: "pw"}, "YXBpa2V5OmFiYzEyMw==", true},
+		{"username and password", Values{KeyUsername: "ada", KeyPassword: "pw"}, "YWRhOnB3", true},
+		{"missing password", Values{KeyUsername: "ada"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := tt.values.Credential()
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v", err)
+			}
+			if err == nil && cred.GenerateToken() != tt.token {
+				t.Errorf("token = %s, want %s", cred.GenerateToken(), tt.token)
+			}
+		})
+	}
+}
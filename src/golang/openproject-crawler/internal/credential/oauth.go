@@ -0,0 +1,155 @@
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultOAuth2Scope = "api_v3"
+
+	// tokenExpiryMargin renews a token shortly before it expires, so a
+	// request does not leave with a token that is about to be rejected.
+	tokenExpiryMargin = 30 * time.Second
+)
+
+// OAuth2Config selects the refresh-token grant when RefreshToken is set and
+// the client-credentials grant otherwise.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	Scope        string
+	Client       *http.Client
+}
+
+type OAuth2Provider struct {
+	config      OAuth2Config
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func NewOAuth2Provider(config OAuth2Config) (*OAuth2Provider, error) {
+	if config.TokenURL == "" {
+		return nil, errors.New("OAuth2 token URL was empty")
+	}
+	if config.ClientID == "" {
+		return nil, errors.New("OAuth2 client ID was empty")
+	}
+	if config.RefreshToken == "" && config.ClientSecret == "" {
+		return nil, errors.New("OAuth2 needs a client secret or a refresh token")
+	}
+	if config.Scope == "" {
+		config.Scope = DefaultOAuth2Scope
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &OAuth2Provider{config: config}, nil
+}
+
+func (p *OAuth2Provider) Authorize(req *http.Request) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken == "" || (!p.expiry.IsZero() && time.Now().Add(tokenExpiryMargin).After(p.expiry)) {
+		if err := p.fetchToken(req.Context()); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+p.accessToken)
+	return nil
+}
+
+// Refresh requests a new token unless another request already replaced the
+// one that was rejected.
+func (p *OAuth2Provider) Refresh(rejected *http.Request) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if rejected.Header.Get("Authorization") != "Bearer "+p.accessToken {
+		return nil
+	}
+	return p.fetchToken(rejected.Context())
+}
+
+func (p *OAuth2Provider) fetchToken(ctx context.Context) error {
+	form := url.Values{
+		"client_id": {p.config.ClientID},
+		"scope":     {p.config.Scope},
+	}
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+	if p.config.RefreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", p.config.RefreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request OAuth2 token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read OAuth2 token response: %w", err)
+	}
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil && resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("failed to decode OAuth2 token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		reason := resp.Status
+		if token.Error != "" {
+			reason = token.Error
+			if token.ErrorDescription != "" {
+				reason += ": " + token.ErrorDescription
+			}
+		}
+		return fmt.Errorf("failed to obtain OAuth2 token: %s", reason)
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return fmt.Errorf("unsupported OAuth2 token type %q", token.TokenType)
+	}
+
+	p.accessToken = token.AccessToken
+	p.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		p.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	// Refresh tokens may be rotated; the old one stops working.
+	if token.RefreshToken != "" && p.config.RefreshToken != "" {
+		p.config.RefreshToken = token.RefreshToken
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"openproject-crawler/internal/fakeapi"
+	"strings"
+	"testing"
+)
+
+func authorize(t *testing.T, provider Provider) (*http.Request, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://openproject.invalid/api/v3/statuses", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req, provider.Authorize(req)
+}
+
+func TestOAuth2Grants(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	srv.SetOAuth2Client("crawler", "s3cret")
+
+	tests := []struct {
+		name   string
+		config OAuth2Config
+		grant  string
+		err    string
+	}{
+		{"client credentials", OAuth2Config{ClientID: "crawler", ClientSecret: "s3cret"}, "client_credentials", ""},
+		{"refresh token", OAuth2Config{ClientID: "crawler", RefreshToken: srv.IssueRefreshToken()}, "refresh_token", ""},
+		{"refresh token with secret", OAuth2Config{ClientID: "crawler", ClientSecret: "s3cret", RefreshToken: srv.IssueRefreshToken()}, "refresh_token", ""},
+		{"wrong secret", OAuth2Config{ClientID: "crawler", ClientSecret: "guess"}, "client_credentials", "failed to obtain OAuth2 token: invalid_client"},
+		{"unknown refresh token", OAuth2Config{ClientID: "crawler", RefreshToken: "refresh-0"}, "refresh_token", "failed to obtain OAuth2 token: invalid_grant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.TokenURL = srv.URL + "/oauth/token"
+			provider, err := NewOAuth2Provider(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := len(srv.Requests())
+			req, err := authorize(t, provider)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("Authorization"); !strings.HasPrefix(got, "Bearer access-") {
+				t.Errorf("Authorization = %q", got)
+			}
+			requests := srv.Requests()[before:]
+			if len(requests) != 1 || requests[0].Path != "/oauth/token" {
+				t.Fatalf("requests = %+v, want one token request", requests)
+			}
+			// A token that has not expired is reused.
+			if _, err := authorize(t, provider); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(srv.Requests()) - before; got != 1 {
+				t.Errorf("token requests = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestOAuth2RotatedRefreshToken(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	srv.SetOAuth2Client("crawler", "s3cret")
+
+	provider, err := NewOAuth2Provider(OAuth2Config{
+		TokenURL:     srv.URL + "/oauth/token",
+		ClientID:     "crawler",
+		RefreshToken: srv.IssueRefreshToken(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := authorize(t, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first refresh token was used up; only the rotated one is accepted.
+	for i := 0; i < 2; i++ {
+		if err := provider.Refresh(first); err != nil {
+			t.Fatalf("refresh %d: %v", i+1, err)
+		}
+		if first, err = authorize(t, provider); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A request rejected with a token that was already replaced does not
+	// refresh again.
+	stale, _ := http.NewRequest(http.MethodGet, "http://openproject.invalid/api/v3/statuses", nil)
+	stale.Header.Set("Authorization", "Bearer access-0")
+	before := len(srv.Requests())
+	if err := provider.Refresh(stale); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(srv.Requests()) - before; got != 0 {
+		t.Errorf("token requests = %d for a stale rejection, want 0", got)
+	}
+}
+
+func TestNewOAuth2Provider(t *testing.T) {
+	tests := []struct {
+		name   string
+		config OAuth2Config
+		err    string
+	}{
+		{"no token URL", OAuth2Config{ClientID: "crawler", ClientSecret: "s3cret"}, "OAuth2 token URL was empty"},
+		{"no client ID", OAuth2Config{TokenURL: "https://example.com/oauth/token", ClientSecret: "s3cret"}, "OAuth2 client ID was empty"},
+		{"no secret or refresh token", OAuth2Config{TokenURL: "https://example.com/oauth/token", ClientID: "crawler"}, "OAuth2 needs a client secret or a refresh token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewOAuth2Provider(tt.config); err == nil || err.Error() != tt.err {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+	if err := NewBasicProvider("token").Refresh(nil); !errors.Is(err, ErrNotRefreshable) {
+		t.Errorf("basic refresh = %v, want ErrNotRefreshable", err)
+	}
+}
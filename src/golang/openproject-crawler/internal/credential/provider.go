@@ -0,0 +1,109 @@
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotRefreshable is returned by providers whose credential cannot be
+// renewed without the user, such as API keys and session cookies.
+var ErrNotRefreshable = errors.New("credential cannot be refreshed")
+
+// DefaultSessionCookie is the name of OpenProject's session cookie.
+const DefaultSessionCookie = "_open_project_session"
+
+// Provider authorizes API requests. Refresh is called once when a request
+// authorized by the provider was rejected with 401 Unauthorized.
+type Provider interface {
+	Authorize(req *http.Request) error
+	Refresh(rejected *http.Request) error
+}
+
+type BasicProvider struct {
+	token string
+}
+
+// NewBasicProvider uses a token from Credential.GenerateToken.
+func NewBasicProvider(token string) *BasicProvider {
+	return &BasicProvider{token: token}
+}
+
+func (p *BasicProvider) Authorize(req *http.Request) error {
+	req.Header.Set("Authorization", "Basic "+p.token)
+	return nil
+}
+
+func (p *BasicProvider) Refresh(rejected *http.Request) error {
+	return ErrNotRefreshable
+}
+
+type SessionProvider struct {
+	name  string
+	value string
+}
+
+func NewSessionProvider(name, value string) (*SessionProvider, error) {
+	if value == "" {
+		return nil, errors.New("session cookie was empty")
+	}
+	if name == "" {
+		name = DefaultSessionCookie
+	}
+	return &SessionProvider{name: name, value: value}, nil
+}
+
+func (p *SessionProvider) Authorize(req *http.Request) error {
+	// Set rather than add, so a retried request does not carry the cookie twice.
+	req.Header.Set("Cookie", (&http.Cookie{Name: p.name, Value: p.value}).String())
+	return nil
+}
+
+func (p *SessionProvider) Refresh(rejected *http.Request) error {
+	return ErrNotRefreshable
+}
+
+// NewProvider picks a provider from credential values: OAuth2 when a client
+// ID is set, a session cookie when a session is set, Basic auth otherwise.
+// OAuth2 tokens are requested from TokenURL(apiURL) unless token_url is set.
+func NewProvider(values Values, apiURL string) (Provider, error) {
+	switch {
+	case values[KeyClientID] != "":
+		tokenURL := values[KeyTokenURL]
+		if tokenURL == "" {
+			var err error
+			if tokenURL, err = TokenURL(apiURL); err != nil {
+				return nil, err
+			}
+		}
+		return NewOAuth2Provider(OAuth2Config{
+			TokenURL:     tokenURL,
+			ClientID:     values[KeyClientID],
+			ClientSecret: values[KeyClientSecret],
+			RefreshToken: values[KeyRefreshToken],
+			Scope:        values[KeyScope],
+		})
+	case values[KeySession] != "":
+		return NewSessionProvider(values[KeySessionCookie], values[KeySession])
+	}
+	cred, err := values.Credential()
+	if err != nil {
+		return nil, err
+	}
+	return NewBasicProvider(cred.GenerateToken()), nil
+}
+
+// TokenURL derives the OAuth2 token endpoint from an API URL such as
+// https://host/api/v3, keeping any path prefix the instance is served under.
+func TokenURL(apiURL string) (string, error) {
+	parsed, err := url.Parse(apiURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("invalid API URL %q", apiURL)
+	}
+	root := strings.TrimSuffix(strings.TrimSuffix(parsed.Path, "/"), "/api/v3")
+	parsed.Path = root + "/oauth/token"
+	parsed.RawQuery = ""
+	return parsed.String(), nil
+}
@@ -0,0 +1,37 @@
+// Package fakeapitest connects crawlers to a fake OpenProject API in tests.
+package fakeapitest
+
+import (
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/internal/httpclient"
+	"testing"
+)
+
+type crawler interface {
+	SetRateLimiter(*httpclient.RateLimiter)
+	SetRetryPolicy(httpclient.RetryPolicy) error
+}
+
+// New starts a fake API serving fixtures, or the default fixtures if nil,
+// that is closed when the test ends.
+func New(t testing.TB, fixtures *fakeapi.Fixtures) *fakeapi.Server {
+	t.Helper()
+	srv := fakeapi.New(fixtures)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Crawler connects a crawler built by newCrawler to srv, without rate limiting
+// and retries.
+func Crawler[T crawler](t testing.TB, srv *fakeapi.Server, newCrawler func(apiURL, authToken string) (T, error)) T {
+	t.Helper()
+	c, err := newCrawler(srv.APIURL(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRateLimiter(nil)
+	if err := c.SetRetryPolicy(httpclient.RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
@@ -0,0 +1,296 @@
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type filter struct {
+	name     string
+	operator string
+	values   []string
+}
+
+// defaultWorkPackageFilters is what OpenProject applies to work package
+// collections requested without a filters parameter: open work packages only.
+var defaultWorkPackageFilters = []filter{{name: "status", operator: "o"}}
+
+func parseFilters(value string) ([]filter, error) {
+	var raw []map[string]struct {
+		Operator string        `json:"operator"`
+		Values   []interface{} `json:"values"`
+	}
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("filters are not valid JSON: %v", err)
+	}
+	var filters []filter
+	for _, entry := range raw {
+		for name, spec := range entry {
+			f := filter{name: name, operator: spec.Operator}
+			for _, v := range spec.Values {
+				f.values = append(f.values, fmt.Sprintf("%v", v))
+			}
+			filters = append(filters, f)
+		}
+	}
+	return filters, nil
+}
+
+func (s *Server) matchWorkPackage(wp model.WorkPackage, f filter) (bool, error) {
+	switch f.name {
+	case "id":
+		return matchValue(strconv.Itoa(wp.ID), f)
+	case "project":
+		return matchLink(wp.Links.Project, f)
+	case "status":
+		switch f.operator {
+		case "o", "c":
+			id, _ := wp.Links.Status.ID()
+			return s.closedStatuses[id] == (f.operator == "c"), nil
+		}
+		return matchLink(wp.Links.Status, f)
+	case "type":
+		return matchLink(wp.Links.Type, f)
+	case "priority":
+		return matchLink(wp.Links.Priority, f)
+	case "author":
+		return matchLink(wp.Links.Author, f)
+	case "assignee":
+		return matchLink(wp.Links.Assignee, f)
+	case "responsible":
+		return matchLink(wp.Links.Responsible, f)
+	case "version":
+		return matchLink(wp.Links.Version, f)
+	case "parent":
+		return matchLink(wp.Links.Parent, f)
+	case "subject":
+		return matchText(wp.Subject, f)
+	case "createdAt":
+		return matchTime(wp.CreatedAt, f)
+	case "updatedAt":
+		return matchTime(wp.UpdatedAt, f)
+	}
+	return false, fmt.Errorf("%s filter does not exist", f.name)
+}
+
+func matchProject(project model.Project, f filter) (bool, error) {
+	switch f.name {
+	case "id":
+		return matchValue(strconv.Itoa(project.ID), f)
+	case "active":
+		return matchValue(strconv.FormatBool(project.Active)[:1], f)
+	case "parent_id":
+		return matchLink(project.Links.Parent, f)
+	}
+	return false, fmt.Errorf("%s filter does not exist", f.name)
+}
+
+func matchTimeEntry(entry model.TimeEntry, f filter) (bool, error) {
+	switch f.name {
+	case "project":
+		return matchLink(entry.Links.Project, f)
+	case "user":
+		return matchLink(entry.Links.User, f)
+	case "work_package":
+		return matchLink(entry.Links.WorkPackage, f)
+	case "activity":
+		return matchLink(entry.Links.Activity, f)
+	case "spent_on":
+		spentOn, err := time.Parse(dateLayout, entry.SpentOn)
+		if err != nil {
+			return false, err
+		}
+		return matchTime(spentOn, f)
+	}
+	return false, fmt.Errorf("%s filter does not exist", f.name)
+}
+
+// matchPrincipal's member filter keeps the principals with a membership in
+// one of the given projects.
+func (s *Server) matchPrincipal(principal model.Principal, f filter) (bool, error) {
+	switch f.name {
+	case "id":
+		return matchValue(strconv.Itoa(principal.ID), f)
+	case "type":
+		return matchValue(principal.Type, f)
+	case "status":
+		return matchValue(principal.Status, f)
+	case "member":
+		memberships, err := s.memberships()
+		if err != nil {
+			return false, err
+		}
+		for _, membership := range memberships {
+			if id, ok := membership.value.Links.Principal.ID(); !ok || id != principal.ID {
+				continue
+			}
+			if member, err := matchLink(membership.value.Links.Project, f); member || err != nil {
+				return member, err
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("%s filter does not exist", f.name)
+}
+
+func matchMembership(membership model.Membership, f filter) (bool, error) {
+	switch f.name {
+	case "project":
+		return matchLink(membership.Links.Project, f)
+	case "principal":
+		return matchLink(membership.Links.Principal, f)
+	}
+	return false, fmt.Errorf("%s filter does not exist", f.name)
+}
+
+func matchNonWorkingDay(day model.NonWorkingDay, f filter) (bool, error) {
+	if f.name != "date" {
+		return false, fmt.Errorf("%s filter does not exist", f.name)
+	}
+	date, err := time.Parse(dateLayout, day.Date)
+	if err != nil {
+		return false, err
+	}
+	return matchTime(date, f)
+}
+
+func matchValue(value string, f filter) (bool, error) {
+	found := false
+	for _, v := range f.values {
+		if v == value {
+			found = true
+		}
+	}
+	switch f.operator {
+	case "=":
+		return found, nil
+	case "!":
+		return !found, nil
+	}
+	return false, fmt.Errorf("%s filter does not support operator %q", f.name, f.operator)
+}
+
+func matchLink(link model.Link, f filter) (bool, error) {
+	switch f.operator {
+	case "*":
+		return !link.IsZero(), nil
+	case "!*":
+		return link.IsZero(), nil
+	}
+	id, ok := link.ID()
+	if !ok {
+		return f.operator == "!", nil
+	}
+	return matchValue(strconv.Itoa(id), f)
+}
+
+func matchText(value string, f filter) (bool, error) {
+	if len(f.values) != 1 {
+		return false, fmt.Errorf("%s filter needs exactly one value", f.name)
+	}
+	contains := strings.Contains(strings.ToLower(value), strings.ToLower(f.values[0]))
+	switch f.operator {
+	case "~":
+		return contains, nil
+	case "!~":
+		return !contains, nil
+	}
+	return false, fmt.Errorf("%s filter does not support operator %q", f.name, f.operator)
+}
+
+func matchTime(value time.Time, f filter) (bool, error) {
+	switch f.operator {
+	case "<>d":
+		if len(f.values) != 2 {
+			return false, fmt.Errorf("%s filter needs a start and an end date", f.name)
+		}
+		from, to := time.Time{}, time.Time{}
+		var err error
+		if f.values[0] != "" {
+			if from, err = parseTime(f.values[0], false); err != nil {
+				return false, fmt.Errorf("%s filter has an invalid date: %v", f.name, err)
+			}
+		}
+		if f.values[1] != "" {
+			if to, err = parseTime(f.values[1], true); err != nil {
+				return false, fmt.Errorf("%s filter has an invalid date: %v", f.name, err)
+			}
+		}
+		return !value.Before(from) && (to.IsZero() || !value.After(to)), nil
+	case ">t-", "<t-":
+		if len(f.values) != 1 {
+			return false, fmt.Errorf("%s filter needs a number of days", f.name)
+		}
+		days, err := strconv.Atoi(f.values[0])
+		if err != nil {
+			return false, fmt.Errorf("%s filter has an invalid number of days %q", f.name, f.values[0])
+		}
+		since := time.Now().AddDate(0, 0, -days)
+		if f.operator == ">t-" {
+			return value.After(since), nil
+		}
+		return value.Before(since), nil
+	}
+	return false, fmt.Errorf("%s filter does not support operator %q", f.name, f.operator)
+}
+
+// parseTime accepts dates and timestamps; a date as the end of a range
+// covers that whole day.
+func parseTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+func sortWorkPackages(workPackages []element[model.WorkPackage], value string) error {
+	var criteria [][]string
+	if err := json.Unmarshal([]byte(value), &criteria); err != nil {
+		return fmt.Errorf("sortBy is not valid JSON: %v", err)
+	}
+	var compares []func(a, b model.WorkPackage) int
+	for _, criterion := range criteria {
+		if len(criterion) != 2 || (criterion[1] != "asc" && criterion[1] != "desc") {
+			return fmt.Errorf("invalid sort criterion %v", criterion)
+		}
+		var compare func(a, b model.WorkPackage) int
+		switch criterion[0] {
+		case "id":
+			compare = func(a, b model.WorkPackage) int { return a.ID - b.ID }
+		case "subject":
+			compare = func(a, b model.WorkPackage) int { return strings.Compare(a.Subject, b.Subject) }
+		case "createdAt":
+			compare = func(a, b model.WorkPackage) int { return a.CreatedAt.Compare(b.CreatedAt) }
+		case "updatedAt":
+			compare = func(a, b model.WorkPackage) int { return a.UpdatedAt.Compare(b.UpdatedAt) }
+		default:
+			return fmt.Errorf("cannot sort by %s", criterion[0])
+		}
+		if criterion[1] == "desc" {
+			asc := compare
+			compare = func(a, b model.WorkPackage) int { return asc(b, a) }
+		}
+		compares = append(compares, compare)
+	}
+	sort.SliceStable(workPackages, func(i, j int) bool {
+		for _, compare := range compares {
+			if c := compare(workPackages[i].value, workPackages[j].value); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
@@ -0,0 +1,61 @@
+package fakeapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+//go:embed testdata/instance.json
+var defaultInstance []byte
+
+// Fixtures holds the resources the server answers with, as the JSON
+// OpenProject itself returns. Activities are keyed by work package ID.
+type Fixtures struct {
+	Projects       []json.RawMessage         `json:"projects"`
+	WorkPackages   []json.RawMessage         `json:"workPackages"`
+	Activities     map[int][]json.RawMessage `json:"activities"`
+	Statuses       []json.RawMessage         `json:"statuses"`
+	WeekDays       []json.RawMessage         `json:"weekDays"`
+	NonWorkingDays []json.RawMessage         `json:"nonWorkingDays"`
+	TimeEntries    []json.RawMessage         `json:"timeEntries"`
+	Users          []json.RawMessage         `json:"users"`
+	Groups         []json.RawMessage         `json:"groups"`
+	Placeholders   []json.RawMessage         `json:"placeholderUsers"`
+	Memberships    []json.RawMessage         `json:"memberships"`
+	Relations      []json.RawMessage         `json:"relations"`
+	Versions       []json.RawMessage         `json:"versions"`
+}
+
+// DefaultFixtures returns a small instance with three projects (Operations,
+// and Demo project with one subproject), five work packages and their
+// activities and five relations, three versions, four statuses, a Monday to
+// Friday week, six time entries, and three users, a group and a placeholder
+// user with six project memberships.
+func DefaultFixtures() *Fixtures {
+	fixtures, err := ParseFixtures(defaultInstance)
+	if err != nil {
+		panic(fmt.Sprintf("fakeapi: invalid embedded fixtures: %v", err))
+	}
+	return fixtures
+}
+
+func LoadFixtures(path string) (*Fixtures, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	return ParseFixtures(content)
+}
+
+func ParseFixtures(content []byte) (*Fixtures, error) {
+	var fixtures Fixtures
+	if err := json.Unmarshal(content, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+	if fixtures.Activities == nil {
+		fixtures.Activities = make(map[int][]json.RawMessage)
+	}
+	return &fixtures, nil
+}
@@ -0,0 +1,762 @@
+// Package fakeapi serves a small OpenProject API v3 from fixtures, so the
+// crawlers can be tested without a live instance.
+package fakeapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"openproject-crawler/pkg/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	APIPath         = "/api/v3"
+	DefaultPageSize = 20
+	MaxPageSize     = 1000
+
+	dateLayout    = "2006-01-02"
+	tokenLifetime = 2 * time.Hour
+	errorURN      = "urn:openproject-org:api:v3:errors:"
+)
+
+// Fault makes the server misbehave on matching requests: wait Latency, then
+// answer with Status (and Retry-After), or with a truncated JSON body if
+// Malformed is set. A fault with only Latency slows requests down.
+type Fault struct {
+	// Path matches requests whose path below /api/v3 starts with it; empty
+	// matches every API request.
+	Path       string
+	Status     int
+	RetryAfter string
+	Latency    time.Duration
+	Malformed  bool
+	// Times limits how many requests the fault applies to; zero means all.
+	Times int
+}
+
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+}
+
+// Server is an httptest.Server answering like OpenProject. Requests are not
+// authenticated unless SetAPIKey or SetOAuth2Client is called.
+type Server struct {
+	*httptest.Server
+	fixtures       *Fixtures
+	closedStatuses map[int]bool
+	mu             sync.Mutex
+	apiKey         string
+	clientID       string
+	clientSecret   string
+	tokens         map[string]bool
+	refreshTokens  map[string]bool
+	tokenSeq       int
+	faults         []*Fault
+	requests       []Request
+}
+
+type element[T any] struct {
+	raw   json.RawMessage
+	value T
+}
+
+type collection struct {
+	Type     string `json:"_type"`
+	Total    int    `json:"total"`
+	Count    int    `json:"count"`
+	PageSize int    `json:"pageSize,omitempty"`
+	Offset   int    `json:"offset,omitempty"`
+	Embedded struct {
+		Elements []json.RawMessage `json:"elements"`
+	} `json:"_embedded"`
+	Links map[string]model.Link `json:"_links,omitempty"`
+}
+
+// New starts a server for fixtures, or for DefaultFixtures if nil. Close it
+// when done.
+func New(fixtures *Fixtures) *Server {
+	if fixtures == nil {
+		fixtures = DefaultFixtures()
+	}
+	s := &Server{
+		fixtures:       fixtures,
+		closedStatuses: make(map[int]bool),
+		tokens:         make(map[string]bool),
+		refreshTokens:  make(map[string]bool),
+	}
+	statuses, err := decodeElements[model.Status](fixtures.Statuses)
+	if err != nil {
+		panic(fmt.Sprintf("fakeapi: invalid status fixture: %v", err))
+	}
+	for _, status := range statuses {
+		s.closedStatuses[status.value.ID] = status.value.IsClosed
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+APIPath+"/projects", s.handleProjects)
+	mux.HandleFunc("GET "+APIPath+"/projects/{project}", s.handleProject)
+	mux.HandleFunc("GET "+APIPath+"/projects/{project}/work_packages", s.handleProjectWorkPackages)
+	mux.HandleFunc("GET "+APIPath+"/projects/{project}/versions", s.handleProjectVersions)
+	mux.HandleFunc("GET "+APIPath+"/work_packages", s.handleWorkPackages)
+	mux.HandleFunc("GET "+APIPath+"/work_packages/{id}", s.handleWorkPackage)
+	mux.HandleFunc("GET "+APIPath+"/work_packages/{id}/activities", s.handleActivities)
+	mux.HandleFunc("GET "+APIPath+"/work_packages/{id}/relations", s.handleRelations)
+	mux.HandleFunc("GET "+APIPath+"/statuses", s.handleStatuses)
+	mux.HandleFunc("GET "+APIPath+"/days/week", s.handleWeekDays)
+	mux.HandleFunc("GET "+APIPath+"/days/non_working", s.handleNonWorkingDays)
+	mux.HandleFunc("GET "+APIPath+"/time_entries", s.handleTimeEntries)
+	mux.HandleFunc("GET "+APIPath+"/users", s.handleUsers)
+	mux.HandleFunc("GET "+APIPath+"/users/{id}", s.handleUser)
+	mux.HandleFunc("GET "+APIPath+"/groups", s.handleGroups)
+	mux.HandleFunc("GET "+APIPath+"/principals", s.handlePrincipals)
+	mux.HandleFunc("GET "+APIPath+"/memberships", s.handleMemberships)
+	mux.HandleFunc("GET "+APIPath+"/versions", s.handleVersions)
+	mux.HandleFunc("GET "+APIPath+"/versions/{id}", s.handleVersion)
+	mux.HandleFunc("POST /oauth/token", s.handleToken)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+	})
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+func (s *Server) APIURL() string {
+	return s.URL + APIPath
+}
+
+// SetAPIKey requires Basic auth with the user "apikey" and key as password.
+func (s *Server) SetAPIKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKey = key
+}
+
+// SetOAuth2Client enables /oauth/token for one client and requires API
+// requests to carry a bearer token it issued (or the API key, if set).
+func (s *Server) SetOAuth2Client(id, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientID = id
+	s.clientSecret = secret
+}
+
+// IssueRefreshToken returns a refresh token the token endpoint accepts once.
+func (s *Server) IssueRefreshToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.newRefreshToken()
+}
+
+// ExpireTokens revokes every access token issued so far.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+func (s *Server) Inject(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns every request received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// RequestCount counts the requests whose path below /api/v3 starts with path.
+func (s *Server) RequestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, req := range s.requests {
+		if strings.HasPrefix(req.Path, APIPath+path) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+		})
+		s.mu.Unlock()
+
+		if !strings.HasPrefix(r.URL.Path, APIPath+"/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if fault, ok := s.takeFault(strings.TrimPrefix(r.URL.Path, APIPath)); ok {
+			if fault.Latency > 0 {
+				select {
+				case <-time.After(fault.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			switch {
+			case fault.Status != 0:
+				if fault.RetryAfter != "" {
+					w.Header().Set("Retry-After", fault.RetryAfter)
+				}
+				writeError(w, fault.Status, "InternalError", "Injected fault.")
+				return
+			case fault.Malformed:
+				w.Header().Set("Content-Type", "application/hal+json")
+				w.Write([]byte(`{"_type":"Collection","total":3,"count":3,"_embedded":{"elements":[{"id":`))
+				return
+			}
+		}
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="OpenProject API"`)
+			writeError(w, http.StatusUnauthorized, "Unauthenticated", "You need to be authenticated to access this resource.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) takeFault(path string) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, fault := range s.faults {
+		if !strings.HasPrefix(path, fault.Path) {
+			continue
+		}
+		taken := *fault
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return taken, true
+	}
+	return Fault{}, false
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.apiKey == "" && s.clientID == "" {
+		return true
+	}
+	scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || s.apiKey == "" {
+			return false
+		}
+		return string(decoded) == "apikey:"+s.apiKey
+	case "bearer":
+		return s.tokens[value]
+	}
+	return false
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret := r.PostForm.Get("client_secret")
+	if s.clientID == "" || r.PostForm.Get("client_id") != s.clientID || (secret != "" && secret != s.clientSecret) {
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	response := map[string]interface{}{
+		"token_type": "Bearer",
+		"expires_in": int(tokenLifetime / time.Second),
+		"scope":      r.PostForm.Get("scope"),
+		"created_at": time.Now().Unix(),
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		if secret == "" {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_client")
+			return
+		}
+	case "refresh_token":
+		refreshToken := r.PostForm.Get("refresh_token")
+		if !s.refreshTokens[refreshToken] {
+			writeTokenError(w, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+		delete(s.refreshTokens, refreshToken)
+		response["refresh_token"] = s.newRefreshToken()
+	default:
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	s.tokenSeq++
+	accessToken := fmt.Sprintf("access-%d", s.tokenSeq)
+	s.tokens[accessToken] = true
+	response["access_token"] = accessToken
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) newRefreshToken() string {
+	s.tokenSeq++
+	token := fmt.Sprintf("refresh-%d", s.tokenSeq)
+	s.refreshTokens[token] = true
+	return token
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := decodeElements[model.Project](s.fixtures.Projects)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	filters, err := queryFilters(r, nil)
+	if err == nil {
+		projects, err = filterElements(projects, filters, matchProject)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidQuery", err.Error())
+		return
+	}
+	writePage(w, r, raws(projects))
+}
+
+func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.findProject(r.PathValue("project"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+		return
+	}
+	writeRaw(w, project.raw)
+}
+
+func (s *Server) findProject(ref string) (element[model.Project], bool) {
+	projects, _ := decodeElements[model.Project](s.fixtures.Projects)
+	for _, project := range projects {
+		if strconv.Itoa(project.value.ID) == ref || project.value.Identifier == ref {
+			return project, true
+		}
+	}
+	return element[model.Project]{}, false
+}
+
+func (s *Server) handleProjectWorkPackages(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.findProject(r.PathValue("project"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+		return
+	}
+	s.serveWorkPackages(w, r, filter{name: "project", operator: "=", values: []string{strconv.Itoa(project.value.ID)}})
+}
+
+func (s *Server) handleWorkPackages(w http.ResponseWriter, r *http.Request) {
+	s.serveWorkPackages(w, r)
+}
+
+func (s *Server) serveWorkPackages(w http.ResponseWriter, r *http.Request, scope ...filter) {
+	workPackages, err := decodeElements[model.WorkPackage](s.fixtures.WorkPackages)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	filters, err := queryFilters(r, defaultWorkPackageFilters)
+	if err == nil {
+		workPackages, err = filterElements(workPackages, append(scope, filters...), s.matchWorkPackage)
+	}
+	if err == nil {
+		sortBy := r.URL.Query().Get("sortBy")
+		if sortBy == "" {
+			sortBy = `[["id","asc"]]`
+		}
+		err = sortWorkPackages(workPackages, sortBy)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidQuery", err.Error())
+		return
+	}
+	writePage(w, r, raws(workPackages))
+}
+
+func (s *Server) findWorkPackage(ref string) (element[model.WorkPackage], bool) {
+	workPackages, _ := decodeElements[model.WorkPackage](s.fixtures.WorkPackages)
+	for _, workPackage := range workPackages {
+		if strconv.Itoa(workPackage.value.ID) == ref {
+			return workPackage, true
+		}
+	}
+	return element[model.WorkPackage]{}, false
+}
+
+func (s *Server) handleWorkPackage(w http.ResponseWriter, r *http.Request) {
+	workPackage, ok := s.findWorkPackage(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+		return
+	}
+	writeRaw(w, workPackage.raw)
+}
+
+// handleActivities returns every activity at once, like OpenProject, which
+// does not paginate them.
+func (s *Server) handleActivities(w http.ResponseWriter, r *http.Request) {
+	workPackage, ok := s.findWorkPackage(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+		return
+	}
+	writeAll(w, r, s.fixtures.Activities[workPackage.value.ID])
+}
+
+// handleRelations returns the relations from or to a work package at once.
+func (s *Server) handleRelations(w http.ResponseWriter, r *http.Request) {
+	workPackage, ok := s.findWorkPackage(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+		return
+	}
+	relations, err := decodeElements[model.Relation](s.fixtures.Relations)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	var involved []element[model.Relation]
+	for _, relation := range relations {
+		from, _ := relation.value.Links.From.ID()
+		to, _ := relation.value.Links.To.ID()
+		if from == workPackage.value.ID || to == workPackage.value.ID {
+			involved = append(involved, relation)
+		}
+	}
+	writeAll(w, r, raws(involved))
+}
+
+func (s *Server) handleStatuses(w http.ResponseWriter, r *http.Request) {
+	writeAll(w, r, s.fixtures.Statuses)
+}
+
+func (s *Server) handleWeekDays(w http.ResponseWriter, r *http.Request) {
+	writeAll(w, r, s.fixtures.WeekDays)
+}
+
+func (s *Server) handleNonWorkingDays(w http.ResponseWriter, r *http.Request) {
+	days, err := decodeElements[model.NonWorkingDay](s.fixtures.NonWorkingDays)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	filters, err := queryFilters(r, nil)
+	if err == nil {
+		days, err = filterElements(days, filters, matchNonWorkingDay)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidQuery", err.Error())
+		return
+	}
+	writeAll(w, r, raws(days))
+}
+
+func (s *Server) handleTimeEntries(w http.ResponseWriter, r *http.Request) {
+	entries, err := decodeElements[model.TimeEntry](s.fixtures.TimeEntries)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	filters, err := queryFilters(r, nil)
+	if err == nil {
+		entries, err = filterElements(entries, filters, matchTimeEntry)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidQuery", err.Error())
+		return
+	}
+	writePage(w, r, raws(entries))
+}
+
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	writePage(w, r, s.fixtures.Users)
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	users, err := decodeElements[model.User](s.fixtures.Users)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	for _, user := range users {
+		if strconv.Itoa(user.value.ID) == r.PathValue("id") {
+			writeRaw(w, user.raw)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+}
+
+func (s *Server) handleGroups(w http.ResponseWriter, r *http.Request) {
+	writePage(w, r, s.fixtures.Groups)
+}
+
+// handlePrincipals lists users, then groups, then placeholder users, and
+// supports the member and type filters.
+func (s *Server) handlePrincipals(w http.ResponseWriter, r *http.Request) {
+	var all []json.RawMessage
+	all = append(all, s.fixtures.Users...)
+	all = append(all, s.fixtures.Groups...)
+	all = append(all, s.fixtures.Placeholders...)
+	principals, err := decodeElements[model.Principal](all)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	filters, err := queryFilters(r, nil)
+	if err == nil {
+		principals, err = filterElements(principals, filters, s.matchPrincipal)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidQuery", err.Error())
+		return
+	}
+	writePage(w, r, raws(principals))
+}
+
+func (s *Server) handleMemberships(w http.ResponseWriter, r *http.Request) {
+	memberships, err := s.memberships()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	filters, err := queryFilters(r, nil)
+	if err == nil {
+		memberships, err = filterElements(memberships, filters, matchMembership)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidQuery", err.Error())
+		return
+	}
+	writePage(w, r, raws(memberships))
+}
+
+func (s *Server) memberships() ([]element[model.Membership], error) {
+	return decodeElements[model.Membership](s.fixtures.Memberships)
+}
+
+func (s *Server) handleVersions(w http.ResponseWriter, r *http.Request) {
+	writeAll(w, r, s.fixtures.Versions)
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	versions, err := decodeElements[model.Version](s.fixtures.Versions)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	for _, version := range versions {
+		if strconv.Itoa(version.value.ID) == r.PathValue("id") {
+			writeRaw(w, version.raw)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+}
+
+// handleProjectVersions returns the versions a project defines, and the ones
+// its ancestors share with their subprojects or every project shares.
+func (s *Server) handleProjectVersions(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.findProject(r.PathValue("project"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "The requested resource could not be found.")
+		return
+	}
+	versions, err := decodeElements[model.Version](s.fixtures.Versions)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	ancestors := s.ancestors(project.value)
+	var available []element[model.Version]
+	for _, version := range versions {
+		definingID, _ := version.value.Links.DefiningProject.ID()
+		shared := version.value.Sharing == "system" || (ancestors[definingID] && version.value.Sharing != "none")
+		if definingID == project.value.ID || shared {
+			available = append(available, version)
+		}
+	}
+	writeAll(w, r, raws(available))
+}
+
+func (s *Server) ancestors(project model.Project) map[int]bool {
+	ancestors := make(map[int]bool)
+	for {
+		parentID, ok := project.ParentID()
+		if !ok || ancestors[parentID] {
+			return ancestors
+		}
+		ancestors[parentID] = true
+		parent, ok := s.findProject(strconv.Itoa(parentID))
+		if !ok {
+			return ancestors
+		}
+		project = parent.value
+	}
+}
+
+func queryFilters(r *http.Request, defaults []filter) ([]filter, error) {
+	if !r.URL.Query().Has("filters") {
+		return defaults, nil
+	}
+	return parseFilters(r.URL.Query().Get("filters"))
+}
+
+func decodeElements[T any](values []json.RawMessage) ([]element[T], error) {
+	elements := make([]element[T], 0, len(values))
+	for _, raw := range values {
+		var value T
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		elements = append(elements, element[T]{raw: raw, value: value})
+	}
+	return elements, nil
+}
+
+func filterElements[T any](elements []element[T], filters []filter, match func(T, filter) (bool, error)) ([]element[T], error) {
+	var matched []element[T]
+	for _, e := range elements {
+		keep := true
+		for _, f := range filters {
+			ok, err := match(e.value, f)
+			if err != nil {
+				return nil, err
+			}
+			keep = keep && ok
+		}
+		if keep {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+func raws[T any](elements []element[T]) []json.RawMessage {
+	values := make([]json.RawMessage, len(elements))
+	for i, e := range elements {
+		values[i] = e.raw
+	}
+	return values
+}
+
+// writePage answers with one page selected by pageSize and offset. Like
+// OpenProject, responses narrowed with select carry no links.
+func writePage(w http.ResponseWriter, r *http.Request, elements []json.RawMessage) {
+	query := r.URL.Query()
+	pageSize, offset := DefaultPageSize, 1
+	var err error
+	if value := query.Get("pageSize"); value != "" {
+		if pageSize, err = strconv.Atoi(value); err != nil || pageSize < 0 {
+			writeError(w, http.StatusBadRequest, "InvalidQuery", "pageSize must be a positive integer.")
+			return
+		}
+		pageSize = min(pageSize, MaxPageSize)
+	}
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 1 {
+			writeError(w, http.StatusBadRequest, "InvalidQuery", "offset must be a positive integer.")
+			return
+		}
+	}
+
+	start := min((offset-1)*pageSize, len(elements))
+	end := min(start+pageSize, len(elements))
+	page := collection{
+		Type:     "Collection",
+		Total:    len(elements),
+		Count:    end - start,
+		PageSize: pageSize,
+		Offset:   offset,
+	}
+	page.Embedded.Elements = append([]json.RawMessage{}, elements[start:end]...)
+	if !query.Has("select") {
+		page.Links = map[string]model.Link{"self": pageLink(r, offset, pageSize)}
+		if offset*pageSize < len(elements) {
+			page.Links["nextByOffset"] = pageLink(r, offset+1, pageSize)
+		}
+		if offset > 1 {
+			page.Links["previousByOffset"] = pageLink(r, offset-1, pageSize)
+		}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func writeAll(w http.ResponseWriter, r *http.Request, elements []json.RawMessage) {
+	page := collection{
+		Type:  "Collection",
+		Total: len(elements),
+		Count: len(elements),
+		Links: map[string]model.Link{"self": {Href: r.URL.Path}},
+	}
+	page.Embedded.Elements = append([]json.RawMessage{}, elements...)
+	writeJSON(w, http.StatusOK, page)
+}
+
+func pageLink(r *http.Request, offset, pageSize int) model.Link {
+	query := r.URL.Query()
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("pageSize", strconv.Itoa(pageSize))
+	return model.Link{Href: r.URL.Path + "?" + query.Encode()}
+}
+
+func writeRaw(w http.ResponseWriter, raw json.RawMessage) {
+	w.Header().Set("Content-Type", "application/hal+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(raw)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/hal+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, identifier, message string) {
+	writeJSON(w, status, map[string]string{
+		"_type":           "Error",
+		"errorIdentifier": errorURN + identifier,
+		"message":         message,
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -0,0 +1,138 @@
+package fakeapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"openproject-crawler/pkg/model"
+	"reflect"
+	"testing"
+)
+
+func get(t *testing.T, rawURL string, header http.Header) (int, model.Collection[model.WorkPackage]) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var page model.Collection[model.WorkPackage]
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, page
+}
+
+func ids(page model.Collection[model.WorkPackage]) []int {
+	var result []int
+	for _, wp := range page.Elements() {
+		result = append(result, wp.ID)
+	}
+	return result
+}
+
+func TestWorkPackagesPagination(t *testing.T) {
+	srv := New(nil)
+	defer srv.Close()
+
+	status, page := get(t, srv.APIURL()+"/work_packages?filters=[]&pageSize=2&offset=2", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if page.Total != 5 || page.Count != 2 || page.PageSize != 2 || page.Offset != 2 {
+		t.Errorf("total/count/pageSize/offset = %d/%d/%d/%d", page.Total, page.Count, page.PageSize, page.Offset)
+	}
+	if got := ids(page); !reflect.DeepEqual(got, []int{103, 201}) {
+		t.Errorf("ids = %v", got)
+	}
+	next, err := url.Parse(page.Links.NextByOffset.Href)
+	if err != nil || next.Query().Get("offset") != "3" {
+		t.Errorf("nextByOffset = %q", page.Links.NextByOffset.Href)
+	}
+
+	_, last := get(t, srv.APIURL()+"/work_packages?filters=[]&pageSize=2&offset=3", nil)
+	if !last.Links.NextByOffset.IsZero() {
+		t.Errorf("last page links to %q", last.Links.NextByOffset.Href)
+	}
+}
+
+func TestWorkPackageFilters(t *testing.T) {
+	srv := New(nil)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		filters string
+		want    []int
+		status  int
+	}{
+		{"default open", "/work_packages", "", []int{102, 103, 301}, http.StatusOK},
+		{"all", "/work_packages", `[]`, []int{101, 102, 103, 201, 301}, http.StatusOK},
+		{"closed", "/work_packages", `[{"status":{"operator":"c","values":[]}}]`, []int{101, 201}, http.StatusOK},
+		{"project scope", "/projects/demo/work_packages", `[]`, []int{101, 102, 103}, http.StatusOK},
+		{"type", "/work_packages", `[{"type":{"operator":"=","values":["2","4"]}}]`, []int{101, 103}, http.StatusOK},
+		{"updated between", "/work_packages", `[{"updatedAt":{"operator":"<>d","values":["2024-03-06","2024-03-08"]}}]`, []int{101, 102}, http.StatusOK},
+		{"subject", "/work_packages", `[{"subject":{"operator":"~","values":["csv"]}}]`, []int{103}, http.StatusOK},
+		{"version", "/work_packages", `[{"version":{"operator":"=","values":["1"]}}]`, []int{101, 102}, http.StatusOK},
+		{"unknown filter", "/work_packages", `[{"colour":{"operator":"=","values":["1"]}}]`, nil, http.StatusBadRequest},
+		{"unknown project", "/projects/nope/work_packages", `[]`, nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			if tt.filters != "" {
+				query.Set("filters", tt.filters)
+			}
+			status, page := get(t, srv.APIURL()+tt.path+"?"+query.Encode(), nil)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if got := ids(page); tt.status == http.StatusOK && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	srv := New(nil)
+	defer srv.Close()
+	srv.SetAPIKey("secret")
+
+	if status, _ := get(t, srv.APIURL()+"/statuses", nil); status != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d", status)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("apikey", "secret")
+	if status, _ := get(t, srv.APIURL()+"/statuses", req.Header); status != http.StatusOK {
+		t.Errorf("authenticated status = %d", status)
+	}
+}
+
+func TestFaultTimes(t *testing.T) {
+	srv := New(nil)
+	defer srv.Close()
+	srv.Inject(Fault{Path: "/statuses", Status: http.StatusServiceUnavailable, Times: 2})
+
+	var statuses []int
+	for i := 0; i < 3; i++ {
+		status, _ := get(t, srv.APIURL()+"/statuses", nil)
+		statuses = append(statuses, status)
+	}
+	if want := []int{503, 503, 200}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if got := srv.RequestCount("/statuses"); got != 3 {
+		t.Errorf("RequestCount = %d", got)
+	}
+}
@@ -0,0 +1,363 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CassetteMode string
+
+const (
+	// ModeRecord sends requests and saves every exchange to the cassette.
+	ModeRecord CassetteMode = "record"
+	// ModeReplay answers from the cassette and never touches the network.
+	ModeReplay CassetteMode = "replay"
+	// ModePassthrough sends requests without recording them.
+	ModePassthrough CassetteMode = "passthrough"
+)
+
+const redacted = "REDACTED"
+
+// ErrNotRecorded is returned in replay mode for requests the cassette has
+// no exchange for. Such requests are not retried.
+var ErrNotRecorded = errors.New("request not recorded in cassette")
+
+// defaultRedactedHeaders never reach a cassette file.
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// MatchOptions selects which parts of a request must equal a recorded one
+// for it to be replayed. IgnoreParams leaves query parameters out of the
+// comparison, e.g. "filters" when they hold dates relative to the day of the
+// crawl. The options only matter when replaying.
+type MatchOptions struct {
+	Method       bool
+	Path         bool
+	Query        bool
+	IgnoreParams []string
+}
+
+func StrictMatch() MatchOptions {
+	return MatchOptions{Method: true, Path: true, Query: true}
+}
+
+// Cassette is an http.RoundTripper that records exchanges into a directory,
+// one JSON file per method and path, and replays them. Repeated requests,
+// such as retries or pages replayed without matching the query, are
+// answered in the order they were recorded; the last matching exchange keeps
+// answering once the others are used up.
+type Cassette struct {
+	dir       string
+	mode      CassetteMode
+	match     MatchOptions
+	transport http.RoundTripper
+	redact    []string
+	mu        sync.Mutex
+	files     map[string][]*Interaction
+	loadedAll bool
+	played    map[*Interaction]bool
+}
+
+type cassetteFile struct {
+	Key          string         `json:"key"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recordedAt"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// RecordedResponse keeps JSON bodies as JSON so cassettes stay readable and
+// editable; other bodies are kept as text.
+type RecordedResponse struct {
+	StatusCode int             `json:"statusCode"`
+	Header     http.Header     `json:"header,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	BodyText   string          `json:"bodyText,omitempty"`
+}
+
+func ParseCassetteMode(value string) (CassetteMode, error) {
+	switch mode := CassetteMode(value); mode {
+	case ModeRecord, ModeReplay, ModePassthrough:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown cassette mode %q", value)
+}
+
+func NewCassette(dir string, mode CassetteMode, match MatchOptions) (*Cassette, error) {
+	if _, err := ParseCassetteMode(string(mode)); err != nil {
+		return nil, err
+	}
+	if dir == "" && mode != ModePassthrough {
+		return nil, errors.New("cassette directory was empty")
+	}
+	if mode == ModeRecord {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	}
+	return &Cassette{
+		dir:       dir,
+		mode:      mode,
+		match:     match,
+		transport: http.DefaultTransport,
+		redact:    defaultRedactedHeaders,
+		files:     make(map[string][]*Interaction),
+		played:    make(map[*Interaction]bool),
+	}, nil
+}
+
+func (c *Cassette) GetMode() CassetteMode {
+	return c.mode
+}
+
+// SetTransport sets the transport requests go out through when recording or
+// passing through; http.DefaultTransport by default.
+func (c *Cassette) SetTransport(transport http.RoundTripper) {
+	c.transport = transport
+}
+
+// AddRedactedHeaders redacts more headers, e.g. a custom API token header,
+// besides Authorization, Proxy-Authorization, Cookie and Set-Cookie.
+func (c *Cassette) AddRedactedHeaders(names ...string) {
+	c.redact = append(append([]string{}, c.redact...), names...)
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch c.mode {
+	case ModeReplay:
+		return c.replay(req)
+	case ModeRecord:
+		return c.record(req)
+	}
+	return c.transport.RoundTrip(req)
+}
+
+// record sends req and saves the exchange. The first exchange recorded for a
+// method and path replaces what an earlier recording left in its file.
+func (c *Cassette) record(req *http.Request) (*http.Response, error) {
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response to record: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Header: c.redactHeader(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     c.redactHeader(resp.Header),
+		},
+		RecordedAt: time.Now().UTC(),
+	}
+	if json.Valid(body) {
+		interaction.Response.Body = body
+	} else {
+		interaction.Response.BodyText = string(body)
+	}
+
+	key := fileKey(req.Method, req.URL.Path)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[key] = append(c.files[key], interaction)
+	if err := c.save(key); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	candidates, err := c.candidates(req)
+	if err != nil {
+		return nil, err
+	}
+	var found *Interaction
+	for _, interaction := range candidates {
+		if !c.matches(req, interaction.Request) {
+			continue
+		}
+		found = interaction
+		if !c.played[interaction] {
+			break
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, req.URL.RequestURI())
+	}
+	c.played[found] = true
+
+	recorded := found.Response
+	body := []byte(recorded.Body)
+	if recorded.Body == nil {
+		body = []byte(recorded.BodyText)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// candidates returns the recordings req may match: those of its file when
+// method and path must match, otherwise every recording in recorded order.
+func (c *Cassette) candidates(req *http.Request) ([]*Interaction, error) {
+	if c.match.Method && c.match.Path {
+		key := fileKey(req.Method, req.URL.Path)
+		if _, ok := c.files[key]; !ok {
+			file, err := c.load(c.fileName(key))
+			if err != nil {
+				return nil, err
+			}
+			c.files[key] = file.Interactions
+		}
+		return c.files[key], nil
+	}
+
+	if !c.loadedAll {
+		paths, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cassette: %w", err)
+		}
+		for _, path := range paths {
+			file, err := c.load(path)
+			if err != nil {
+				return nil, err
+			}
+			c.files[file.Key] = file.Interactions
+		}
+		c.loadedAll = true
+	}
+	var all []*Interaction
+	for _, interactions := range c.files {
+		all = append(all, interactions...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].RecordedAt.Before(all[j].RecordedAt)
+	})
+	return all, nil
+}
+
+func (c *Cassette) matches(req *http.Request, recorded RecordedRequest) bool {
+	if c.match.Method && recorded.Method != req.Method {
+		return false
+	}
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	if c.match.Path && recordedURL.Path != req.URL.Path {
+		return false
+	}
+	return !c.match.Query || c.query(recordedURL.Query()) == c.query(req.URL.Query())
+}
+
+func (c *Cassette) query(values url.Values) string {
+	for _, name := range c.match.IgnoreParams {
+		values.Del(strings.TrimSpace(name))
+	}
+	return values.Encode()
+}
+
+func fileKey(method, path string) string {
+	return method + " " + path
+}
+
+// fileName combines a readable form of key with its hash, e.g.
+// "GET_api_v3_statuses-3f2a9c1b7d4e.json".
+func (c *Cassette) fileName(key string) string {
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(key, "_"), "_")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, name+"-"+hex.EncodeToString(sum[:6])+".json")
+}
+
+func (c *Cassette) load(path string) (cassetteFile, error) {
+	var file cassetteFile
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return file, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return file, nil
+}
+
+func (c *Cassette) save(key string) error {
+	content, err := json.MarshalIndent(cassetteFile{Key: key, Interactions: c.files[key]}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.WriteFile(c.fileName(key), append(content, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+func (c *Cassette) redactHeader(header http.Header) http.Header {
+	clean := header.Clone()
+	for _, name := range c.redact {
+		if _, ok := clean[http.CanonicalHeaderKey(name)]; ok {
+			clean.Set(name, redacted)
+		}
+	}
+	return clean
+}
+
+// GetTransport returns the transport requests are sent through; nil means
+// http.DefaultTransport.
+func (api *APIClient) GetTransport() http.RoundTripper {
+	return api.client.Transport
+}
+
+// SetTransport replaces the transport requests are sent through, e.g. with
+// a Cassette.
+func (api *APIClient) SetTransport(transport http.RoundTripper) {
+	api.client.Transport = transport
+}
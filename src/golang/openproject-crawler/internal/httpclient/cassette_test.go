@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"openproject-crawler/internal/credential"
+	"openproject-crawler/internal/fakeapi"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCassetteClient(t *testing.T, apiURL, authToken string, cassette *Cassette) *APIClient {
+	t.Helper()
+	api, err := NewAPIClient(apiURL, authToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.SetRateLimiter(nil)
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = 0
+	if err := api.SetRetryPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	api.SetTransport(cassette)
+	return api
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	srv := fakeapi.New(nil)
+	srv.SetAPIKey("top-secret")
+	srv.Inject(fakeapi.Fault{Path: "/statuses", Status: http.StatusTooManyRequests, RetryAfter: "0", Times: 1})
+	cred, err := credential.SetCredential(credential.APIKeyUsername, "top-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder, err := NewCassette(dir, ModeRecord, StrictMatch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newCassetteClient(t, srv.APIURL(), cred.GenerateToken(), recorder)
+	api.SetPageSize(2)
+	ctx := context.Background()
+	if _, err := api.GetCollection(ctx, "/work_packages", allWorkPackages); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.GetCollection(ctx, "/statuses", nil); err != nil {
+		t.Fatal(err)
+	}
+	apiURL := srv.APIURL()
+	srv.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("cassette files = %d, want work packages and statuses", len(files))
+	}
+	for _, file := range files {
+		content, _ := os.ReadFile(file)
+		if strings.Contains(string(content), cred.GenerateToken()) {
+			t.Errorf("%s contains the credential", filepath.Base(file))
+		}
+	}
+
+	player, err := NewCassette(dir, ModeReplay, StrictMatch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	api = newCassetteClient(t, apiURL, "", player)
+	api.SetPageSize(2)
+	collection, err := api.GetCollection(ctx, "/work_packages", allWorkPackages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.Elements) != 5 || collection.Pages != 3 {
+		t.Errorf("replayed %d elements in %d pages", len(collection.Elements), collection.Pages)
+	}
+	collection, err = api.GetCollection(ctx, "/statuses", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collection.Retries != 1 {
+		t.Errorf("replayed retries = %d, want the recorded 429 first", collection.Retries)
+	}
+}
+
+func TestCassetteMatching(t *testing.T) {
+	dir := t.TempDir()
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+
+	recorder, err := NewCassette(dir, ModeRecord, StrictMatch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := map[string]interface{}{"filters": `[{"updatedAt":{"operator":"<>d","values":["2024-03-01","2024-03-31"]}}]`}
+	ctx := context.Background()
+	if _, err := newCassetteClient(t, srv.APIURL(), "", recorder).GetCollection(ctx, "/work_packages", params); err != nil {
+		t.Fatal(err)
+	}
+
+	shifted := map[string]interface{}{"filters": `[{"updatedAt":{"operator":"<>d","values":["2024-03-02","2024-04-01"]}}]`}
+	ignoring := StrictMatch()
+	ignoring.IgnoreParams = []string{"filters"}
+	tests := []struct {
+		name   string
+		match  MatchOptions
+		params map[string]interface{}
+		hit    bool
+	}{
+		{"same query", StrictMatch(), params, true},
+		{"strict query", StrictMatch(), shifted, false},
+		{"ignored param", ignoring, shifted, true},
+		{"query not matched", MatchOptions{Method: true, Path: true}, shifted, true},
+		{"any path, strict query", MatchOptions{Method: true, Query: true}, shifted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player, err := NewCassette(dir, ModeReplay, tt.match)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := srv.RequestCount("")
+			_, err = newCassetteClient(t, srv.APIURL(), "", player).GetCollection(ctx, "/work_packages", tt.params)
+			if tt.hit && err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+			if !tt.hit && !errors.Is(err, ErrNotRecorded) {
+				t.Fatalf("err = %v, want ErrNotRecorded", err)
+			}
+			if got := srv.RequestCount(""); got != before {
+				t.Errorf("replay sent %d requests", got-before)
+			}
+		})
+	}
+}
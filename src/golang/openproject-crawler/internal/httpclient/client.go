@@ -1,10 +1,14 @@
 package httpclient
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"openproject-crawler/internal/core"
+	"openproject-crawler/internal/credential"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +17,12 @@ type APIClient struct {
 	authToken string
 	client    *http.Client
 	headers   map[string]string
+	pageSize  int
+	maxPages  int
+	retry     RetryPolicy
+	retries   atomic.Int64
+	limiter   *RateLimiter
+	auth      credential.Provider
 }
 
 func NewAPIClient(baseURL, authToken string) (*APIClient, error) {
@@ -20,8 +30,9 @@ func NewAPIClient(baseURL, authToken string) (*APIClient, error) {
 		"Content-Type": "application/json",
 	}
 
+	var auth credential.Provider
 	if authToken != "" {
-		headers["Authorization"] = "Basic " + authToken
+		auth = credential.NewBasicProvider(authToken)
 	}
 
 	urlHandler, err := core.NewURLHandler(baseURL, "")
@@ -34,30 +45,71 @@ func NewAPIClient(baseURL, authToken string) (*APIClient, error) {
 		authToken:  authToken,
 		headers:    headers,
 		client:     &http.Client{Timeout: 30 * time.Second},
+		pageSize:   DefaultPageSize,
+		maxPages:   DefaultMaxPages,
+		retry:      DefaultRetryPolicy(),
+		limiter:    NewRateLimiter(DefaultRequestsPerSecond, DefaultBurst),
+		auth:       auth,
 	}, nil
 }
 
-func (api *APIClient) GetRequest(customURI string, params map[string]interface{}) (string, error) {
+func (api *APIClient) GetAuthProvider() credential.Provider {
+	return api.auth
+}
+
+// SetAuthProvider replaces the Basic auth built from the client's token.
+// A nil provider sends requests unauthenticated.
+func (api *APIClient) SetAuthProvider(provider credential.Provider) {
+	api.auth = provider
+}
+
+func (api *APIClient) GetRequest(ctx context.Context, customURI string, params map[string]interface{}) (string, error) {
 	if customURI != "" {
 		api.SetURIPath(customURI)
 	}
 
-	fullURL := api.GetFullURL()
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	if len(params) == 0 {
+		params["pageSize"] = api.pageSize
+	}
+
+	body, _, err := api.get(ctx, api.GetFullURL(), params)
+	return body, err
+}
 
-	req, err := http.NewRequest("GET", fullURL, nil)
+// GetResource fetches a single resource, e.g. "/users/5", and decodes it
+// into T. Unlike GetRequest it leaves the client's URI path alone, so it is
+// safe for concurrent use.
+func GetResource[T any](ctx context.Context, api *APIClient, customURI string) (T, error) {
+	var resource T
+	body, _, err := api.get(ctx, api.ResolveURL(customURI), nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return resource, err
+	}
+	if err := json.Unmarshal([]byte(body), &resource); err != nil {
+		return resource, fmt.Errorf("failed to decode %s: %w", customURI, err)
 	}
+	return resource, nil
+}
 
-	for key, value := range api.headers {
-		req.Header.Set(key, value)
+func (api *APIClient) get(ctx context.Context, fullURL string, params map[string]interface{}) (string, int, error) {
+	req, err := api.newRequest(ctx, http.MethodGet, fullURL, params)
+	if err != nil {
+		return "", 0, err
 	}
+	return api.doWithRetry(req)
+}
 
-	if params == nil {
-		params = make(map[string]interface{})
+func (api *APIClient) newRequest(ctx context.Context, method, fullURL string, params map[string]interface{}) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	if len(params) == 0 {
-		params["pageSize"] = "1000"
+
+	for key, value := range api.headers {
+		req.Header.Set(key, value)
 	}
 
 	q := req.URL.Query()
@@ -66,20 +118,36 @@ func (api *APIClient) GetRequest(customURI string, params map[string]interface{}
 	}
 	req.URL.RawQuery = q.Encode()
 
+	return req, nil
+}
+
+func (api *APIClient) do(req *http.Request) (string, *http.Response, error) {
+	if api.limiter != nil {
+		if err := api.limiter.Wait(req.Context(), req.URL.Host); err != nil {
+			return "", nil, err
+		}
+	}
+	if api.auth != nil {
+		if err := api.auth.Authorize(req); err != nil {
+			return "", nil, &AuthError{Err: err}
+		}
+	}
+
 	resp, err := api.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute request: %w", err)
+		return "", nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch URL: %s", resp.Status)
+		io.Copy(io.Discard, resp.Body)
+		return "", resp, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return "", resp, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	return string(body), nil
+	return string(body), resp, nil
 }
@@ -0,0 +1,177 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"openproject-crawler/internal/credential"
+	"openproject-crawler/internal/fakeapi"
+	"strings"
+	"testing"
+	"time"
+)
+
+var allWorkPackages = map[string]interface{}{"filters": "[]"}
+
+func newTestClient(t *testing.T, srv *fakeapi.Server, authToken string) *APIClient {
+	t.Helper()
+	api, err := NewAPIClient(srv.APIURL(), authToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.SetRateLimiter(nil)
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 3
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 5 * time.Millisecond
+	if err := api.SetRetryPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+func TestGetCollectionPages(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		params    map[string]interface{}
+		maxPages  int
+		wantLen   int
+		wantPages int
+		truncated bool
+	}{
+		{"next links", allWorkPackages, 0, 5, 3, false},
+		{"select without links", map[string]interface{}{"filters": "[]", "select": "total,count,pageSize,offset,elements/id"}, 0, 5, 3, false},
+		{"page limit", allWorkPackages, 2, 4, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestClient(t, srv, "")
+			api.SetPageSize(2)
+			api.SetMaxPages(tt.maxPages)
+			collection, err := api.GetCollection(context.Background(), "/work_packages", tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(collection.Elements) != tt.wantLen || collection.Pages != tt.wantPages || collection.Truncated != tt.truncated {
+				t.Errorf("elements/pages/truncated = %d/%d/%v", len(collection.Elements), collection.Pages, collection.Truncated)
+			}
+			if collection.Total != 5 {
+				t.Errorf("total = %d", collection.Total)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		fault       fakeapi.Fault
+		wantRetries int
+		wantStatus  int
+	}{
+		{"rate limited", fakeapi.Fault{Status: http.StatusTooManyRequests, RetryAfter: "0", Times: 2}, 2, 0},
+		{"retry after beyond max delay", fakeapi.Fault{Status: http.StatusServiceUnavailable, RetryAfter: "3600", Times: 1}, 1, 0},
+		{"server error", fakeapi.Fault{Status: http.StatusInternalServerError}, 2, http.StatusInternalServerError},
+		{"not found", fakeapi.Fault{Status: http.StatusNotFound}, 0, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeapi.New(nil)
+			defer srv.Close()
+			srv.Inject(tt.fault)
+
+			api := newTestClient(t, srv, "")
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			collection, err := api.GetCollection(ctx, "/statuses", nil)
+			if collection.Retries != tt.wantRetries {
+				t.Errorf("retries = %d, want %d", collection.Retries, tt.wantRetries)
+			}
+			var statusErr *StatusError
+			switch {
+			case tt.wantStatus == 0 && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus):
+				t.Fatalf("err = %v, want status %d", err, tt.wantStatus)
+			}
+			if got := srv.RequestCount("/statuses"); got != tt.wantRetries+1 {
+				t.Errorf("requests = %d", got)
+			}
+		})
+	}
+}
+
+func TestMalformedJSON(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	srv.Inject(fakeapi.Fault{Path: "/statuses", Malformed: true})
+
+	api := newTestClient(t, srv, "")
+	_, err := api.GetCollection(context.Background(), "/statuses", nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to parse collection page") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	srv.SetAPIKey("secret")
+
+	cred, err := credential.SetCredential(credential.APIKeyUsername, "wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newTestClient(t, srv, cred.GenerateToken())
+	_, err = api.GetCollection(context.Background(), "/statuses", nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("err = %v, want 401", err)
+	}
+	if got := srv.RequestCount("/statuses"); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestOAuth2Refresh(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	srv.SetOAuth2Client("crawler", "s3cret")
+
+	provider, err := credential.NewOAuth2Provider(credential.OAuth2Config{
+		TokenURL:     srv.URL + "/oauth/token",
+		ClientID:     "crawler",
+		ClientSecret: "s3cret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newTestClient(t, srv, "")
+	api.SetAuthProvider(provider)
+
+	ctx := context.Background()
+	if _, err := api.GetCollection(ctx, "/statuses", nil); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	collection, err := api.GetCollection(ctx, "/statuses", nil)
+	if err != nil {
+		t.Fatalf("request after expiry: %v", err)
+	}
+	if collection.Retries != 0 {
+		t.Errorf("refresh counted as %d retries", collection.Retries)
+	}
+
+	tokenRequests := 0
+	for _, req := range srv.Requests() {
+		if req.Path == "/oauth/token" {
+			tokenRequests++
+		}
+	}
+	if tokenRequests != 2 {
+		t.Errorf("token requests = %d, want 2", tokenRequests)
+	}
+}
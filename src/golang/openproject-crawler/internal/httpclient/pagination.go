@@ -0,0 +1,154 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"openproject-crawler/pkg/model"
+	"strconv"
+)
+
+const (
+	DefaultPageSize = 1000
+	DefaultMaxPages = 100
+)
+
+type Collection struct {
+	Total     int
+	PageSize  int
+	Pages     int
+	Retries   int
+	Truncated bool
+	Elements  []json.RawMessage
+}
+
+func (api *APIClient) GetPageSize() int {
+	return api.pageSize
+}
+
+func (api *APIClient) SetPageSize(value int) error {
+	if value <= 0 {
+		return fmt.Errorf("page size must be positive, got %d", value)
+	}
+	api.pageSize = value
+	return nil
+}
+
+func (api *APIClient) GetMaxPages() int {
+	return api.maxPages
+}
+
+// SetMaxPages bounds how many pages GetCollection walks; zero or less means no limit.
+func (api *APIClient) SetMaxPages(value int) {
+	api.maxPages = value
+}
+
+// GetCollection fetches every page of a HAL collection by following
+// _links.nextByOffset until the last page or the page limit is reached.
+// On error the pages fetched so far are returned alongside it.
+func (api *APIClient) GetCollection(ctx context.Context, customURI string, params map[string]interface{}) (*Collection, error) {
+	if customURI == "" {
+		customURI = api.GetURIPath()
+	}
+	fullURL := api.ResolveURL(customURI)
+
+	query := make(map[string]interface{}, len(params)+2)
+	for key, value := range params {
+		query[key] = value
+	}
+	if _, ok := query["pageSize"]; !ok {
+		query["pageSize"] = api.pageSize
+	}
+	offset, err := parseOffset(query["offset"])
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &Collection{Elements: []json.RawMessage{}}
+	for {
+		if api.maxPages > 0 && collection.Pages >= api.maxPages {
+			collection.Truncated = true
+			break
+		}
+		query["offset"] = offset
+
+		response, retries, err := api.get(ctx, fullURL, query)
+		collection.Retries += retries
+		if err != nil {
+			return collection, err
+		}
+
+		var page model.Collection[json.RawMessage]
+		if err := json.Unmarshal([]byte(response), &page); err != nil {
+			return collection, fmt.Errorf("failed to parse collection page %d: %w", offset, err)
+		}
+		if page.Embedded.Elements == nil {
+			return collection, fmt.Errorf("_embedded or elements key not found on page %d", offset)
+		}
+
+		collection.Total = page.Total
+		collection.PageSize = page.PageSize
+		collection.Pages++
+		collection.Elements = append(collection.Elements, page.Embedded.Elements...)
+
+		next, ok := nextOffset(&page, offset)
+		if !ok {
+			break
+		}
+		offset = next
+	}
+
+	return collection, nil
+}
+
+// GetElements walks a collection like GetCollection and decodes its elements
+// into T. On error the elements decoded so far are returned alongside it.
+func GetElements[T any](ctx context.Context, api *APIClient, customURI string, params map[string]interface{}) ([]T, *Collection, error) {
+	collection, err := api.GetCollection(ctx, customURI, params)
+	if collection == nil {
+		return nil, nil, err
+	}
+	elements := make([]T, 0, len(collection.Elements))
+	for i, raw := range collection.Elements {
+		var element T
+		if decodeErr := json.Unmarshal(raw, &element); decodeErr != nil {
+			return elements, collection, fmt.Errorf("failed to decode element %d: %w", i, decodeErr)
+		}
+		elements = append(elements, element)
+	}
+	return elements, collection, err
+}
+
+func parseOffset(value interface{}) (int, error) {
+	if value == nil {
+		return 1, nil
+	}
+	offset, err := strconv.Atoi(fmt.Sprintf("%v", value))
+	if err != nil || offset < 1 {
+		return 0, fmt.Errorf("invalid offset: %v", value)
+	}
+	return offset, nil
+}
+
+func nextOffset(page *model.Collection[json.RawMessage], current int) (int, bool) {
+	if page.Count == 0 {
+		return 0, false
+	}
+	if page.Links.NextByOffset.IsZero() {
+		// Responses narrowed with "select" carry no links; fall back to the totals.
+		if page.PageSize > 0 && current*page.PageSize < page.Total {
+			return current + 1, true
+		}
+		return 0, false
+	}
+	next, err := url.Parse(page.Links.NextByOffset.Href)
+	if err != nil {
+		return current + 1, true
+	}
+	offset, err := strconv.Atoi(next.Query().Get("offset"))
+	if err != nil || offset <= current {
+		return current + 1, true
+	}
+	return offset, true
+}
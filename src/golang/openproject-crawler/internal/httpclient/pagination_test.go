@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"openproject-crawler/pkg/model"
+	"testing"
+)
+
+func TestNextOffset(t *testing.T) {
+	link := func(href string) model.Link {
+		return model.Link{Href: href}
+	}
+	tests := []struct {
+		name     string
+		page     model.Collection[json.RawMessage]
+		current  int
+		want     int
+		wantNext bool
+	}{
+		{"next link", model.Collection[json.RawMessage]{Total: 5, Count: 2, PageSize: 2, Links: model.CollectionLinks{NextByOffset: link("/api/v3/work_packages?offset=2&pageSize=2")}}, 1, 2, true},
+		{"next link skipping ahead", model.Collection[json.RawMessage]{Total: 9, Count: 2, PageSize: 2, Links: model.CollectionLinks{NextByOffset: link("/api/v3/work_packages?offset=4")}}, 2, 4, true},
+		{"next link without offset", model.Collection[json.RawMessage]{Total: 5, Count: 2, PageSize: 2, Links: model.CollectionLinks{NextByOffset: link("/api/v3/work_packages")}}, 1, 2, true},
+		{"next link going back", model.Collection[json.RawMessage]{Total: 5, Count: 2, PageSize: 2, Links: model.CollectionLinks{NextByOffset: link("/api/v3/work_packages?offset=1")}}, 2, 3, true},
+		{"empty page", model.Collection[json.RawMessage]{Total: 5, Count: 0, PageSize: 2, Links: model.CollectionLinks{NextByOffset: link("/api/v3/work_packages?offset=4")}}, 3, 0, false},
+		{"select, more pages", model.Collection[json.RawMessage]{Total: 5, Count: 2, PageSize: 2}, 2, 3, true},
+		{"select, last page", model.Collection[json.RawMessage]{Total: 5, Count: 1, PageSize: 2}, 3, 0, false},
+		{"select, exact last page", model.Collection[json.RawMessage]{Total: 4, Count: 2, PageSize: 2}, 2, 0, false},
+		{"select without pageSize", model.Collection[json.RawMessage]{Total: 5, Count: 2}, 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextOffset(&tt.page, tt.current)
+			if got != tt.want || ok != tt.wantNext {
+				t.Errorf("nextOffset = %d, %v; want %d, %v", got, ok, tt.want, tt.wantNext)
+			}
+		})
+	}
+}
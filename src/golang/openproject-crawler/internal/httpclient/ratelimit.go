@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRequestsPerSecond = 10
+	DefaultBurst             = 10
+)
+
+// RateLimiter is a token bucket per host. Clients sharing a RateLimiter
+// share the budget for each host they talk to.
+type RateLimiter struct {
+	mu    sync.Mutex
+	rate  float64
+	burst float64
+	hosts map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:  requestsPerSecond,
+		burst: float64(burst),
+		hosts: make(map[string]*bucket),
+	}
+}
+
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	return sleep(ctx, l.reserve(host))
+}
+
+func (l *RateLimiter) reserve(host string) time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.hosts[host]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.hosts[host] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+func (api *APIClient) GetRateLimiter() *RateLimiter {
+	return api.limiter
+}
+
+// SetRateLimiter replaces the client's own limiter, e.g. with one shared by
+// all clients of a crawler; nil disables rate limiting for this client.
+func (api *APIClient) SetRateLimiter(limiter *RateLimiter) {
+	api.limiter = limiter
+}
@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := NewRateLimiter(2, 2)
+	for i := 0; i < 2; i++ {
+		if delay := limiter.reserve("a.example.com"); delay != 0 {
+			t.Fatalf("request %d within the burst waits %v", i+1, delay)
+		}
+	}
+	if delay := limiter.reserve("a.example.com"); delay <= 0 || delay > 500*time.Millisecond {
+		t.Errorf("request beyond the burst waits %v, want up to 500ms", delay)
+	}
+	if delay := limiter.reserve("b.example.com"); delay != 0 {
+		t.Errorf("another host waits %v", delay)
+	}
+	if delay := NewRateLimiter(0, 1).reserve("a.example.com"); delay != 0 {
+		t.Errorf("unlimited limiter waits %v", delay)
+	}
+}
+
+func TestClientsOwnRateLimiter(t *testing.T) {
+	first, err := NewAPIClient("https://openproject.example.com/api/v3", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewAPIClient("https://openproject.example.com/api/v3", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.GetRateLimiter() == nil || first.GetRateLimiter() == second.GetRateLimiter() {
+		t.Error("clients share a default rate limiter")
+	}
+}
@@ -0,0 +1,211 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"openproject-crawler/internal/credential"
+	"strconv"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts       int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	RetryableStatuses []int
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		RetryableStatuses: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooEarly,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to fetch URL: %s", e.Status)
+}
+
+// AuthError means the auth provider could not authorize a request, e.g.
+// because the OAuth2 token endpoint refused the client.
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("failed to authorize request: %v", e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+func (api *APIClient) GetRetryPolicy() RetryPolicy {
+	return api.retry
+}
+
+func (api *APIClient) SetRetryPolicy(policy RetryPolicy) error {
+	if policy.MaxAttempts < 1 {
+		return fmt.Errorf("max attempts must be at least 1, got %d", policy.MaxAttempts)
+	}
+	if policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+		return errors.New("retry delays must not be negative")
+	}
+	api.retry = policy
+	return nil
+}
+
+// RetryCount reports how many retries this client has made since it was created.
+func (api *APIClient) RetryCount() int {
+	return int(api.retries.Load())
+}
+
+// doWithRetry retries transient failures with backoff. A 401 response makes
+// the auth provider refresh its credential once; that retry is not counted.
+func (api *APIClient) doWithRetry(req *http.Request) (string, int, error) {
+	retries := 0
+	refreshed := false
+	for attempt := 1; ; attempt++ {
+		body, resp, err := api.do(req)
+		if err == nil {
+			return body, retries, nil
+		}
+		if !refreshed && api.auth != nil && isUnauthorized(err) {
+			refreshed = true
+			refreshErr := api.auth.Refresh(req)
+			if refreshErr == nil {
+				attempt--
+				continue
+			}
+			if !errors.Is(refreshErr, credential.ErrNotRefreshable) {
+				return "", retries, fmt.Errorf("%w; credential refresh failed: %v", err, refreshErr)
+			}
+		}
+		if attempt >= api.retry.MaxAttempts || req.Context().Err() != nil || !api.retry.shouldRetry(req.Method, err) {
+			if retries > 0 {
+				err = fmt.Errorf("%w (after %d retries)", err, retries)
+			}
+			return "", retries, err
+		}
+
+		delay := api.retry.backoff(attempt)
+		if resp != nil {
+			if after, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = api.retry.capDelay(after)
+			}
+		}
+		if err := sleep(req.Context(), delay); err != nil {
+			return "", retries, err
+		}
+
+		retries++
+		api.retries.Add(1)
+	}
+}
+
+func isUnauthorized(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p RetryPolicy) shouldRetry(method string, err error) bool {
+	if !isIdempotent(method) {
+		return false
+	}
+	var authErr *AuthError
+	if errors.As(err, &authErr) || errors.Is(err, ErrNotRecorded) {
+		return false
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	for _, status := range p.RetryableStatuses {
+		if status == statusErr.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 {
+		delay = p.MaxDelay
+	}
+	delay = p.capDelay(delay)
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// capDelay limits a delay to MaxDelay, so a server asking to retry in an hour
+// cannot stall the crawl; zero MaxDelay means no limit.
+func (p RetryPolicy) capDelay(delay time.Duration) time.Duration {
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,47 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+const DefaultSize = 8
+
+// Pool bounds how many submitted functions run at once. A single Pool can be
+// shared between crawlers so they draw from the same concurrency budget.
+type Pool struct {
+	sem chan struct{}
+}
+
+func New(size int) *Pool {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Pool{sem: make(chan struct{}, size)}
+}
+
+func (p *Pool) Size() int {
+	return cap(p.sem)
+}
+
+// Go blocks until a slot is free, then runs fn in a new goroutine tracked by wg.
+// It returns the context error without running fn if ctx is done first.
+func (p *Pool) Go(ctx context.Context, wg *sync.WaitGroup, fn func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			wg.Done()
+		}()
+		fn()
+	}()
+	return nil
+}
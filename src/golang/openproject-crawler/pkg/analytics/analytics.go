@@ -0,0 +1,297 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	DefaultStartStatuses = []string{"In progress"}
+	DefaultEndStatuses   = []string{"Closed"}
+	DefaultPercentiles   = []float64{50, 85, 95}
+)
+
+// Config chooses which statuses start and end the measured intervals. Lead
+// time runs from creation to the end status; cycle time runs from the first
+// start status to the end status.
+type Config struct {
+	StartStatuses []string
+	EndStatuses   []string
+	Percentiles   []float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		StartStatuses: DefaultStartStatuses,
+		EndStatuses:   DefaultEndStatuses,
+		Percentiles:   DefaultPercentiles,
+	}
+}
+
+// ClosedStatusNames lists the statuses flagged isClosed, for use as EndStatuses.
+func ClosedStatusNames(statuses []model.Status) []string {
+	var names []string
+	for _, status := range statuses {
+		if status.IsClosed {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
+
+type Item struct {
+	ID        int
+	Project   string
+	Type      string
+	Priority  string
+	CreatedAt time.Time
+	Timeline  []model.StatusInterval
+}
+
+type ItemMetrics struct {
+	ID             int      `json:"id"`
+	Project        string   `json:"project"`
+	Type           string   `json:"type"`
+	Priority       string   `json:"priority"`
+	LeadTimeHours  *float64 `json:"leadTimeHours,omitempty"`
+	CycleTimeHours *float64 `json:"cycleTimeHours,omitempty"`
+}
+
+type Distribution struct {
+	Count       int                `json:"count"`
+	MinHours    float64            `json:"minHours"`
+	MaxHours    float64            `json:"maxHours"`
+	MeanHours   float64            `json:"meanHours"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+type Summary struct {
+	LeadTime  Distribution `json:"leadTime"`
+	CycleTime Distribution `json:"cycleTime"`
+}
+
+type Report struct {
+	Overall    Summary            `json:"overall"`
+	ByProject  map[string]Summary `json:"byProject"`
+	ByType     map[string]Summary `json:"byType"`
+	ByPriority map[string]Summary `json:"byPriority"`
+	Items      []ItemMetrics      `json:"items"`
+}
+
+type Analyzer struct {
+	start       map[string]bool
+	end         map[string]bool
+	percentiles []float64
+}
+
+func NewAnalyzer(config Config) (*Analyzer, error) {
+	if len(config.EndStatuses) == 0 {
+		return nil, fmt.Errorf("at least one end status is required")
+	}
+	if len(config.Percentiles) == 0 {
+		config.Percentiles = DefaultPercentiles
+	}
+	for _, p := range config.Percentiles {
+		if p <= 0 || p > 100 {
+			return nil, fmt.Errorf("percentile must be in (0, 100], got %v", p)
+		}
+	}
+	return &Analyzer{
+		start:       toSet(config.StartStatuses),
+		end:         toSet(config.EndStatuses),
+		percentiles: config.Percentiles,
+	}, nil
+}
+
+// ItemsFromTasks builds analytics input from merged tasks. When the matching
+// work package is given, its current project, type, priority and creation
+// time take precedence over the values recorded in the first activity.
+// Task dates are read in location, the time zone they were parsed in.
+func ItemsFromTasks(tasks []model.Task, workPackages []model.WorkPackage, location *time.Location) []Item {
+	byID := make(map[int]model.WorkPackage, len(workPackages))
+	for _, wp := range workPackages {
+		byID[wp.ID] = wp
+	}
+
+	items := make([]Item, 0, len(tasks))
+	for _, task := range tasks {
+		item := Item{
+			ID:       task.TaskInfo.ID,
+			Project:  task.TaskInfo.Project,
+			Type:     task.TaskInfo.Type,
+			Priority: task.TaskInfo.Priority,
+			Timeline: task.StatusTimeline,
+		}
+		if createdAt, ok := task.TaskInfo.CreatedTime(location); ok {
+			item.CreatedAt = createdAt
+		}
+		if wp, ok := byID[item.ID]; ok {
+			item.Project = firstNonEmpty(wp.ProjectName(), item.Project)
+			item.Type = firstNonEmpty(wp.TypeName(), item.Type)
+			item.Priority = firstNonEmpty(wp.PriorityName(), item.Priority)
+			if !wp.CreatedAt.IsZero() {
+				item.CreatedAt = wp.CreatedAt
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// Measure computes lead and cycle time for one item. Both are nil while the
+// item is not in an end status; cycle time is also nil if the item never
+// entered a start status.
+func (a *Analyzer) Measure(item Item) ItemMetrics {
+	metrics := ItemMetrics{
+		ID:       item.ID,
+		Project:  item.Project,
+		Type:     item.Type,
+		Priority: item.Priority,
+	}
+
+	closedAt, ok := a.closedAt(item.Timeline)
+	if !ok {
+		return metrics
+	}
+
+	created := item.CreatedAt
+	if created.IsZero() && len(item.Timeline) > 0 {
+		created = item.Timeline[0].EnteredAt
+	}
+	if !created.IsZero() && !closedAt.Before(created) {
+		lead := closedAt.Sub(created).Hours()
+		metrics.LeadTimeHours = &lead
+	}
+
+	for _, interval := range item.Timeline {
+		if !a.start[interval.Status] {
+			continue
+		}
+		if !closedAt.Before(interval.EnteredAt) {
+			cycle := closedAt.Sub(interval.EnteredAt).Hours()
+			metrics.CycleTimeHours = &cycle
+		}
+		break
+	}
+	return metrics
+}
+
+func (a *Analyzer) Analyze(items []Item) Report {
+	report := Report{
+		Items: make([]ItemMetrics, 0, len(items)),
+	}
+	for _, item := range items {
+		report.Items = append(report.Items, a.Measure(item))
+	}
+
+	report.Overall = a.summarize(report.Items)
+	report.ByProject = a.summarizeBy(report.Items, func(m ItemMetrics) string { return m.Project })
+	report.ByType = a.summarizeBy(report.Items, func(m ItemMetrics) string { return m.Type })
+	report.ByPriority = a.summarizeBy(report.Items, func(m ItemMetrics) string { return m.Priority })
+	return report
+}
+
+// closedAt finds when the item entered the run of end statuses it is still
+// in, so a reopened and re-closed item is measured to its final close.
+func (a *Analyzer) closedAt(timeline []model.StatusInterval) (time.Time, bool) {
+	index := -1
+	for i := len(timeline) - 1; i >= 0 && a.end[timeline[i].Status]; i-- {
+		index = i
+	}
+	if index < 0 {
+		return time.Time{}, false
+	}
+	return timeline[index].EnteredAt, true
+}
+
+func (a *Analyzer) summarizeBy(items []ItemMetrics, key func(ItemMetrics) string) map[string]Summary {
+	groups := make(map[string][]ItemMetrics)
+	for _, item := range items {
+		groups[key(item)] = append(groups[key(item)], item)
+	}
+	summaries := make(map[string]Summary, len(groups))
+	for name, group := range groups {
+		summaries[name] = a.summarize(group)
+	}
+	return summaries
+}
+
+func (a *Analyzer) summarize(items []ItemMetrics) Summary {
+	var lead, cycle []float64
+	for _, item := range items {
+		if item.LeadTimeHours != nil {
+			lead = append(lead, *item.LeadTimeHours)
+		}
+		if item.CycleTimeHours != nil {
+			cycle = append(cycle, *item.CycleTimeHours)
+		}
+	}
+	return Summary{
+		LeadTime:  a.distribution(lead),
+		CycleTime: a.distribution(cycle),
+	}
+}
+
+func (a *Analyzer) distribution(values []float64) Distribution {
+	dist := Distribution{
+		Count:       len(values),
+		Percentiles: make(map[string]float64, len(a.percentiles)),
+	}
+	if len(values) == 0 {
+		return dist
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	dist.MinHours = sorted[0]
+	dist.MaxHours = sorted[len(sorted)-1]
+	dist.MeanHours = sum / float64(len(sorted))
+	for _, p := range a.percentiles {
+		dist.Percentiles[percentileKey(p)] = Percentile(sorted, p)
+	}
+	return dist
+}
+
+// Percentile interpolates linearly between the closest ranks of sorted values.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if upper >= len(sorted) {
+		upper = len(sorted) - 1
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func percentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
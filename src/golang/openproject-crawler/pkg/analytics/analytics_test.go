@@ -0,0 +1,195 @@
+package analytics
+
+import (
+	"openproject-crawler/pkg/model"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var day0 = time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+// at is hours after day0.
+func at(hours float64) time.Time {
+	return day0.Add(time.Duration(hours * float64(time.Hour)))
+}
+
+// timeline builds consecutive intervals from status and start-hour pairs.
+func timeline(steps ...interface{}) []model.StatusInterval {
+	var intervals []model.StatusInterval
+	for i := 0; i+1 < len(steps); i += 2 {
+		interval := model.StatusInterval{Status: steps[i].(string), EnteredAt: at(steps[i+1].(float64))}
+		if n := len(intervals); n > 0 {
+			left := interval.EnteredAt
+			intervals[n-1].LeftAt = &left
+		}
+		intervals = append(intervals, interval)
+	}
+	return intervals
+}
+
+func hoursPtr(value float64) *float64 {
+	return &value
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{"empty", nil, 50, 0},
+		{"single", []float64{7}, 50, 7},
+		{"single p100", []float64{7}, 100, 7},
+		{"median of odd", []float64{1, 2, 10}, 50, 2},
+		{"median of even", []float64{1, 2, 3, 4}, 50, 2.5},
+		{"interpolated", []float64{10, 20, 30, 40, 50}, 85, 44},
+		{"max", []float64{10, 20, 30}, 100, 30},
+		{"smallest rank", []float64{10, 20, 30}, 1, 10.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("Percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAnalyzer(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		err    string
+	}{
+		{"default", DefaultConfig(), ""},
+		{"default percentiles", Config{EndStatuses: []string{"Done"}}, ""},
+		{"no end status", Config{StartStatuses: []string{"In progress"}}, "at least one end status"},
+		{"zero percentile", Config{EndStatuses: []string{"Done"}, Percentiles: []float64{0}}, "percentile must be in (0, 100], got 0"},
+		{"percentile above 100", Config{EndStatuses: []string{"Done"}, Percentiles: []float64{101}}, "got 101"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAnalyzer(tt.config)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	analyzer, err := NewAnalyzer(Config{StartStatuses: []string{"In progress"}, EndStatuses: []string{"Closed", "Rejected"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		item  Item
+		lead  *float64
+		cycle *float64
+	}{
+		{"empty timeline", Item{}, nil, nil},
+		{"still open", Item{CreatedAt: day0, Timeline: timeline("New", 0.0, "In progress", 2.0)}, nil, nil},
+		{"closed", Item{CreatedAt: day0, Timeline: timeline("New", 0.0, "In progress", 2.0, "Closed", 10.0)}, hoursPtr(10), hoursPtr(8)},
+		{"never started", Item{CreatedAt: day0, Timeline: timeline("New", 0.0, "Rejected", 4.0)}, hoursPtr(4), nil},
+		{"created from timeline", Item{Timeline: timeline("New", 1.0, "In progress", 2.0, "Closed", 5.0)}, hoursPtr(4), hoursPtr(3)},
+		{"first start counts", Item{CreatedAt: day0, Timeline: timeline("In progress", 0.0, "New", 1.0, "In progress", 3.0, "Closed", 6.0)}, hoursPtr(6), hoursPtr(6)},
+		{"reopened", Item{CreatedAt: day0, Timeline: timeline("In progress", 0.0, "Closed", 2.0, "In progress", 5.0, "Closed", 9.0)}, hoursPtr(9), hoursPtr(9)},
+		{"closed then rejected", Item{CreatedAt: day0, Timeline: timeline("In progress", 0.0, "Closed", 2.0, "Rejected", 5.0)}, hoursPtr(2), hoursPtr(2)},
+		{"reopened and open", Item{CreatedAt: day0, Timeline: timeline("In progress", 0.0, "Closed", 2.0, "In progress", 5.0)}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := analyzer.Measure(tt.item)
+			if !reflect.DeepEqual(metrics.LeadTimeHours, tt.lead) || !reflect.DeepEqual(metrics.CycleTimeHours, tt.cycle) {
+				t.Errorf("lead, cycle = %v, %v; want %v, %v", deref(metrics.LeadTimeHours), deref(metrics.CycleTimeHours), deref(tt.lead), deref(tt.cycle))
+			}
+		})
+	}
+}
+
+func deref(value *float64) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+func TestAnalyze(t *testing.T) {
+	analyzer, err := NewAnalyzer(Config{StartStatuses: []string{"In progress"}, EndStatuses: []string{"Closed"}, Percentiles: []float64{50, 85}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty := analyzer.Analyze(nil)
+	if empty.Overall.LeadTime.Count != 0 || len(empty.Items) != 0 || len(empty.ByType) != 0 {
+		t.Errorf("empty report = %+v", empty)
+	}
+	if want := map[string]float64{}; !reflect.DeepEqual(empty.Overall.LeadTime.Percentiles, want) {
+		t.Errorf("empty percentiles = %v", empty.Overall.LeadTime.Percentiles)
+	}
+
+	single := analyzer.Analyze([]Item{
+		{ID: 1, Type: "Bug", CreatedAt: day0, Timeline: timeline("New", 0.0, "In progress", 4.0, "Closed", 10.0)},
+	})
+	want := Distribution{Count: 1, MinHours: 10, MaxHours: 10, MeanHours: 10, Percentiles: map[string]float64{"p50": 10, "p85": 10}}
+	if !reflect.DeepEqual(single.Overall.LeadTime, want) {
+		t.Errorf("single lead time = %+v, want %+v", single.Overall.LeadTime, want)
+	}
+	if got := single.ByType["Bug"].CycleTime.MeanHours; got != 6 {
+		t.Errorf("single cycle time = %v, want 6", got)
+	}
+
+	report := analyzer.Analyze([]Item{
+		{ID: 1, Type: "Bug", CreatedAt: day0, Timeline: timeline("New", 0.0, "In progress", 4.0, "Closed", 10.0)},
+		{ID: 2, Type: "Bug", CreatedAt: day0, Timeline: timeline("In progress", 0.0, "Closed", 20.0)},
+		{ID: 3, Type: "Feature", CreatedAt: day0, Timeline: timeline("New", 0.0, "Closed", 30.0)},
+		{ID: 4, Type: "Feature", CreatedAt: day0, Timeline: timeline("New", 0.0)},
+	})
+	lead := report.Overall.LeadTime
+	if lead.Count != 3 || lead.MinHours != 10 || lead.MaxHours != 30 || lead.MeanHours != 20 || lead.Percentiles["p50"] != 20 || lead.Percentiles["p85"] != 27 {
+		t.Errorf("lead time = %+v", lead)
+	}
+	if cycle := report.Overall.CycleTime; cycle.Count != 2 || cycle.Percentiles["p50"] != 13 {
+		t.Errorf("cycle time = %+v", cycle)
+	}
+	if features := report.ByType["Feature"]; features.LeadTime.Count != 1 || features.CycleTime.Count != 0 {
+		t.Errorf("features = %+v", features)
+	}
+	if len(report.Items) != 4 || report.Items[3].LeadTimeHours != nil {
+		t.Errorf("items = %+v", report.Items)
+	}
+}
+
+func TestItemsFromTasks(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tasks := []model.Task{
+		{TaskInfo: model.TaskInfo{ID: 1, Project: "Old", Type: "Bug", CreatedDate: "2024-03-04 18:00:00"}},
+		{TaskInfo: model.TaskInfo{ID: 2, Type: "Task"}},
+	}
+	workPackages := []model.WorkPackage{{ID: 2, CreatedAt: day0}}
+	workPackages[0].Links.Project = model.Link{Title: "Demo project"}
+
+	items := ItemsFromTasks(tasks, workPackages, tokyo)
+	if len(items) != 2 {
+		t.Fatalf("items = %d, want 2", len(items))
+	}
+	if !items[0].CreatedAt.Equal(day0) || items[0].Project != "Old" {
+		t.Errorf("item 1 = %+v, want created at %v", items[0], day0)
+	}
+	if !items[1].CreatedAt.Equal(day0) || items[1].Project != "Demo project" || items[1].Type != "Task" {
+		t.Errorf("item 2 = %+v", items[1])
+	}
+	if empty := ItemsFromTasks(nil, nil, nil); len(empty) != 0 {
+		t.Errorf("items of no tasks = %v", empty)
+	}
+}
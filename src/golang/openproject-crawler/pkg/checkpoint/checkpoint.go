@@ -0,0 +1,191 @@
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"openproject-crawler/pkg/model"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Scope is what a crawl of a project covers besides the project itself: the
+// work package filters, as sent in the filters parameter, and whether
+// subprojects are included. Crawls of different scopes keep separate state.
+type Scope struct {
+	Filters     string `json:"filters,omitempty"`
+	Subprojects bool   `json:"subprojects,omitempty"`
+}
+
+// key tells the state files of scopes apart. The whole project, without
+// filters, keeps the plain project file name.
+func (s Scope) key() string {
+	if s == (Scope{}) {
+		return ""
+	}
+	content, _ := json.Marshal(s)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:4])
+}
+
+// State is what an incremental crawl of one project keeps between runs: the
+// latest work package updatedAt seen and the raw activities of every work
+// package, keyed by work package ID.
+type State struct {
+	Project    string                   `json:"project"`
+	Scope      Scope                    `json:"scope"`
+	UpdatedAt  time.Time                `json:"updatedAt"`
+	CrawledAt  time.Time                `json:"crawledAt"`
+	Activities map[int][]model.Activity `json:"activities"`
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Path returns the state file of a crawl of project in scope inside dir.
+func Path(dir, project string, scope Scope) string {
+	name := unsafeChars.ReplaceAllString(project, "_")
+	if key := scope.key(); key != "" {
+		name += "." + key
+	}
+	return filepath.Join(dir, name+".json")
+}
+
+// Load reads the state of a crawl of project in scope. A missing file yields
+// an empty state, which makes the next crawl a full one.
+func Load(dir, project string, scope Scope) (*State, error) {
+	path := Path(dir, project, scope)
+	state := &State{Project: project, Scope: scope, Activities: make(map[int][]model.Activity)}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: %w", path, err)
+	}
+	if state.Project != project {
+		return nil, fmt.Errorf("checkpoint %s belongs to project %q", path, state.Project)
+	}
+	if state.Scope != scope {
+		return nil, fmt.Errorf("checkpoint %s was made with filters %q and subprojects %t", path, state.Scope.Filters, state.Scope.Subprojects)
+	}
+	if state.Activities == nil {
+		state.Activities = make(map[int][]model.Activity)
+	}
+	return state, nil
+}
+
+// Save writes the state atomically, so an interrupted run keeps the
+// previous checkpoint intact.
+func (s *State) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	content, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".checkpoint-*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), Path(dir, s.Project, s.Scope)); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// IsZero reports whether no crawl has completed yet.
+func (s *State) IsZero() bool {
+	return s.UpdatedAt.IsZero()
+}
+
+// Merge adds newly fetched activities, replacing earlier copies with the same
+// activity ID. The checkpoint advances to the latest updatedAt among
+// workPackages only when advance is set, i.e. when every fetch succeeded.
+func (s *State) Merge(workPackages []model.WorkPackage, tasksData [][]model.Activity, advance bool) {
+	for _, activities := range tasksData {
+		if len(activities) == 0 {
+			continue
+		}
+		taskID, ok := activities[0].Links.WorkPackage.ID()
+		if !ok {
+			continue
+		}
+		s.Activities[taskID] = mergeActivities(s.Activities[taskID], activities)
+	}
+	if !advance {
+		return
+	}
+	for _, wp := range workPackages {
+		if wp.UpdatedAt.After(s.UpdatedAt) {
+			s.UpdatedAt = wp.UpdatedAt
+		}
+	}
+	s.CrawledAt = time.Now()
+}
+
+// Prune drops the activities of work packages not in ids, e.g. ones that
+// were deleted or no longer match the filters.
+func (s *State) Prune(ids []int) {
+	keep := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+	for id := range s.Activities {
+		if !keep[id] {
+			delete(s.Activities, id)
+		}
+	}
+}
+
+// TasksData returns the activities of every known work package, ordered by
+// work package ID, in the shape CrawlActivities merges.
+func (s *State) TasksData() [][]model.Activity {
+	ids := make([]int, 0, len(s.Activities))
+	for id := range s.Activities {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	tasksData := make([][]model.Activity, len(ids))
+	for i, id := range ids {
+		tasksData[i] = s.Activities[id]
+	}
+	return tasksData
+}
+
+func mergeActivities(previous, fetched []model.Activity) []model.Activity {
+	byID := make(map[int]model.Activity, len(previous)+len(fetched))
+	for _, activity := range previous {
+		byID[activity.ID] = activity
+	}
+	for _, activity := range fetched {
+		byID[activity.ID] = activity
+	}
+	merged := make([]model.Activity, 0, len(byID))
+	for _, activity := range byID {
+		merged = append(merged, activity)
+	}
+	// The first activity creates the work package; keep them in history order.
+	sort.Slice(merged, func(i, j int) bool {
+		if !merged[i].CreatedAt.Equal(merged[j].CreatedAt) {
+			return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+		}
+		return merged[i].ID < merged[j].ID
+	})
+	return merged
+}
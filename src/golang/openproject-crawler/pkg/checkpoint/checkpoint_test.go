@@ -0,0 +1,85 @@
+package checkpoint
+
+import (
+	"openproject-crawler/pkg/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPathByScope(t *testing.T) {
+	open := Scope{Filters: `[{"status":{"operator":"o","values":[]}}]`}
+	tests := []struct {
+		name  string
+		scope Scope
+		want  string
+	}{
+		{"whole project", Scope{}, "my_project.json"},
+		{"subprojects", Scope{Subprojects: true}, "my_project." + Scope{Subprojects: true}.key() + ".json"},
+		{"filters", open, "my_project." + open.key() + ".json"},
+	}
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := Path("state", "my project", tt.scope)
+			if want := filepath.Join("state", tt.want); path != want {
+				t.Errorf("path = %s, want %s", path, want)
+			}
+			if seen[path] {
+				t.Errorf("path %s is shared with another scope", path)
+			}
+			seen[path] = true
+		})
+	}
+}
+
+func TestLoadScope(t *testing.T) {
+	dir := t.TempDir()
+	scope := Scope{Filters: `[{"type":{"operator":"=","values":["1"]}}]`, Subprojects: true}
+	state, err := Load(dir, "demo", scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.UpdatedAt = time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	if err := state.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(dir, "demo", scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.UpdatedAt.Equal(state.UpdatedAt) {
+		t.Errorf("updatedAt = %v, want %v", loaded.UpdatedAt, state.UpdatedAt)
+	}
+	// Another scope starts with a full crawl.
+	other, err := Load(dir, "demo", Scope{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !other.IsZero() {
+		t.Errorf("unfiltered checkpoint starts at %v", other.UpdatedAt)
+	}
+
+	// A file copied over from another scope is rejected.
+	content, err := os.ReadFile(Path(dir, "demo", scope))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(Path(dir, "demo", Scope{}), content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir, "demo", Scope{}); err == nil || !strings.Contains(err.Error(), "was made with filters") {
+		t.Errorf("err = %v, want a scope mismatch", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	state := &State{Activities: map[int][]model.Activity{101: {{ID: 1}}, 102: {{ID: 2}}, 103: {{ID: 3}}}}
+	state.Prune([]int{101, 103, 104})
+	if len(state.Activities) != 2 || state.Activities[102] != nil {
+		t.Errorf("activities after pruning = %v", state.Activities)
+	}
+}
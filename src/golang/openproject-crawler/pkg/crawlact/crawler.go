@@ -1,12 +1,16 @@
 package crawlact
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"openproject-crawler/internal/core"
 	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/internal/workerpool"
+	"openproject-crawler/pkg/model"
 	"sync"
+	"time"
 )
 
 type CrawlActivities struct {
@@ -14,7 +18,10 @@ type CrawlActivities struct {
 	*core.DataParser
 	tasksID   []int
 	params    map[string]interface{}
-	tasksData [][]map[string]interface{}
+	tasksData [][]model.Activity
+	retries   map[int]int
+	pool      *workerpool.Pool
+	state     *State
 	mu        sync.Mutex
 	once      sync.Once
 }
@@ -30,7 +37,9 @@ func NewCrawlActivities(apiURL, authToken string) (*CrawlActivities, error) {
 		DataParser: parser,
 		tasksID:    make([]int, 0),
 		params:     make(map[string]interface{}),
-		tasksData:  [][]map[string]interface{}{},
+		tasksData:  [][]model.Activity{},
+		retries:    make(map[int]int),
+		pool:       workerpool.New(workerpool.DefaultSize),
 	}, nil
 }
 
@@ -42,58 +51,118 @@ func (c *CrawlActivities) SettasksID(value []int) {
 	c.tasksID = value
 }
 
-func (c *CrawlActivities) fetchData(taskID interface{}, ch chan<- []map[string]interface{}, errCh chan<- error, wg *sync.WaitGroup) {
-	defer wg.Done()
+func (c *CrawlActivities) GetTasksData() [][]model.Activity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tasksData
+}
+
+// GetStatusTimelines rebuilds the status intervals of every crawled task,
+// keyed by work package ID, with open intervals measured up to asOf.
+func (c *CrawlActivities) GetStatusTimelines(asOf time.Time) map[int][]model.StatusInterval {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timelines := make(map[int][]model.StatusInterval, len(c.tasksData))
+	for _, activities := range c.tasksData {
+		if len(activities) == 0 {
+			continue
+		}
+		if taskID, ok := activities[0].Links.WorkPackage.ID(); ok {
+			timelines[taskID] = core.BuildStatusTimeline(activities, asOf)
+		}
+	}
+	return timelines
+}
+
+func (c *CrawlActivities) GetPool() *workerpool.Pool {
+	return c.pool
+}
+
+func (c *CrawlActivities) SetPool(pool *workerpool.Pool) {
+	c.pool = pool
+	c.DataParser.SetPool(pool)
+}
+
+func (c *CrawlActivities) GetState() *State {
+	return c.state
+}
+
+// SetState makes FetchTasksData record each finished task in state and skip
+// the tasks state already holds as completed.
+func (c *CrawlActivities) SetState(state *State) {
+	c.state = state
+}
+
+func (c *CrawlActivities) fetchData(ctx context.Context, taskID int, ch chan<- []model.Activity, errCh chan<- error) {
 	customURI := fmt.Sprintf("/work_packages/%v/activities", taskID)
-	response, err := c.GetRequest(customURI, c.params)
+	activities, collection, err := httpclient.GetElements[model.Activity](ctx, c.APIClient, customURI, c.params)
+	if collection != nil && collection.Retries > 0 {
+		c.mu.Lock()
+		c.retries[taskID] += collection.Retries
+		c.mu.Unlock()
+	}
 	if err != nil {
-		errCh <- fmt.Errorf("failed to fetch data for task %v: %v", taskID, err)
+		// Cancelled tasks are not failures; a resumed crawl fetches them anyway.
+		if c.state != nil && ctx.Err() == nil {
+			if stateErr := c.state.recordFailed(taskID, err); stateErr != nil {
+				log.Printf("Error: %v\n", stateErr)
+			}
+		}
+		errCh <- fmt.Errorf("failed to fetch data for task %v: %w", taskID, err)
 		ch <- nil
 		return
 	}
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		errCh <- fmt.Errorf("failed to parse JSON response for task %v: %v", taskID, err)
-		ch <- nil
+	if collection.Truncated {
+		// Keep the partial activities for this run, but fetch the task again
+		// when the crawl is resumed.
+		err := fmt.Errorf("activities for task %v truncated after %d pages", taskID, collection.Pages)
+		if c.state != nil {
+			if stateErr := c.state.recordFailed(taskID, err); stateErr != nil {
+				log.Printf("Error: %v\n", stateErr)
+			}
+		}
+		errCh <- err
+		ch <- activities
 		return
 	}
-
-	if embedded, ok := jsonResponse["_embedded"].(map[string]interface{}); ok {
-		if elements, ok := embedded["elements"].([]interface{}); ok {
-			elementsMap := make([]map[string]interface{}, len(elements))
-			for i, elem := range elements {
-				elementsMap[i] = elem.(map[string]interface{})
-			}
-			ch <- elementsMap
-			return
-		} else {
-			errCh <- fmt.Errorf("invalid 'elements' array for task %v", taskID)
+	if c.state != nil {
+		if stateErr := c.state.recordCompleted(taskID, activities); stateErr != nil {
+			log.Printf("Error: %v\n", stateErr)
 		}
-	} else {
-		errCh <- fmt.Errorf("missing or invalid '_embedded' key for task %v", taskID)
 	}
-	ch <- nil
+	ch <- activities
 }
 
-func (c *CrawlActivities) GetTasksActivities() ([]map[string]interface{}, error) {
+// FetchTasksData fetches the activities of every task without merging them
+// and returns this batch. Failed tasks are logged and skipped; the error then
+// reports how many failed, or the context error if ctx was cancelled.
+func (c *CrawlActivities) FetchTasksData(ctx context.Context) ([][]model.Activity, error) {
 	var wg sync.WaitGroup
-	ch := make(chan []map[string]interface{}, len(c.tasksID))
+	ch := make(chan []model.Activity, len(c.tasksID))
 	errCh := make(chan error, len(c.tasksID))
 
 	for _, taskID := range c.tasksID {
-		wg.Add(1)
-		go c.fetchData(taskID, ch, errCh, &wg)
+		if c.state != nil {
+			if activities, ok := c.state.Completed(taskID); ok {
+				ch <- activities
+				continue
+			}
+		}
+		if err := c.pool.Go(ctx, &wg, func() {
+			c.fetchData(ctx, taskID, ch, errCh)
+		}); err != nil {
+			break
+		}
 	}
 
 	wg.Wait()
 	close(ch)
 	close(errCh)
 
-	batchResults := make([][]map[string]interface{}, 0, len(c.tasksID))
-	for elementsMap := range ch {
-		if elementsMap != nil {
-			batchResults = append(batchResults, elementsMap)
+	batchResults := make([][]model.Activity, 0, len(c.tasksID))
+	for activities := range ch {
+		if activities != nil {
+			batchResults = append(batchResults, activities)
 		}
 	}
 
@@ -101,22 +170,71 @@ func (c *CrawlActivities) GetTasksActivities() ([]map[string]interface{}, error)
 	c.tasksData = append(c.tasksData, batchResults...)
 	c.mu.Unlock()
 
+	failed := 0
 	for err := range errCh {
-		if err != nil {
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
 			log.Printf("Error: %v\n", err)
+			failed++
 		}
 	}
 
-	var mergedData []map[string]interface{}
+	if err := ctx.Err(); err != nil {
+		return batchResults, err
+	}
+	if failed > 0 {
+		return batchResults, fmt.Errorf("%d of %d tasks failed", failed, len(c.tasksID))
+	}
+	return batchResults, nil
+}
+
+// SetTasksData replaces the fetched activities, e.g. with a dataset restored
+// from an earlier crawl.
+func (c *CrawlActivities) SetTasksData(value [][]model.Activity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tasksData = value
+}
+
+// MergeTasksData merges the fetched activities into tasks. Merging is not
+// interrupted by ctx, so a cancelled crawl still yields what it fetched;
+// only the author lookups of a user resolver are skipped.
+func (c *CrawlActivities) MergeTasksData(ctx context.Context) ([]model.Task, error) {
+	c.mu.Lock()
+	c.SetDataInput(c.tasksData)
+	c.mu.Unlock()
+
+	c.ResolveAuthors(ctx)
+
+	mergedData, err := c.MergeData(context.WithoutCancel(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("error merging data: %v", err)
+	}
+
+	c.mu.Lock()
+	for i := range mergedData {
+		mergedData[i].TaskInfo.Retries = c.retries[mergedData[i].TaskInfo.ID]
+	}
+	c.mu.Unlock()
+	return mergedData, nil
+}
+
+// GetTasksActivities fetches and merges the activities of every task. If ctx
+// is cancelled or some tasks fail, the tasks fetched so far are merged and
+// returned together with the error.
+func (c *CrawlActivities) GetTasksActivities(ctx context.Context) ([]model.Task, error) {
+	_, fetchErr := c.FetchTasksData(ctx)
+
+	var mergedData []model.Task
 	var mergeErr error
 	c.once.Do(func() {
-		c.SetDataInput(c.tasksData)
-		mergedData, mergeErr = c.MergeData()
+		mergedData, mergeErr = c.MergeTasksData(ctx)
 	})
-
 	if mergeErr != nil {
-		return nil, fmt.Errorf("error merging data: %v", mergeErr)
+		return nil, mergeErr
 	}
 
-	return mergedData, nil
+	if err := ctx.Err(); err != nil {
+		return mergedData, fmt.Errorf("activity crawl interrupted: %w", err)
+	}
+	return mergedData, fetchErr
 }
@@ -0,0 +1,364 @@
+package crawlact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/crawlusers"
+	"openproject-crawler/pkg/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestCrawler(t *testing.T, srv *fakeapi.Server, tasksID []int) *CrawlActivities {
+	t.Helper()
+	c, err := NewCrawlActivities(srv.APIURL(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRateLimiter(nil)
+	if err := c.SetRetryPolicy(httpclient.RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	c.SettasksID(tasksID)
+	return c
+}
+
+func TestGetTasksActivities(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	c := newTestCrawler(t, srv, []int{101, 102, 201})
+
+	tasks, err := c.GetTasksActivities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("tasks = %d, want 3", len(tasks))
+	}
+	byID := make(map[int]int)
+	for i, task := range tasks {
+		byID[task.TaskInfo.ID] = i
+	}
+
+	bug := tasks[byID[101]]
+	if bug.TaskName != "Login fails" || bug.TaskInfo.Type != "Bug" || bug.TaskInfo.Priority != "High" || bug.TaskInfo.Project != "Demo project" {
+		t.Errorf("task 101 info = %q %+v", bug.TaskName, bug.TaskInfo)
+	}
+	if bug.TaskInfo.ClosedStatus != "Closed" || bug.TaskInfo.ClosedDate == "" {
+		t.Errorf("task 101 not closed: %+v", bug.TaskInfo)
+	}
+	// The comment is not an activity with changes.
+	if got := len(bug.TaskActivities); got != 2 {
+		t.Errorf("task 101 activities = %d, want 2", got)
+	}
+	if docs := tasks[byID[102]]; docs.TaskInfo.ClosedDate != "" {
+		t.Errorf("task 102 closed at %s", docs.TaskInfo.ClosedDate)
+	}
+
+	timelines := c.GetStatusTimelines(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+	var statuses []string
+	for _, interval := range timelines[101] {
+		statuses = append(statuses, interval.Status)
+	}
+	if want := []string{"New", "In progress", "Closed"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("timeline of 101 = %v, want %v", statuses, want)
+	}
+}
+
+func TestGetTasksActivitiesFailedTask(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	srv.Inject(fakeapi.Fault{Path: "/work_packages/102/activities", Status: http.StatusInternalServerError})
+	c := newTestCrawler(t, srv, []int{101, 102, 201})
+
+	tasks, err := c.GetTasksActivities(context.Background())
+	if err == nil || err.Error() != "1 of 3 tasks failed" {
+		t.Errorf("err = %v, want 1 of 3 tasks failed", err)
+	}
+	if len(tasks) != 2 {
+		t.Errorf("tasks = %d, want the 2 fetched ones", len(tasks))
+	}
+}
+
+func TestClosedWithComment(t *testing.T) {
+	fixtures := fakeapi.DefaultFixtures()
+	closing := json.RawMessage(`{"_type": "Activity::Comment", "id": 1013, "version": 3,
+		"comment": {"format": "markdown", "raw": "Published.", "html": "<p>Published.</p>"},
+		"details": [{"format": "custom", "raw": "Status changed from In progress to Closed"}],
+		"createdAt": "2024-03-07T11:00:00.000Z", "updatedAt": "2024-03-07T11:00:00.000Z",
+		"_links": {"self": {"href": "/api/v3/activities/1013"}, "workPackage": {"href": "/api/v3/work_packages/102", "title": "Write docs"}}}`)
+	fixtures.Activities[102] = append(fixtures.Activities[102], closing)
+	srv := fakeapi.New(fixtures)
+	defer srv.Close()
+	c := newTestCrawler(t, srv, []int{102})
+	c.GetCalendar().SetLocation(time.UTC)
+
+	tasks, err := c.GetTasksActivities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := tasks[0].TaskInfo
+	if info.ClosedDate != "2024-03-07 11:00:00" || info.ClosedStatus != "Closed" || info.Durations == nil || info.Duration == "" {
+		t.Errorf("task 102 not closed by the comment: %+v", info)
+	}
+	timeline := tasks[0].StatusTimeline
+	if last := timeline[len(timeline)-1]; last.Status != "Closed" || last.EnteredAt.Format(time.DateTime) != info.ClosedDate {
+		t.Errorf("timeline ends in %s at %v, closed at %s", last.Status, last.EnteredAt, info.ClosedDate)
+	}
+}
+
+func TestTaskDatesInCalendarLocation(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	c := newTestCrawler(t, srv, []int{101})
+	tokyo := time.FixedZone("JST", 9*60*60)
+	c.GetCalendar().SetLocation(tokyo)
+
+	tasks, err := c.GetTasksActivities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := tasks[0].TaskInfo
+	if info.CreatedDate != "2024-03-04 18:00:00" {
+		t.Errorf("created date = %s, want 2024-03-04 18:00:00", info.CreatedDate)
+	}
+	created, ok := info.CreatedTime(tokyo)
+	if want := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC); !ok || !created.Equal(want) {
+		t.Errorf("created time = %v, want %v", created, want)
+	}
+	if entered := tasks[0].StatusTimeline[0].EnteredAt; entered.Location() != tokyo {
+		t.Errorf("timeline starts at %v, not in %v", entered, tokyo)
+	}
+}
+
+func TestResumeState(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	srv.Inject(fakeapi.Fault{Path: "/work_packages/102/", Status: http.StatusInternalServerError})
+	path := filepath.Join(t.TempDir(), "demo.progress.jsonl")
+
+	state, err := OpenState(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestCrawler(t, srv, []int{101, 102, 201})
+	c.SetState(state)
+	batch, err := c.FetchTasksData(context.Background())
+	if err == nil || err.Error() != "1 of 3 tasks failed" {
+		t.Fatalf("err = %v", err)
+	}
+	if len(batch) != 2 {
+		t.Errorf("batch = %d tasks, want 2", len(batch))
+	}
+	if got := state.FailedIDs(); !reflect.DeepEqual(got, []int{102}) {
+		t.Errorf("FailedIDs = %v", got)
+	}
+	state.Close()
+
+	srv.ClearFaults()
+	resumed, err := OpenState(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	c = newTestCrawler(t, srv, []int{101, 102, 201})
+	c.SetState(resumed)
+	batch, err = c.FetchTasksData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 3 {
+		t.Errorf("batch = %d tasks, want 3", len(batch))
+	}
+	if got := srv.RequestCount("/work_packages/101/"); got != 1 {
+		t.Errorf("task 101 fetched %d times, want 1", got)
+	}
+	if got := srv.RequestCount("/work_packages/102/"); got != 2 {
+		t.Errorf("task 102 fetched %d times, want 2", got)
+	}
+	if failed := resumed.FailedIDs(); len(failed) != 0 {
+		t.Errorf("FailedIDs after resume = %v", failed)
+	}
+}
+
+// pagedActivities serves the fixture activities one page per request with
+// nextByOffset links, which is how a long history would be truncated.
+func pagedActivities(t *testing.T) *httptest.Server {
+	t.Helper()
+	fixtures := fakeapi.DefaultFixtures()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+fakeapi.APIPath+"/work_packages/{id}/activities", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		activities := fixtures.Activities[id]
+		pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		start := min((offset-1)*pageSize, len(activities))
+		end := min(start+pageSize, len(activities))
+		page := map[string]interface{}{
+			"_type":     "Collection",
+			"total":     len(activities),
+			"count":     end - start,
+			"pageSize":  pageSize,
+			"offset":    offset,
+			"_embedded": map[string]interface{}{"elements": activities[start:end]},
+			"_links":    map[string]interface{}{},
+		}
+		if end < len(activities) {
+			page["_links"] = map[string]interface{}{"nextByOffset": map[string]string{
+				"href": fmt.Sprintf("%s?offset=%d&pageSize=%d", r.URL.Path, offset+1, pageSize),
+			}}
+		}
+		w.Header().Set("Content-Type", "application/hal+json")
+		json.NewEncoder(w).Encode(page)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestResumeTruncated(t *testing.T) {
+	srv := pagedActivities(t)
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "demo.progress.jsonl")
+	newCrawler := func(state *State, maxPages int) *CrawlActivities {
+		c, err := NewCrawlActivities(srv.URL+fakeapi.APIPath, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetRateLimiter(nil)
+		if err := c.SetPageSize(1); err != nil {
+			t.Fatal(err)
+		}
+		c.SetMaxPages(maxPages)
+		c.SettasksID([]int{101})
+		c.SetState(state)
+		return c
+	}
+
+	state, err := OpenState(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := newCrawler(state, 1).FetchTasksData(context.Background())
+	if err == nil || err.Error() != "1 of 1 tasks failed" {
+		t.Fatalf("err = %v", err)
+	}
+	// The partial history is still returned for this run.
+	if len(batch) != 1 || len(batch[0]) != 1 {
+		t.Errorf("batch = %v, want the first activity of 101", batch)
+	}
+	if got := state.FailedIDs(); !reflect.DeepEqual(got, []int{101}) {
+		t.Errorf("FailedIDs = %v, want [101]", got)
+	}
+	if _, ok := state.Completed(101); ok {
+		t.Error("truncated task 101 recorded as completed")
+	}
+	state.Close()
+
+	resumed, err := OpenState(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	if _, ok := resumed.Completed(101); ok {
+		t.Fatal("resumed state skips truncated task 101")
+	}
+	batch, err = newCrawler(resumed, 0).FetchTasksData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(fakeapi.DefaultFixtures().Activities[101]); len(batch) != 1 || len(batch[0]) != want {
+		t.Errorf("resumed batch = %v, want all %d activities of 101", batch, want)
+	}
+	if failed := resumed.FailedIDs(); len(failed) != 0 {
+		t.Errorf("FailedIDs after resume = %v", failed)
+	}
+}
+
+func TestOpenState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.progress.jsonl")
+	content := `{"taskId":101,"activities":[{"id":1001}],"at":"2024-03-04T09:00:00Z"}
+{"taskId":102,"error":"boom","at":"2024-03-04T09:00:01Z"}
+{"taskId":102,"activities":[{"id":1021}],"at":"2024-03-04T09:00:02Z"}
+{"taskId":201,"error":"boom","at":"2024-03-04T09:00:03Z"}
+{"taskId":201,"activ`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		resume    bool
+		completed []int
+		failed    []int
+	}{
+		// The half-written last line is skipped, so 201 stays failed; 102
+		// succeeded after failing.
+		{"resume", true, []int{101, 102}, []int{201}},
+		{"fresh start", false, nil, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := OpenState(path, tt.resume)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer state.Close()
+			var completed []int
+			for _, id := range []int{101, 102, 201} {
+				if _, ok := state.Completed(id); ok {
+					completed = append(completed, id)
+				}
+			}
+			if !reflect.DeepEqual(completed, tt.completed) {
+				t.Errorf("completed = %v, want %v", completed, tt.completed)
+			}
+			if got := state.FailedIDs(); !reflect.DeepEqual(got, tt.failed) {
+				t.Errorf("FailedIDs = %v, want %v", got, tt.failed)
+			}
+		})
+	}
+}
+
+func TestActivityAuthors(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	// Bob cannot be looked up, so his activities keep the link title.
+	srv.Inject(fakeapi.Fault{Path: "/users/6", Status: http.StatusNotFound})
+	users, err := crawlusers.NewCrawlUsers(srv.APIURL(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users.SetRateLimiter(nil)
+
+	c := newTestCrawler(t, srv, []int{101, 201})
+	c.SetUserResolver(crawlusers.NewResolver(users))
+	tasks, err := c.GetTasksActivities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	authors := make(map[int]model.Author)
+	for _, task := range tasks {
+		for _, activity := range task.TaskActivities {
+			authors[activity.ID] = *activity.Author
+		}
+	}
+	bob := model.Author{ID: 6, Name: "Bob Builder"}
+	want := map[int]model.Author{1002: bob, 1003: bob, 2012: {ID: 5, Name: "Ada Admin", Login: "ada"}}
+	if !reflect.DeepEqual(authors, want) {
+		t.Errorf("authors = %+v, want %+v", authors, want)
+	}
+	if got := srv.RequestCount("/users/"); got != 2 {
+		t.Errorf("user requests = %d, want one per author", got)
+	}
+	if got := c.GetAuthors(); len(got) != 1 || got[0].Login != "ada" {
+		t.Errorf("GetAuthors = %+v", got)
+	}
+}
@@ -0,0 +1,169 @@
+package crawlact
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"openproject-crawler/pkg/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// taskRecord is one line of a state file: the activities of a completed task
+// or the reason a task failed.
+type taskRecord struct {
+	TaskID     int              `json:"taskId"`
+	Activities []model.Activity `json:"activities,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	At         time.Time        `json:"at"`
+}
+
+// State records the progress of an activity crawl in a JSON Lines file, one
+// line per finished task, so an interrupted crawl can be resumed. Later lines
+// win, so a task that failed and then succeeded counts as completed.
+type State struct {
+	path      string
+	file      *os.File
+	mu        sync.Mutex
+	completed map[int][]model.Activity
+	failed    map[int]string
+}
+
+// OpenState opens the state file at path. With resume, the progress already
+// recorded there is loaded; otherwise the file is started afresh.
+func OpenState(path string, resume bool) (*State, error) {
+	s := &State{
+		path:      path,
+		completed: make(map[int][]model.Activity),
+		failed:    make(map[int]string),
+	}
+	if resume {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	s.file = file
+	return s, nil
+}
+
+func (s *State) load() error {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var record taskRecord
+		// A crash can leave the last line half written; skip it and refetch.
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		s.apply(record)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read state file %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *State) apply(record taskRecord) {
+	if record.Error != "" {
+		delete(s.completed, record.TaskID)
+		s.failed[record.TaskID] = record.Error
+		return
+	}
+	delete(s.failed, record.TaskID)
+	s.completed[record.TaskID] = record.Activities
+}
+
+func (s *State) Path() string {
+	return s.path
+}
+
+// Completed returns the activities recorded for taskID.
+func (s *State) Completed(taskID int) ([]model.Activity, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	activities, ok := s.completed[taskID]
+	return activities, ok
+}
+
+// Failed returns the reason of every task whose last attempt failed.
+func (s *State) Failed() map[int]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := make(map[int]string, len(s.failed))
+	for taskID, reason := range s.failed {
+		failed[taskID] = reason
+	}
+	return failed
+}
+
+// FailedIDs returns the IDs of failed tasks in ascending order.
+func (s *State) FailedIDs() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]int, 0, len(s.failed))
+	for taskID := range s.failed {
+		ids = append(ids, taskID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (s *State) recordCompleted(taskID int, activities []model.Activity) error {
+	return s.record(taskRecord{TaskID: taskID, Activities: activities, At: time.Now()})
+}
+
+func (s *State) recordFailed(taskID int, reason error) error {
+	return s.record(taskRecord{TaskID: taskID, Error: reason.Error(), At: time.Now()})
+}
+
+func (s *State) record(record taskRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode state of task %d: %w", record.TaskID, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	s.apply(record)
+	return nil
+}
+
+func (s *State) Close() error {
+	return s.file.Close()
+}
+
+// Remove closes and deletes the state file, e.g. once a crawl has finished
+// without failures and there is nothing left to resume.
+func (s *State) Remove() error {
+	s.Close()
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove state file: %w", err)
+	}
+	return nil
+}
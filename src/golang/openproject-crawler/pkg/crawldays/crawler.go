@@ -0,0 +1,81 @@
+package crawldays
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/model"
+	"openproject-crawler/pkg/workcal"
+	"time"
+)
+
+const (
+	weekPath       = "/days/week"
+	nonWorkingPath = "/days/non_working"
+	dateLayout     = "2006-01-02"
+)
+
+type CrawlDays struct {
+	*httpclient.APIClient
+}
+
+func NewCrawlDays(apiURL, authToken string) (*CrawlDays, error) {
+	apiClient, err := httpclient.NewAPIClient(apiURL, authToken)
+	if err != nil {
+		return nil, err
+	}
+	return &CrawlDays{APIClient: apiClient}, nil
+}
+
+func (c *CrawlDays) GetWeekDays(ctx context.Context) ([]model.WeekDay, error) {
+	days, _, err := httpclient.GetElements[model.WeekDay](ctx, c.APIClient, weekPath, nil)
+	return days, err
+}
+
+func (c *CrawlDays) GetNonWorkingDays(ctx context.Context, from, to time.Time) ([]model.NonWorkingDay, error) {
+	filters, err := json.Marshal([]map[string]interface{}{
+		{
+			"date": map[string]interface{}{
+				"operator": "<>d",
+				"values":   []string{from.Format(dateLayout), to.Format(dateLayout)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{"filters": string(filters)}
+	days, _, err := httpclient.GetElements[model.NonWorkingDay](ctx, c.APIClient, nonWorkingPath, params)
+	return days, err
+}
+
+// LoadCalendar applies the instance's working week and the non-working days
+// between from and to onto calendar.
+func (c *CrawlDays) LoadCalendar(ctx context.Context, calendar *workcal.Calendar, from, to time.Time) error {
+	weekDays, err := c.GetWeekDays(ctx)
+	if err != nil {
+		return err
+	}
+	var working []time.Weekday
+	for _, day := range weekDays {
+		if day.Working {
+			// OpenProject numbers days ISO style, Monday 1 to Sunday 7.
+			working = append(working, time.Weekday(day.Day%7))
+		}
+	}
+	calendar.SetWorkingDays(working...)
+
+	nonWorking, err := c.GetNonWorkingDays(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	for _, day := range nonWorking {
+		date, err := time.ParseInLocation(dateLayout, day.Date, calendar.GetLocation())
+		if err != nil {
+			return fmt.Errorf("invalid non-working day %q: %w", day.Date, err)
+		}
+		calendar.AddHoliday(date, day.Name)
+	}
+	return nil
+}
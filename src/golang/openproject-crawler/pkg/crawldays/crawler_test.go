@@ -0,0 +1,45 @@
+package crawldays
+
+import (
+	"context"
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/pkg/workcal"
+	"testing"
+	"time"
+)
+
+func TestLoadCalendar(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	c, err := NewCrawlDays(srv.APIURL(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRateLimiter(nil)
+
+	calendar := workcal.NewCalendar()
+	calendar.SetLocation(time.UTC)
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	if err := c.LoadCalendar(context.Background(), calendar, from, to); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(calendar.Holidays()); got != 1 {
+		t.Errorf("holidays = %d, want only Good Friday", got)
+	}
+	tests := []struct {
+		date    time.Time
+		working bool
+	}{
+		{time.Date(2024, 3, 28, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := calendar.IsWorkingDay(tt.date); got != tt.working {
+			t.Errorf("IsWorkingDay(%s) = %v, want %v", tt.date.Format(dateLayout), got, tt.working)
+		}
+	}
+}
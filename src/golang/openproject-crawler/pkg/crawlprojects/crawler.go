@@ -1,23 +1,18 @@
 package crawlprojects
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/model"
 )
 
-const (
-	projectsPath  = "/projects"
-	elementsKey   = "elements"
-	embeddedKey   = "_embedded"
-	totalKey      = "total"
-	idKey         = "id"
-	identifierKey = "identifier"
-)
+const projectsPath = "/projects"
 
 type CrawlProjects struct {
 	*httpclient.APIClient
-	data map[string]interface{}
+	data    []model.Project
+	total   int
+	fetched bool
 }
 
 func NewCrawlProjects(apiURL, authToken string) (*CrawlProjects, error) {
@@ -28,70 +23,48 @@ func NewCrawlProjects(apiURL, authToken string) (*CrawlProjects, error) {
 	apiClient.SetURIPath(projectsPath)
 	return &CrawlProjects{
 		APIClient: apiClient,
-		data:      make(map[string]interface{}),
+		data:      []model.Project{},
 	}, nil
 }
 
-func (c *CrawlProjects) fetchData() error {
-	response, err := c.GetRequest("", nil)
+func (c *CrawlProjects) fetchData(ctx context.Context) error {
+	projects, collection, err := httpclient.GetElements[model.Project](ctx, c.APIClient, "", nil)
 	if err != nil {
 		return err
 	}
 
-	var responseData map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &responseData); err != nil {
-		return err
-	}
+	c.data = projects
+	c.total = collection.Total
+	c.fetched = true
+	return nil
+}
 
-	embedded, ok := responseData[embeddedKey].(map[string]interface{})
-	if !ok || embedded[elementsKey] == nil {
-		return fmt.Errorf("%s or %s key not found", embeddedKey, elementsKey)
+func (c *CrawlProjects) GetProjects(ctx context.Context) ([]model.Project, error) {
+	if err := c.fetchData(ctx); err != nil {
+		return nil, err
 	}
+	return c.data, nil
+}
 
-	c.data = map[string]interface{}{
-		elementsKey: embedded[elementsKey],
-		totalKey:    responseData[totalKey],
-	}
-	return nil
+func (c *CrawlProjects) GetProjectsData() []model.Project {
+	return c.data
 }
 
-func (c *CrawlProjects) GetTotalProjects() (int, error) {
-	if err := c.fetchData(); err != nil {
+func (c *CrawlProjects) GetTotalProjects(ctx context.Context) (int, error) {
+	if err := c.fetchData(ctx); err != nil {
 		return 0, err
 	}
-
-	total, ok := c.data[totalKey].(float64)
-	if !ok {
-		return 0, fmt.Errorf("%s key not found in data", totalKey)
-	}
-
-	return int(total), nil
+	return c.total, nil
 }
 
-func (c *CrawlProjects) GetProjectsID() (map[int]string, error) {
-	if err := c.fetchData(); err != nil {
+func (c *CrawlProjects) GetProjectsID(ctx context.Context) (map[int]string, error) {
+	if err := c.fetchData(ctx); err != nil {
 		return nil, err
 	}
 
 	projectMap := make(map[int]string)
-	elements, ok := c.data[elementsKey].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("%s key not found or not a slice", elementsKey)
-	}
-
-	for _, element := range elements {
-		project, ok := element.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("element is not a map")
-		}
-
-		id, ok := project[idKey].(float64)
-		identifier, ok2 := project[identifierKey].(string)
-		if !ok || !ok2 {
-			return nil, fmt.Errorf("%s or %s not found or invalid", idKey, identifierKey)
-		}
-
-		projectMap[int(id)] = identifier
+	for _, project := range c.data {
+		projectMap[project.ID] = project.Identifier
 	}
 
 	return projectMap, nil
@@ -0,0 +1,191 @@
+package crawlprojects
+
+import (
+	"context"
+	"fmt"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProjectNode is a project with its subprojects, ordered by name.
+type ProjectNode struct {
+	Project  model.Project  `json:"project"`
+	Children []*ProjectNode `json:"children,omitempty"`
+}
+
+// ensureData fetches the projects once; later lookups use the cached list.
+func (c *CrawlProjects) ensureData(ctx context.Context) error {
+	if c.fetched {
+		return nil
+	}
+	return c.fetchData(ctx)
+}
+
+func (c *CrawlProjects) GetProjectByID(ctx context.Context, id int) (*model.Project, error) {
+	if err := c.ensureData(ctx); err != nil {
+		return nil, err
+	}
+	if project := c.findByID(id); project != nil {
+		return project, nil
+	}
+	return nil, fmt.Errorf("found no project with ID %d", id)
+}
+
+func (c *CrawlProjects) GetProjectByIdentifier(ctx context.Context, identifier string) (*model.Project, error) {
+	if err := c.ensureData(ctx); err != nil {
+		return nil, err
+	}
+	if project := c.findByIdentifier(identifier); project != nil {
+		return project, nil
+	}
+	return nil, fmt.Errorf("found no project with identifier %q", identifier)
+}
+
+// GetProjectByName matches the display name case-insensitively. Names are
+// not unique, so more than one match is an error.
+func (c *CrawlProjects) GetProjectByName(ctx context.Context, name string) (*model.Project, error) {
+	if err := c.ensureData(ctx); err != nil {
+		return nil, err
+	}
+	project, err := uniqueName(name, c.findByName(name))
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, fmt.Errorf("found no project named %q", name)
+	}
+	return project, nil
+}
+
+// LookupProject resolves ref as a numeric ID, then an identifier, then a
+// display name.
+func (c *CrawlProjects) LookupProject(ctx context.Context, ref string) (*model.Project, error) {
+	if err := c.ensureData(ctx); err != nil {
+		return nil, err
+	}
+	if id, err := strconv.Atoi(ref); err == nil {
+		if project := c.findByID(id); project != nil {
+			return project, nil
+		}
+	}
+	if project := c.findByIdentifier(ref); project != nil {
+		return project, nil
+	}
+	project, err := uniqueName(ref, c.findByName(ref))
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, fmt.Errorf("found no project with ID, identifier or name %q", ref)
+	}
+	return project, nil
+}
+
+func (c *CrawlProjects) findByID(id int) *model.Project {
+	for i := range c.data {
+		if c.data[i].ID == id {
+			return &c.data[i]
+		}
+	}
+	return nil
+}
+
+func (c *CrawlProjects) findByIdentifier(identifier string) *model.Project {
+	for i := range c.data {
+		if c.data[i].Identifier == identifier {
+			return &c.data[i]
+		}
+	}
+	return nil
+}
+
+func (c *CrawlProjects) findByName(name string) []*model.Project {
+	var matches []*model.Project
+	for i := range c.data {
+		if strings.EqualFold(c.data[i].Name, name) {
+			matches = append(matches, &c.data[i])
+		}
+	}
+	return matches
+}
+
+func uniqueName(name string, matches []*model.Project) (*model.Project, error) {
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	}
+	identifiers := make([]string, len(matches))
+	for i, project := range matches {
+		identifiers[i] = project.Identifier
+	}
+	return nil, fmt.Errorf("project name %q is ambiguous; use one of the identifiers %s", name, strings.Join(identifiers, ", "))
+}
+
+// GetProjectTree arranges the visible projects by their parent links.
+// Projects whose parent is not visible become roots.
+func (c *CrawlProjects) GetProjectTree(ctx context.Context) ([]*ProjectNode, error) {
+	if err := c.ensureData(ctx); err != nil {
+		return nil, err
+	}
+	nodes := make(map[int]*ProjectNode, len(c.data))
+	for _, project := range c.data {
+		nodes[project.ID] = &ProjectNode{Project: project}
+	}
+
+	var roots []*ProjectNode
+	for _, project := range c.data {
+		node := nodes[project.ID]
+		if parentID, ok := project.ParentID(); ok {
+			if parent, found := nodes[parentID]; found {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	sortNodes(roots)
+	return roots, nil
+}
+
+// GetSubprojects returns every project below id, at any depth.
+func (c *CrawlProjects) GetSubprojects(ctx context.Context, id int) ([]model.Project, error) {
+	if err := c.ensureData(ctx); err != nil {
+		return nil, err
+	}
+	children := make(map[int][]model.Project)
+	for _, project := range c.data {
+		if parentID, ok := project.ParentID(); ok {
+			children[parentID] = append(children[parentID], project)
+		}
+	}
+
+	var subprojects []model.Project
+	queue := []int{id}
+	seen := map[int]bool{id: true}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range children[current] {
+			if seen[child.ID] {
+				continue
+			}
+			seen[child.ID] = true
+			subprojects = append(subprojects, child)
+			queue = append(queue, child.ID)
+		}
+	}
+	return subprojects, nil
+}
+
+func sortNodes(nodes []*ProjectNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Project.Name < nodes[j].Project.Name
+	})
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+}
@@ -0,0 +1,81 @@
+package crawlprojects
+
+import (
+	"context"
+	"encoding/json"
+	"openproject-crawler/internal/fakeapi"
+	"strings"
+	"testing"
+)
+
+func newTestCrawler(t *testing.T, fixtures *fakeapi.Fixtures) *CrawlProjects {
+	t.Helper()
+	srv := fakeapi.New(fixtures)
+	t.Cleanup(srv.Close)
+	c, err := NewCrawlProjects(srv.APIURL(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRateLimiter(nil)
+	return c
+}
+
+func TestLookupProject(t *testing.T) {
+	fixtures := fakeapi.DefaultFixtures()
+	fixtures.Projects = append(fixtures.Projects,
+		json.RawMessage(`{"_type":"Project","id":4,"identifier":"ops-eu","name":"operations","active":true}`))
+	c := newTestCrawler(t, fixtures)
+
+	tests := []struct {
+		ref     string
+		wantID  int
+		wantErr string
+	}{
+		{"1", 1, ""},
+		{"demo-sub", 2, ""},
+		{"demo project", 1, ""},
+		{"Operations", 0, "ambiguous"},
+		{"missing", 0, "found no project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			project, err := c.LookupProject(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if project.ID != tt.wantID {
+				t.Errorf("ID = %d, want %d", project.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestProjectTree(t *testing.T) {
+	c := newTestCrawler(t, nil)
+	ctx := context.Background()
+
+	roots, err := c.GetProjectTree(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 2 || roots[0].Project.Identifier != "demo" || roots[1].Project.Identifier != "ops" {
+		t.Fatalf("roots = %v", roots)
+	}
+	if len(roots[0].Children) != 1 || roots[0].Children[0].Project.ID != 2 {
+		t.Errorf("children of demo = %v", roots[0].Children)
+	}
+
+	subprojects, err := c.GetSubprojects(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subprojects) != 1 || subprojects[0].ID != 2 {
+		t.Errorf("subprojects = %v", subprojects)
+	}
+}
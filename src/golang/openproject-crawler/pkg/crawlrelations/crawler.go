@@ -0,0 +1,97 @@
+package crawlrelations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/internal/workerpool"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"sync"
+)
+
+type CrawlRelations struct {
+	*httpclient.APIClient
+	data []model.Relation
+	pool *workerpool.Pool
+	mu   sync.Mutex
+}
+
+func NewCrawlRelations(apiURL, authToken string) (*CrawlRelations, error) {
+	apiClient, err := httpclient.NewAPIClient(apiURL, authToken)
+	if err != nil {
+		return nil, err
+	}
+	return &CrawlRelations{
+		APIClient: apiClient,
+		data:      []model.Relation{},
+		pool:      workerpool.New(workerpool.DefaultSize),
+	}, nil
+}
+
+func (c *CrawlRelations) GetPool() *workerpool.Pool {
+	return c.pool
+}
+
+func (c *CrawlRelations) SetPool(pool *workerpool.Pool) {
+	c.pool = pool
+}
+
+// GetRelations fetches the relations of every work package in ids, ordered
+// by relation ID. A relation between two of them is returned once. Failed
+// work packages are logged and skipped; the error then reports how many
+// failed, or the context error if ctx was cancelled.
+func (c *CrawlRelations) GetRelations(ctx context.Context, ids []int) ([]model.Relation, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	byID := make(map[int]model.Relation)
+	failed := 0
+	for _, id := range ids {
+		if err := c.pool.Go(ctx, &wg, func() {
+			customURI := fmt.Sprintf("/work_packages/%d/relations", id)
+			relations, _, err := httpclient.GetElements[model.Relation](ctx, c.APIClient, customURI, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+					log.Printf("Error: failed to fetch relations for task %d: %v\n", id, err)
+					failed++
+				}
+				return
+			}
+			for _, relation := range relations {
+				byID[relation.ID] = relation
+			}
+		}); err != nil {
+			break
+		}
+	}
+	wg.Wait()
+
+	relations := make([]model.Relation, 0, len(byID))
+	for _, relation := range byID {
+		relations = append(relations, relation)
+	}
+	sort.Slice(relations, func(i, j int) bool {
+		return relations[i].ID < relations[j].ID
+	})
+	c.mu.Lock()
+	c.data = relations
+	c.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return relations, err
+	}
+	if failed > 0 {
+		return relations, fmt.Errorf("relations of %d of %d tasks failed", failed, len(ids))
+	}
+	return relations, nil
+}
+
+func (c *CrawlRelations) GetRelationsData() []model.Relation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data
+}
@@ -0,0 +1,147 @@
+package crawlrelations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/internal/fakeapi/fakeapitest"
+	"openproject-crawler/pkg/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fixtureWorkPackages(t *testing.T) []model.WorkPackage {
+	t.Helper()
+	var workPackages []model.WorkPackage
+	for _, raw := range fakeapi.DefaultFixtures().WorkPackages {
+		var wp model.WorkPackage
+		if err := json.Unmarshal(raw, &wp); err != nil {
+			t.Fatal(err)
+		}
+		workPackages = append(workPackages, wp)
+	}
+	return workPackages
+}
+
+func TestGetRelations(t *testing.T) {
+	c := fakeapitest.Crawler(t, fakeapitest.New(t, nil), NewCrawlRelations)
+	relations, err := c.GetRelations(context.Background(), []int{101, 102, 103})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, relation := range relations {
+		ids = append(ids, relation.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 2, 3, 4}) {
+		t.Errorf("relation IDs = %v, want each relation of 101-103 once", ids)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	c := fakeapitest.Crawler(t, fakeapitest.New(t, nil), NewCrawlRelations)
+	ids := []int{101, 102, 103, 201}
+	relations, err := c.GetRelations(context.Background(), ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGraph(ids)
+	g.AddWorkPackages(fixtureWorkPackages(t))
+	g.AddRelations(relations)
+
+	var edges []string
+	for _, edge := range g.Edges {
+		edges = append(edges, fmt.Sprintf("%d %s %d", edge.From, edge.Kind, edge.To))
+	}
+	want := []string{"102 parent 103", "101 precedes 102", "103 relates 101", "101 blocks 301", "103 precedes 201", "201 precedes 301"}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("edges = %v, want %v", edges, want)
+	}
+	if node, _ := g.Node(301); !node.External || node.Subject != "Rotate certificates" {
+		t.Errorf("node 301 = %+v, want external", node)
+	}
+	if node, _ := g.Node(101); node.Days() != 5 || node.Status != "Closed" {
+		t.Errorf("node 101 = %+v", node)
+	}
+
+	if cycles := g.Cycles(); len(cycles) != 0 {
+		t.Errorf("cycles = %v", cycles)
+	}
+	path, err := g.CriticalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(path, Path{WorkPackages: []int{101, 102}, Days: 10}) {
+		t.Errorf("critical path = %+v", path)
+	}
+
+	var dot, mermaid bytes.Buffer
+	if err := WriteDOT(&dot, g); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteMermaid(&mermaid, g); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`wp101 [label="#101 Login fails\nBug Closed"];`,
+		`wp301 [label="#301 Rotate certificates", style=dashed];`,
+		`wp103 -> wp201 [label="precedes +2d"];`,
+		`wp103 -> wp101 [label="relates", dir=none, style=dashed];`,
+	} {
+		if !strings.Contains(dot.String(), line) {
+			t.Errorf("DOT misses %s:\n%s", line, dot.String())
+		}
+	}
+	for _, line := range []string{
+		`wp301["#301 Rotate certificates"]:::external`,
+		`wp102 ==>|parent| wp103`,
+		`wp103 -.-|relates| wp101`,
+		`wp103 -->|precedes +2d| wp201`,
+	} {
+		if !strings.Contains(mermaid.String(), line) {
+			t.Errorf("Mermaid misses %s:\n%s", line, mermaid.String())
+		}
+	}
+}
+
+func TestCycles(t *testing.T) {
+	follows := func(id, from, to int) model.Relation {
+		return model.Relation{
+			ID:           id,
+			RelationType: "follows",
+			Links: model.RelationLinks{
+				From: model.Link{Href: fmt.Sprintf("/api/v3/work_packages/%d", from)},
+				To:   model.Link{Href: fmt.Sprintf("/api/v3/work_packages/%d", to)},
+			},
+		}
+	}
+	tests := []struct {
+		name      string
+		relations []model.Relation
+		want      [][]int
+	}{
+		{"chain", []model.Relation{follows(1, 2, 1), follows(2, 3, 2)}, nil},
+		{"triangle", []model.Relation{follows(1, 2, 1), follows(2, 3, 2), follows(3, 1, 3)}, [][]int{{1, 2, 3}}},
+		{"shortest way back", []model.Relation{follows(1, 2, 1), follows(2, 3, 2), follows(3, 1, 3), follows(4, 1, 2)}, [][]int{{1, 2}}},
+		{"two cycles", []model.Relation{follows(1, 2, 1), follows(2, 1, 2), follows(3, 4, 3), follows(4, 3, 4)}, [][]int{{1, 2}, {3, 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGraph([]int{1, 2, 3, 4})
+			g.AddRelations(tt.relations)
+			cycles := g.Cycles()
+			if !reflect.DeepEqual(cycles, tt.want) {
+				t.Errorf("cycles = %v, want %v", cycles, tt.want)
+			}
+			_, err := g.CriticalPath()
+			var cycleErr *CycleError
+			if (len(tt.want) > 0) != errors.As(err, &cycleErr) {
+				t.Errorf("CriticalPath err = %v", err)
+			}
+		})
+	}
+}
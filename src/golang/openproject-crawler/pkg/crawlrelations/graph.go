@@ -0,0 +1,192 @@
+package crawlrelations
+
+import (
+	"openproject-crawler/pkg/model"
+	"sort"
+	"time"
+)
+
+// Edge kinds. Each relation type is turned into one direction, e.g.
+// "A follows B" becomes the edge B precedes A, and "A blocked by B" the edge
+// B blocks A. Parent edges point from a parent to its child.
+const (
+	KindPrecedes   = "precedes"
+	KindBlocks     = "blocks"
+	KindRelates    = "relates"
+	KindDuplicates = "duplicates"
+	KindIncludes   = "includes"
+	KindRequires   = "requires"
+	KindParent     = "parent"
+)
+
+const dateLayout = "2006-01-02"
+
+// reversedKinds maps the relation types stored the other way round to the
+// kind of the reversed edge.
+var reversedKinds = map[string]string{
+	"follows":    KindPrecedes,
+	"blocked":    KindBlocks,
+	"duplicated": KindDuplicates,
+	"partof":     KindIncludes,
+	"required":   KindRequires,
+}
+
+type Node struct {
+	ID        int    `json:"id"`
+	Subject   string `json:"subject,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Status    string `json:"status,omitempty"`
+	StartDate string `json:"startDate,omitempty"`
+	DueDate   string `json:"dueDate,omitempty"`
+	// External nodes were not crawled themselves but are related to, or the
+	// parent of, a crawled work package.
+	External bool `json:"external,omitempty"`
+}
+
+// Days is the node's duration from start to due date, both inclusive. A node
+// with only one of the dates lasts a day; one without dates takes no time.
+func (n Node) Days() int {
+	start, startErr := time.Parse(dateLayout, n.StartDate)
+	due, dueErr := time.Parse(dateLayout, n.DueDate)
+	switch {
+	case startErr == nil && dueErr == nil:
+		if due.Before(start) {
+			return 0
+		}
+		return int(due.Sub(start).Hours()/24) + 1
+	case startErr == nil || dueErr == nil:
+		return 1
+	}
+	return 0
+}
+
+type Edge struct {
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	Kind       string `json:"kind"`
+	Lag        int    `json:"lag,omitempty"`
+	RelationID int    `json:"relationId,omitempty"`
+}
+
+// Graph is the dependency graph of a set of work packages, with nodes
+// ordered by ID and edges in the order they were added.
+type Graph struct {
+	Nodes     []Node `json:"nodes"`
+	Edges     []Edge `json:"edges"`
+	index     map[int]int
+	relations map[int]bool
+}
+
+// NewGraph starts a graph with a node for each work package ID, e.g. the IDs
+// CrawlWorkPackages.GetTasksID returns.
+func NewGraph(ids []int) *Graph {
+	g := &Graph{
+		Nodes:     []Node{},
+		Edges:     []Edge{},
+		index:     make(map[int]int),
+		relations: make(map[int]bool),
+	}
+	for _, id := range ids {
+		g.addNode(Node{ID: id})
+	}
+	return g
+}
+
+func (g *Graph) addNode(node Node) {
+	if _, ok := g.index[node.ID]; ok {
+		return
+	}
+	i := sort.Search(len(g.Nodes), func(i int) bool {
+		return g.Nodes[i].ID >= node.ID
+	})
+	g.Nodes = append(g.Nodes, Node{})
+	copy(g.Nodes[i+1:], g.Nodes[i:])
+	g.Nodes[i] = node
+	for j := i; j < len(g.Nodes); j++ {
+		g.index[g.Nodes[j].ID] = j
+	}
+}
+
+func (g *Graph) Node(id int) (Node, bool) {
+	i, ok := g.index[id]
+	if !ok {
+		return Node{}, false
+	}
+	return g.Nodes[i], true
+}
+
+// AddWorkPackages fills in the subject, type, status and dates of the nodes
+// and adds an edge from each node's parent. Work packages without a node are
+// ignored.
+func (g *Graph) AddWorkPackages(workPackages []model.WorkPackage) {
+	for _, wp := range workPackages {
+		i, ok := g.index[wp.ID]
+		if !ok {
+			continue
+		}
+		node := &g.Nodes[i]
+		node.Subject = wp.Subject
+		node.Type = wp.TypeName()
+		node.Status = wp.StatusName()
+		node.StartDate = wp.StartDate
+		node.DueDate = wp.DueDate
+		if node.StartDate == "" && node.DueDate == "" {
+			// Milestones only have a date.
+			node.StartDate = wp.Date
+			node.DueDate = wp.Date
+		}
+	}
+	for _, wp := range workPackages {
+		if _, ok := g.index[wp.ID]; !ok {
+			continue
+		}
+		if parentID, ok := wp.Links.Parent.ID(); ok {
+			g.addExternal(parentID, wp.Links.Parent.Title)
+			g.Edges = append(g.Edges, Edge{From: parentID, To: wp.ID, Kind: KindParent})
+		}
+	}
+}
+
+// AddRelations adds an edge for every relation that involves a node, once per
+// relation ID. The other end becomes an external node if it has none.
+func (g *Graph) AddRelations(relations []model.Relation) {
+	for _, relation := range relations {
+		from, fromOK := relation.Links.From.ID()
+		to, toOK := relation.Links.To.ID()
+		if !fromOK || !toOK || g.relations[relation.ID] {
+			continue
+		}
+		_, hasFrom := g.index[from]
+		_, hasTo := g.index[to]
+		if !hasFrom && !hasTo {
+			continue
+		}
+		g.relations[relation.ID] = true
+		g.addExternal(from, relation.Links.From.Title)
+		g.addExternal(to, relation.Links.To.Title)
+
+		kind := relation.RelationType
+		if reversed, ok := reversedKinds[kind]; ok {
+			kind = reversed
+			from, to = to, from
+		}
+		g.Edges = append(g.Edges, Edge{From: from, To: to, Kind: kind, Lag: relation.LagDays(), RelationID: relation.ID})
+	}
+}
+
+func (g *Graph) addExternal(id int, subject string) {
+	if _, ok := g.index[id]; !ok {
+		g.addNode(Node{ID: id, Subject: subject, External: true})
+	}
+}
+
+// successors lists the nodes each node has an edge of kind to, in edge order.
+func (g *Graph) successors(kind string) map[int][]Edge {
+	next := make(map[int][]Edge)
+	for _, edge := range g.Edges {
+		if edge.Kind == kind {
+			next[edge.From] = append(next[edge.From], edge)
+		}
+	}
+	return next
+}
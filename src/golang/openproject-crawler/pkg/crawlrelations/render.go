@@ -0,0 +1,94 @@
+package crawlrelations
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Label is "#101 Login fails", or just "#101" without a subject.
+func (n Node) Label() string {
+	if n.Subject == "" {
+		return fmt.Sprintf("#%d", n.ID)
+	}
+	return fmt.Sprintf("#%d %s", n.ID, n.Subject)
+}
+
+var dotEdgeStyles = map[string]string{
+	KindBlocks:     `, color="firebrick"`,
+	KindRelates:    `, dir=none, style=dashed`,
+	KindDuplicates: `, style=dotted`,
+	KindParent:     `, style=bold, arrowhead=none`,
+}
+
+// WriteDOT writes the graph in Graphviz DOT, e.g. for
+// "dot -Tsvg graph.dot > graph.svg". External nodes are dashed.
+func WriteDOT(out io.Writer, g *Graph) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "digraph workpackages {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for _, node := range g.Nodes {
+		label := node.Label()
+		if node.Type != "" || node.Status != "" {
+			label += "\n" + strings.TrimSpace(node.Type+" "+node.Status)
+		}
+		style := ""
+		if node.External {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(w, "  wp%d [label=%s%s];\n", node.ID, dotQuote(label), style)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(w, "  wp%d -> wp%d [label=%s%s];\n", edge.From, edge.To, dotQuote(edgeLabel(edge)), dotEdgeStyles[edge.Kind])
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
+
+var mermaidArrows = map[string]string{
+	KindRelates: "-.-",
+	KindParent:  "==>",
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, which GitHub and
+// GitLab render inside a ```mermaid block.
+func WriteMermaid(out io.Writer, g *Graph) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "flowchart LR")
+	for _, node := range g.Nodes {
+		class := ""
+		if node.External {
+			class = ":::external"
+		}
+		fmt.Fprintf(w, "  wp%d[\"%s\"]%s\n", node.ID, mermaidEscape(node.Label()), class)
+	}
+	for _, edge := range g.Edges {
+		arrow, ok := mermaidArrows[edge.Kind]
+		if !ok {
+			arrow = "-->"
+		}
+		fmt.Fprintf(w, "  wp%d %s|%s| wp%d\n", edge.From, arrow, mermaidEscape(edgeLabel(edge)), edge.To)
+	}
+	fmt.Fprintln(w, "  classDef external stroke-dasharray: 5 5")
+	return w.Flush()
+}
+
+func edgeLabel(edge Edge) string {
+	if edge.Lag != 0 {
+		return fmt.Sprintf("%s +%dd", edge.Kind, edge.Lag)
+	}
+	return edge.Kind
+}
+
+func dotQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + strings.ReplaceAll(value, "\n", `\n`) + `"`
+}
+
+// mermaidEscape replaces the characters that would end a label early.
+func mermaidEscape(value string) string {
+	return strings.NewReplacer(`"`, "#quot;", "|", "#124;").Replace(value)
+}
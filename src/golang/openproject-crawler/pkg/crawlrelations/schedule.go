@@ -0,0 +1,188 @@
+package crawlrelations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CycleError means the precedes edges form cycles, so the work packages
+// cannot be scheduled.
+type CycleError struct {
+	Cycles [][]int
+}
+
+func (e *CycleError) Error() string {
+	cycles := make([]string, len(e.Cycles))
+	for i, cycle := range e.Cycles {
+		ids := make([]string, len(cycle)+1)
+		for j, id := range cycle {
+			ids[j] = fmt.Sprintf("#%d", id)
+		}
+		ids[len(cycle)] = ids[0]
+		cycles[i] = strings.Join(ids, " -> ")
+	}
+	return fmt.Sprintf("work packages follow each other in a cycle: %s", strings.Join(cycles, "; "))
+}
+
+// Path is a chain of work packages that follow each other.
+type Path struct {
+	WorkPackages []int `json:"workPackages"`
+	Days         int   `json:"days"`
+}
+
+// Cycles returns the cycles among the precedes edges, one per group of work
+// packages that follow each other, as the IDs along the cycle starting at the
+// lowest one.
+func (g *Graph) Cycles() [][]int {
+	next := g.successors(KindPrecedes)
+	var cycles [][]int
+	for _, component := range g.components(next) {
+		if cycle := shortestCycle(component, next); cycle != nil {
+			cycles = append(cycles, cycle)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// components returns the strongly connected components of the graph formed
+// by next (Tarjan's algorithm), each sorted by ID.
+func (g *Graph) components(next map[int][]Edge) [][]int {
+	index := make(map[int]int)
+	low := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	var components [][]int
+
+	var visit func(id int)
+	visit = func(id int) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, edge := range next[id] {
+			if _, seen := index[edge.To]; !seen {
+				visit(edge.To)
+				low[id] = min(low[id], low[edge.To])
+			} else if onStack[edge.To] {
+				low[id] = min(low[id], index[edge.To])
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+		var component []int
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		sort.Ints(component)
+		components = append(components, component)
+	}
+	for _, node := range g.Nodes {
+		if _, seen := index[node.ID]; !seen {
+			visit(node.ID)
+		}
+	}
+	return components
+}
+
+// shortestCycle finds the shortest way from the lowest ID of a component
+// back to itself, or nil if the component is a single node without a loop.
+func shortestCycle(component []int, next map[int][]Edge) []int {
+	start := component[0]
+	inComponent := make(map[int]bool, len(component))
+	for _, id := range component {
+		inComponent[id] = true
+	}
+	previous := map[int]int{}
+	queue := []int{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, edge := range next[id] {
+			if edge.To == start {
+				cycle := []int{id}
+				for id != start {
+					id = previous[id]
+					cycle = append(cycle, id)
+				}
+				for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+					cycle[i], cycle[j] = cycle[j], cycle[i]
+				}
+				return cycle
+			}
+			if _, seen := previous[edge.To]; !seen && inComponent[edge.To] {
+				previous[edge.To] = id
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+	return nil
+}
+
+// CriticalPath returns the longest chain of work packages that follow each
+// other, measured in calendar days: the duration of each work package from
+// start to due date plus the lag of each precedes relation. Ties go to the
+// chain ending at the lower ID. It fails with a *CycleError if the precedes
+// edges form a cycle.
+func (g *Graph) CriticalPath() (Path, error) {
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		return Path{}, &CycleError{Cycles: cycles}
+	}
+	next := g.successors(KindPrecedes)
+
+	incoming := make(map[int]int)
+	for _, edges := range next {
+		for _, edge := range edges {
+			incoming[edge.To]++
+		}
+	}
+	var ready []int
+	for _, node := range g.Nodes {
+		if incoming[node.ID] == 0 {
+			ready = append(ready, node.ID)
+		}
+	}
+
+	// finish is the length of the longest chain ending with a node.
+	finish := make(map[int]int)
+	previous := make(map[int]int)
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		node, _ := g.Node(id)
+		finish[id] += node.Days()
+		for _, edge := range next[id] {
+			if start := finish[id] + edge.Lag; start > finish[edge.To] || previous[edge.To] == 0 {
+				finish[edge.To] = start
+				previous[edge.To] = id
+			}
+			incoming[edge.To]--
+			if incoming[edge.To] == 0 {
+				ready = append(ready, edge.To)
+			}
+		}
+	}
+
+	path := Path{WorkPackages: []int{}}
+	end := 0
+	for _, node := range g.Nodes {
+		if end == 0 || finish[node.ID] > path.Days {
+			end = node.ID
+			path.Days = finish[node.ID]
+		}
+	}
+	for id := end; id != 0; id = previous[id] {
+		path.WorkPackages = append([]int{id}, path.WorkPackages...)
+	}
+	return path, nil
+}
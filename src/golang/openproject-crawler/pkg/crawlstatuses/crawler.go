@@ -1,22 +1,17 @@
 package crawlstatuses
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/model"
 )
 
-const (
-	statusPath  = "/statuses"
-	elementsKey = "elements"
-	embeddedKey = "_embedded"
-	idKey       = "id"
-	nameKey     = "name"
-)
+const statusPath = "/statuses"
 
 type CrawlStatuses struct {
 	*httpclient.APIClient
-	data map[string]interface{}
+	data []model.Status
 }
 
 func NewCrawlStatuses(apiURL, authToken string) (*CrawlStatuses, error) {
@@ -27,53 +22,42 @@ func NewCrawlStatuses(apiURL, authToken string) (*CrawlStatuses, error) {
 	apiClient.SetURIPath(statusPath)
 	return &CrawlStatuses{
 		APIClient: apiClient,
-		data:      make(map[string]interface{}),
+		data:      []model.Status{},
 	}, nil
 }
 
-func (c *CrawlStatuses) FetchData() error {
-	response, err := c.GetRequest("", nil)
+func (c *CrawlStatuses) FetchData(ctx context.Context) error {
+	statuses, _, err := httpclient.GetElements[model.Status](ctx, c.APIClient, "", nil)
 	if err != nil {
 		return err
 	}
 
-	var responseData map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &responseData); err != nil {
-		return err
-	}
-
-	embedded, ok := responseData[embeddedKey].(map[string]interface{})
-	if !ok || embedded[elementsKey] == nil {
-		return fmt.Errorf("%s or %s key not found", embeddedKey, elementsKey)
-	}
-
-	c.data = map[string]interface{}{
-		elementsKey: embedded[elementsKey],
-	}
+	c.data = statuses
 	return nil
 }
 
-func (c *CrawlStatuses) Initialize() error {
-	return c.FetchData()
+func (c *CrawlStatuses) Initialize(ctx context.Context) error {
+	return c.FetchData(ctx)
 }
 
-func (c *CrawlStatuses) String() string {
-	elements, ok := c.data[elementsKey].([]interface{})
-	if !ok {
-		return "Invalid data format"
+func (c *CrawlStatuses) GetStatuses() []model.Status {
+	return c.data
+}
+
+func (c *CrawlStatuses) GetClosedStatuses() []model.Status {
+	var closed []model.Status
+	for _, status := range c.data {
+		if status.IsClosed {
+			closed = append(closed, status)
+		}
 	}
+	return closed
+}
 
+func (c *CrawlStatuses) String() string {
 	statusMap := make(map[int]string)
-	for _, element := range elements {
-		elementMap, ok := element.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		id, idOk := elementMap["id"].(float64)
-		name, nameOk := elementMap["name"].(string)
-		if idOk && nameOk {
-			statusMap[int(id)] = name
-		}
+	for _, status := range c.data {
+		statusMap[status.ID] = status.Name
 	}
 
 	result, _ := json.Marshal(statusMap)
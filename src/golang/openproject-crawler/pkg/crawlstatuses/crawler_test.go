@@ -0,0 +1,31 @@
+package crawlstatuses
+
+import (
+	"context"
+	"openproject-crawler/internal/fakeapi"
+	"testing"
+)
+
+func TestGetClosedStatuses(t *testing.T) {
+	srv := fakeapi.New(nil)
+	defer srv.Close()
+	c, err := NewCrawlStatuses(srv.APIURL(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRateLimiter(nil)
+	if err := c.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(c.GetStatuses()); got != 4 {
+		t.Errorf("statuses = %d, want 4", got)
+	}
+	closed := c.GetClosedStatuses()
+	if len(closed) != 2 || closed[0].Name != "Closed" || closed[1].Name != "Rejected" {
+		t.Errorf("closed statuses = %v", closed)
+	}
+	if want := `{"1":"New","12":"Closed","14":"Rejected","7":"In progress"}`; c.String() != want {
+		t.Errorf("String() = %s, want %s", c.String(), want)
+	}
+}
@@ -0,0 +1,127 @@
+package crawltime
+
+import (
+	"fmt"
+	"math"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"time"
+)
+
+const unsetKey = "(none)"
+
+// Entry is a time entry with its hours decoded and its links resolved to
+// names, ready to be summed or exported. Hours are not rounded, so sums stay
+// exact; only totals are.
+type Entry struct {
+	ID            int     `json:"id"`
+	SpentOn       string  `json:"spentOn"`
+	Week          string  `json:"week"`
+	Hours         float64 `json:"hours"`
+	UserID        int     `json:"userId,omitempty"`
+	User          string  `json:"user"`
+	ProjectID     int     `json:"projectId,omitempty"`
+	Project       string  `json:"project"`
+	WorkPackageID int     `json:"workPackageId,omitempty"`
+	WorkPackage   string  `json:"workPackage,omitempty"`
+	Activity      string  `json:"activity"`
+	Comment       string  `json:"comment,omitempty"`
+}
+
+type Total struct {
+	Key     string  `json:"key"`
+	Hours   float64 `json:"hours"`
+	Entries int     `json:"entries"`
+}
+
+type Report struct {
+	Entries       int     `json:"entries"`
+	Hours         float64 `json:"hours"`
+	ByUser        []Total `json:"byUser"`
+	ByWorkPackage []Total `json:"byWorkPackage"`
+	ByActivity    []Total `json:"byActivity"`
+	ByWeek        []Total `json:"byWeek"`
+}
+
+func NewEntries(timeEntries []model.TimeEntry) ([]Entry, error) {
+	entries := make([]Entry, 0, len(timeEntries))
+	for _, te := range timeEntries {
+		duration, err := ParseDuration(te.Hours)
+		if err != nil {
+			return nil, fmt.Errorf("time entry %d: %w", te.ID, err)
+		}
+		spentOn, err := time.Parse(dateLayout, te.SpentOn)
+		if err != nil {
+			return nil, fmt.Errorf("time entry %d has an invalid spentOn %q", te.ID, te.SpentOn)
+		}
+		year, week := spentOn.ISOWeek()
+
+		entry := Entry{
+			ID:       te.ID,
+			SpentOn:  te.SpentOn,
+			Week:     fmt.Sprintf("%d-W%02d", year, week),
+			Hours:    duration.Hours(),
+			User:     te.UserName(),
+			Project:  te.Links.Project.Title,
+			Activity: te.ActivityName(),
+			Comment:  te.Comment.Raw,
+		}
+		entry.UserID, _ = te.Links.User.ID()
+		entry.ProjectID, _ = te.Links.Project.ID()
+		if id, ok := te.Links.WorkPackage.ID(); ok {
+			entry.WorkPackageID = id
+			entry.WorkPackage = fmt.Sprintf("#%d %s", id, te.Links.WorkPackage.Title)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Aggregate sums the hours of entries per user, work package, activity and
+// ISO week. Entries without a work package are summed under "(none)".
+func Aggregate(entries []Entry) Report {
+	report := Report{
+		Entries:       len(entries),
+		ByUser:        SumBy(entries, func(e Entry) string { return e.User }),
+		ByWorkPackage: SumBy(entries, func(e Entry) string { return e.WorkPackage }),
+		ByActivity:    SumBy(entries, func(e Entry) string { return e.Activity }),
+		ByWeek:        SumBy(entries, func(e Entry) string { return e.Week }),
+	}
+	for _, entry := range entries {
+		report.Hours += entry.Hours
+	}
+	report.Hours = round2(report.Hours)
+	return report
+}
+
+// SumBy groups entries by key and returns the totals sorted by key, which
+// orders weeks chronologically.
+func SumBy(entries []Entry, key func(Entry) string) []Total {
+	index := make(map[string]int)
+	totals := []Total{}
+	for _, entry := range entries {
+		k := key(entry)
+		if k == "" {
+			k = unsetKey
+		}
+		i, ok := index[k]
+		if !ok {
+			i = len(totals)
+			index[k] = i
+			totals = append(totals, Total{Key: k})
+		}
+		totals[i].Hours += entry.Hours
+		totals[i].Entries++
+	}
+	for i := range totals {
+		totals[i].Hours = round2(totals[i].Hours)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Key < totals[j].Key
+	})
+	return totals
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,96 @@
+package crawltime
+
+import (
+	"context"
+	"encoding/json"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/model"
+	"strconv"
+	"time"
+)
+
+const (
+	timeEntriesPath = "/time_entries"
+	dateLayout      = "2006-01-02"
+)
+
+// Query narrows the time entries fetched. Empty fields do not filter; a zero
+// From or To leaves that end of the spentOn range open.
+type Query struct {
+	ProjectIDs     []int
+	UserIDs        []int
+	WorkPackageIDs []int
+	From           time.Time
+	To             time.Time
+}
+
+// Params renders the query as the filters parameter of /time_entries.
+func (q Query) Params() (map[string]interface{}, error) {
+	filters := []map[string]interface{}{}
+	addIDs := func(name string, ids []int) {
+		if len(ids) == 0 {
+			return
+		}
+		values := make([]string, len(ids))
+		for i, id := range ids {
+			values[i] = strconv.Itoa(id)
+		}
+		filters = append(filters, map[string]interface{}{
+			name: map[string]interface{}{"operator": "=", "values": values},
+		})
+	}
+	addIDs("project", q.ProjectIDs)
+	addIDs("user", q.UserIDs)
+	addIDs("work_package", q.WorkPackageIDs)
+	if !q.From.IsZero() || !q.To.IsZero() {
+		values := []string{"", ""}
+		if !q.From.IsZero() {
+			values[0] = q.From.Format(dateLayout)
+		}
+		if !q.To.IsZero() {
+			values[1] = q.To.Format(dateLayout)
+		}
+		filters = append(filters, map[string]interface{}{
+			"spent_on": map[string]interface{}{"operator": "<>d", "values": values},
+		})
+	}
+
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"filters": string(encoded)}, nil
+}
+
+type CrawlTimeEntries struct {
+	*httpclient.APIClient
+	data []model.TimeEntry
+}
+
+func NewCrawlTimeEntries(apiURL, authToken string) (*CrawlTimeEntries, error) {
+	apiClient, err := httpclient.NewAPIClient(apiURL, authToken)
+	if err != nil {
+		return nil, err
+	}
+	apiClient.SetURIPath(timeEntriesPath)
+	return &CrawlTimeEntries{
+		APIClient: apiClient,
+		data:      []model.TimeEntry{},
+	}, nil
+}
+
+// GetTimeEntries fetches every time entry matching query, following the
+// collection's pages. On error the entries fetched so far are returned.
+func (c *CrawlTimeEntries) GetTimeEntries(ctx context.Context, query Query) ([]model.TimeEntry, error) {
+	params, err := query.Params()
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := httpclient.GetElements[model.TimeEntry](ctx, c.APIClient, "", params)
+	c.data = entries
+	return entries, err
+}
+
+func (c *CrawlTimeEntries) GetTimeEntriesData() []model.TimeEntry {
+	return c.data
+}
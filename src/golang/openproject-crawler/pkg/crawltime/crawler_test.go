@@ -0,0 +1,95 @@
+package crawltime
+
+import (
+	"context"
+	"openproject-crawler/internal/fakeapi/fakeapitest"
+	"openproject-crawler/pkg/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetTimeEntries(t *testing.T) {
+	c := fakeapitest.Crawler(t, fakeapitest.New(t, nil), NewCrawlTimeEntries)
+	c.SetPageSize(2)
+	march := func(day int) time.Time { return time.Date(2024, 3, day, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []int
+	}{
+		{"all", Query{}, []int{1, 2, 3, 4, 5, 6}},
+		{"project", Query{ProjectIDs: []int{1}}, []int{1, 2, 3, 4}},
+		{"user", Query{UserIDs: []int{6}}, []int{1, 2, 5}},
+		{"work package", Query{WorkPackageIDs: []int{101, 201}}, []int{1, 2, 5}},
+		{"spent between", Query{ProjectIDs: []int{1}, From: march(1), To: march(8)}, []int{1, 2, 3}},
+		{"spent from", Query{From: march(8)}, []int{2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := c.GetTimeEntries(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, entry := range entries {
+				ids = append(ids, entry.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	c := fakeapitest.Crawler(t, fakeapitest.New(t, nil), NewCrawlTimeEntries)
+	c.SetPageSize(2)
+	timeEntries, err := c.GetTimeEntries(context.Background(), Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := NewEntries(timeEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Aggregate(entries)
+
+	if report.Entries != 6 || report.Hours != 11.75 {
+		t.Errorf("entries/hours = %d/%v, want 6/11.75", report.Entries, report.Hours)
+	}
+	checks := []struct {
+		name string
+		got  []Total
+		want []Total
+	}{
+		{"user", report.ByUser, []Total{{"Ada Admin", 7.5, 3}, {"Bob Builder", 4.25, 3}}},
+		{"work package", report.ByWorkPackage, []Total{
+			{"#101 Login fails", 3.5, 2}, {"#102 Write docs", 0.5, 1}, {"#201 Subproject setup", 0.75, 1},
+			{"#301 Rotate certificates", 3, 1}, {"(none)", 4, 1},
+		}},
+		{"activity", report.ByActivity, []Total{{"Development", 6.75, 4}, {"Management", 4, 1}, {"Testing", 1, 1}}},
+		{"week", report.ByWeek, []Total{{"2024-W06", 3, 1}, {"2024-W10", 4.75, 4}, {"2024-W11", 4, 1}}},
+	}
+	for _, check := range checks {
+		if !reflect.DeepEqual(check.got, check.want) {
+			t.Errorf("by %s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+}
+
+func TestAggregateRoundsTotalsOnly(t *testing.T) {
+	var timeEntries []model.TimeEntry
+	for id := 1; id <= 3; id++ {
+		timeEntries = append(timeEntries, model.TimeEntry{ID: id, Hours: "PT20M", SpentOn: "2024-03-04"})
+	}
+	entries, err := NewEntries(timeEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Aggregate(entries)
+	if report.Hours != 1 || report.ByWeek[0].Hours != 1 {
+		t.Errorf("hours = %v, by week %v, want 1", report.Hours, report.ByWeek[0].Hours)
+	}
+}
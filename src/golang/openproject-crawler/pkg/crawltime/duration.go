@@ -0,0 +1,60 @@
+package crawltime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration decodes the ISO 8601 durations OpenProject uses for hours,
+// e.g. "PT2H30M", "PT0.5H" or "P1DT4H". A day counts as 24 hours; years and
+// months are rejected because their length is not fixed.
+func ParseDuration(value string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(value, "P")
+	if !ok || rest == "" || rest == "T" {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", value)
+	}
+
+	var total float64
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			if inTime {
+				return 0, fmt.Errorf("invalid ISO 8601 duration %q", value)
+			}
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		end := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != ','
+		})
+		if end <= 0 {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", value)
+		}
+		number, err := strconv.ParseFloat(strings.Replace(rest[:end], ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", value)
+		}
+
+		var unit time.Duration
+		switch designator := rest[end]; {
+		case !inTime && designator == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && designator == 'D':
+			unit = 24 * time.Hour
+		case inTime && designator == 'H':
+			unit = time.Hour
+		case inTime && designator == 'M':
+			unit = time.Minute
+		case inTime && designator == 'S':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("unsupported unit %q in ISO 8601 duration %q", designator, value)
+		}
+		total += number * float64(unit)
+		rest = rest[end+1:]
+	}
+	return time.Duration(total).Round(time.Second), nil
+}
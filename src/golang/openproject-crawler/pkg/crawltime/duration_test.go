@@ -0,0 +1,42 @@
+package crawltime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"PT2H30M", 2*time.Hour + 30*time.Minute, false},
+		{"PT0.5H", 30 * time.Minute, false},
+		{"PT0,25H", 15 * time.Minute, false},
+		{"PT45M", 45 * time.Minute, false},
+		{"PT1H0M30S", time.Hour + 30*time.Second, false},
+		{"P1DT4H", 28 * time.Hour, false},
+		{"P1W", 7 * 24 * time.Hour, false},
+		{"PT0S", 0, false},
+		{"", 0, true},
+		{"P", 0, true},
+		{"PT", 0, true},
+		{"2H", 0, true},
+		{"P1M", 0, true},
+		{"PT1D", 0, true},
+		{"PTH", 0, true},
+		{"PT1HT2M", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDuration(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
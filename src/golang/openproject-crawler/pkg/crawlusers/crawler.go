@@ -0,0 +1,90 @@
+package crawlusers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/model"
+	"strconv"
+)
+
+const (
+	usersPath       = "/users"
+	groupsPath      = "/groups"
+	principalsPath  = "/principals"
+	membershipsPath = "/memberships"
+)
+
+// CrawlUsers fetches the users, groups and project memberships of an
+// instance. Listing users needs an admin account; GetUser works for every
+// user the account can see.
+type CrawlUsers struct {
+	*httpclient.APIClient
+}
+
+func NewCrawlUsers(apiURL, authToken string) (*CrawlUsers, error) {
+	apiClient, err := httpclient.NewAPIClient(apiURL, authToken)
+	if err != nil {
+		return nil, err
+	}
+	apiClient.SetURIPath(usersPath)
+	return &CrawlUsers{APIClient: apiClient}, nil
+}
+
+func (c *CrawlUsers) GetUsers(ctx context.Context) ([]model.User, error) {
+	users, _, err := httpclient.GetElements[model.User](ctx, c.APIClient, usersPath, nil)
+	return users, err
+}
+
+func (c *CrawlUsers) GetUser(ctx context.Context, id int) (model.User, error) {
+	user, err := httpclient.GetResource[model.User](ctx, c.APIClient, fmt.Sprintf("%s/%d", usersPath, id))
+	if err != nil {
+		return user, fmt.Errorf("failed to fetch user %d: %w", id, err)
+	}
+	return user, nil
+}
+
+func (c *CrawlUsers) GetGroups(ctx context.Context) ([]model.Group, error) {
+	groups, _, err := httpclient.GetElements[model.Group](ctx, c.APIClient, groupsPath, nil)
+	return groups, err
+}
+
+// GetPrincipals fetches users, groups and placeholder users, only the
+// members of projectIDs if any are given.
+func (c *CrawlUsers) GetPrincipals(ctx context.Context, projectIDs ...int) ([]model.Principal, error) {
+	params, err := idFilter("member", projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	principals, _, err := httpclient.GetElements[model.Principal](ctx, c.APIClient, principalsPath, params)
+	return principals, err
+}
+
+// GetMemberships fetches who has which roles in projectIDs, or in every
+// project if none are given.
+func (c *CrawlUsers) GetMemberships(ctx context.Context, projectIDs ...int) ([]model.Membership, error) {
+	params, err := idFilter("project", projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	memberships, _, err := httpclient.GetElements[model.Membership](ctx, c.APIClient, membershipsPath, params)
+	return memberships, err
+}
+
+func idFilter(name string, ids []int) (map[string]interface{}, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	encoded, err := json.Marshal([]map[string]interface{}{
+		{name: map[string]interface{}{"operator": "=", "values": values}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"filters": string(encoded)}, nil
+}
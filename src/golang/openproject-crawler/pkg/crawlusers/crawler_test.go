@@ -0,0 +1,107 @@
+package crawlusers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"openproject-crawler/internal/fakeapi/fakeapitest"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/model"
+	"reflect"
+	"testing"
+)
+
+func TestCrawlUsers(t *testing.T) {
+	srv := fakeapitest.New(t, nil)
+	c := fakeapitest.Crawler(t, srv, NewCrawlUsers)
+	c.SetPageSize(2)
+	ctx := context.Background()
+
+	users, err := c.GetUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 || users[0].Login != "ada" || !users[0].Admin || users[2].Status != "locked" {
+		t.Errorf("users = %+v", users)
+	}
+
+	groups, err := c.GetGroups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || !reflect.DeepEqual(groups[0].MemberIDs(), []int{5, 6}) {
+		t.Errorf("groups = %+v", groups)
+	}
+
+	principals, err := c.GetPrincipals(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, principal := range principals {
+		types = append(types, principal.Name+" "+principal.Type)
+	}
+	if want := []string{"Bob Builder User", "Carol Contractor User", "Future hire PlaceholderUser"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("members of project 3 = %v, want %v", types, want)
+	}
+
+	memberships, err := c.GetMemberships(ctx, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roles []string
+	for _, membership := range memberships {
+		for _, role := range membership.RoleNames() {
+			roles = append(roles, membership.PrincipalName()+": "+role+" in "+membership.ProjectName())
+		}
+	}
+	want := []string{"Ada Admin: Project admin in Demo project", "Developers: Member in Demo project", "Bob Builder: Member in Demo subproject"}
+	if !reflect.DeepEqual(roles, want) {
+		t.Errorf("roles = %v, want %v", roles, want)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	srv := fakeapitest.New(t, nil)
+	resolver := NewResolver(fakeapitest.Crawler(t, srv, NewCrawlUsers))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		user, err := resolver.ResolveUser(ctx, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Name != "Bob Builder" || user.Login != "bob" {
+			t.Errorf("user 6 = %+v", user)
+		}
+	}
+	if got := srv.RequestCount("/users/6"); got != 1 {
+		t.Errorf("requests for user 6 = %d, want 1", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := resolver.ResolveUser(ctx, 99)
+		var status *httpclient.StatusError
+		if !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+			t.Fatalf("err = %v, want 404", err)
+		}
+	}
+	if got := srv.RequestCount("/users/99"); got != 1 {
+		t.Errorf("requests for unknown user = %d, want 1", got)
+	}
+
+	resolver.Add(model.User{ID: 5, Name: "Ada Admin", Login: "ada"})
+	if _, err := resolver.ResolveUser(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.RequestCount("/users/5"); got != 0 {
+		t.Errorf("requests for added user = %d, want 0", got)
+	}
+	var ids []int
+	for _, user := range resolver.Users() {
+		ids = append(ids, user.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{5, 6}) {
+		t.Errorf("resolved users = %v", ids)
+	}
+}
@@ -0,0 +1,77 @@
+package crawlusers
+
+import (
+	"context"
+	"errors"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"sync"
+)
+
+// Resolver turns user IDs into users and caches them. Failed lookups are
+// cached too, so a deleted user or one the account may not see is only
+// requested once; lookups cut short by ctx are not cached.
+type Resolver struct {
+	users  *CrawlUsers
+	mu     sync.Mutex
+	cache  map[int]model.User
+	failed map[int]error
+}
+
+func NewResolver(users *CrawlUsers) *Resolver {
+	return &Resolver{
+		users:  users,
+		cache:  make(map[int]model.User),
+		failed: make(map[int]error),
+	}
+}
+
+// Add caches users that are already known, e.g. from GetUsers, so resolving
+// them needs no request.
+func (r *Resolver) Add(users ...model.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range users {
+		r.cache[user.ID] = user
+		delete(r.failed, user.ID)
+	}
+}
+
+func (r *Resolver) ResolveUser(ctx context.Context, id int) (model.User, error) {
+	r.mu.Lock()
+	if user, ok := r.cache[id]; ok {
+		r.mu.Unlock()
+		return user, nil
+	}
+	if err, ok := r.failed[id]; ok {
+		r.mu.Unlock()
+		return model.User{}, err
+	}
+	r.mu.Unlock()
+
+	user, err := r.users.GetUser(ctx, id)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			r.failed[id] = err
+		}
+		return model.User{}, err
+	}
+	r.cache[id] = user
+	return user, nil
+}
+
+// Users returns the users resolved so far, ordered by ID.
+func (r *Resolver) Users() []model.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := make([]model.User, 0, len(r.cache))
+	for _, user := range r.cache {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users
+}
@@ -0,0 +1,266 @@
+package crawlversions
+
+import (
+	"fmt"
+	"math"
+	"openproject-crawler/internal/core"
+	"openproject-crawler/pkg/crawltime"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Item is a work package with its activities, which a burndown replays to
+// find the work package's version, status and estimates on each day.
+type Item struct {
+	WorkPackage model.WorkPackage
+	Activities  []model.Activity
+}
+
+// Amount is an amount of work: work packages, story points and estimated
+// hours.
+type Amount struct {
+	Count  float64 `json:"count"`
+	Points float64 `json:"points"`
+	Hours  float64 `json:"hours"`
+}
+
+func (a Amount) add(b Amount) Amount {
+	return Amount{Count: a.Count + b.Count, Points: a.Points + b.Points, Hours: a.Hours + b.Hours}
+}
+
+func (a Amount) sub(b Amount) Amount {
+	return Amount{Count: a.Count - b.Count, Points: a.Points - b.Points, Hours: a.Hours - b.Hours}
+}
+
+func (a Amount) scale(factor float64) Amount {
+	return Amount{Count: round2(a.Count * factor), Points: round2(a.Points * factor), Hours: round2(a.Hours * factor)}
+}
+
+// Day is the state of a version at the end of a day. Scope is the work in the
+// version, Done the part of it in a closed status and Remaining the rest;
+// Ideal burns the first day's remaining work down evenly to the last day.
+// Scope and Done make a burnup chart, Remaining and Ideal a burndown chart.
+// Days still to come only have Ideal.
+type Day struct {
+	Date      string  `json:"date"`
+	Scope     *Amount `json:"scope,omitempty"`
+	Done      *Amount `json:"done,omitempty"`
+	Remaining *Amount `json:"remaining,omitempty"`
+	Ideal     Amount  `json:"ideal"`
+}
+
+type Burndown struct {
+	VersionID int    `json:"versionId"`
+	Version   string `json:"version"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	// WorkPackages lists every work package that was in the version on at
+	// least one of the days.
+	WorkPackages []int `json:"workPackages"`
+	Days         []Day `json:"days"`
+}
+
+// BuildBurndown reconstructs the daily scope of version from from to to,
+// both inclusive, defaulting to the version's start and end date. Days end
+// at midnight in location, or time.Local if it is nil. A work package counts on a day if it existed
+// and, according to its version change history, belonged to the version at
+// the end of that day; its status, story points and estimated time are
+// replayed the same way. Items should include the work packages moved out of
+// the version, or the scope they had before is missing. Days starting after
+// asOf get only the ideal line, which always spans the whole period; a zero
+// asOf fills in every day.
+func BuildBurndown(version model.Version, items []Item, closedStatuses []string, from, to, asOf time.Time, location *time.Location) (Burndown, error) {
+	if location == nil {
+		location = time.Local
+	}
+	var err error
+	if from.IsZero() {
+		if from, err = versionDate(version, "start", version.StartDate); err != nil {
+			return Burndown{}, err
+		}
+	}
+	if to.IsZero() {
+		if to, err = versionDate(version, "end", version.EndDate); err != nil {
+			return Burndown{}, err
+		}
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, location)
+	if to.Before(from) {
+		return Burndown{}, fmt.Errorf("burndown of version %q ends on %s, before it starts on %s", version.Name, to.Format(dateLayout), from.Format(dateLayout))
+	}
+
+	closed := make(map[string]bool, len(closedStatuses))
+	for _, name := range closedStatuses {
+		closed[name] = true
+	}
+	histories := make([]history, len(items))
+	for i, item := range items {
+		histories[i] = newHistory(item.Activities)
+	}
+
+	burndown := Burndown{
+		VersionID:    version.ID,
+		Version:      version.Name,
+		From:         from.Format(dateLayout),
+		To:           to.Format(dateLayout),
+		WorkPackages: []int{},
+		Days:         []Day{},
+	}
+	inScope := make(map[int]bool)
+	var start Amount
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		actual := asOf.IsZero() || day.Before(asOf)
+		var scope, done Amount
+		for i, item := range items {
+			wp := item.WorkPackage
+			h := histories[i]
+			if !wp.CreatedAt.Before(end) || h.valueAt("version", wp.Links.Version.Title, end) != version.Name {
+				continue
+			}
+			amount := Amount{
+				Count:  1,
+				Points: h.numberAt("storyPoints", formatPoints(wp.StoryPoints), end, parsePoints),
+				Hours:  h.numberAt("estimatedTime", wp.EstimatedTime, end, parseHours),
+			}
+			scope = scope.add(amount)
+			if closed[h.valueAt("status", wp.StatusName(), end)] {
+				done = done.add(amount)
+			}
+			if actual {
+				inScope[wp.ID] = true
+			}
+		}
+		scope, done = scope.scale(1), done.scale(1)
+		remaining := scope.sub(done).scale(1)
+		if day.Equal(from) {
+			// A version that has not started yet has its current work as the
+			// first day's, so it still gets an ideal line.
+			start = remaining
+		}
+		point := Day{Date: day.Format(dateLayout)}
+		if actual {
+			point.Scope, point.Done, point.Remaining = &scope, &done, &remaining
+		}
+		burndown.Days = append(burndown.Days, point)
+	}
+
+	// A one-day period has no time to burn down in and keeps the start.
+	last := len(burndown.Days) - 1
+	for i := range burndown.Days {
+		burndown.Days[i].Ideal = start
+		if last > 0 {
+			burndown.Days[i].Ideal = start.scale(float64(last-i) / float64(last))
+		}
+	}
+	for id := range inScope {
+		burndown.WorkPackages = append(burndown.WorkPackages, id)
+	}
+	sort.Ints(burndown.WorkPackages)
+	return burndown, nil
+}
+
+func versionDate(version model.Version, which, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("version %q has no %s date", version.Name, which)
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("version %q has an invalid %s date %q", version.Name, which, value)
+	}
+	return date, nil
+}
+
+type change struct {
+	at       time.Time
+	from, to string
+}
+
+// history holds the changes of each field of a work package, oldest first.
+type history map[string][]change
+
+func newHistory(activities []model.Activity) history {
+	sorted := make([]model.Activity, len(activities))
+	copy(sorted, activities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	h := make(history)
+	for _, activity := range sorted {
+		for _, c := range core.ParseDetails(activity.Details) {
+			h[c.Field] = append(h[c.Field], change{at: activity.CreatedAt, from: c.From, to: c.To})
+		}
+	}
+	return h
+}
+
+// valueAt is the value field had just before t: what the last earlier change
+// set it to or, before the first change, what that change changed it from.
+// A field that never changed always had current.
+func (h history) valueAt(field, current string, t time.Time) string {
+	changes := h[field]
+	i := sort.Search(len(changes), func(i int) bool {
+		return !changes[i].at.Before(t)
+	})
+	switch {
+	case i > 0:
+		return changes[i-1].to
+	case i < len(changes):
+		return changes[i].from
+	}
+	return current
+}
+
+// numberAt parses valueAt, falling back to the current value for history
+// entries that do not parse.
+func (h history) numberAt(field, current string, t time.Time, parse func(string) (float64, error)) float64 {
+	if value, err := parse(h.valueAt(field, current, t)); err == nil {
+		return value
+	}
+	value, _ := parse(current)
+	return value
+}
+
+func formatPoints(points float64) string {
+	if points == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+func parsePoints(value string) (float64, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// parseHours reads estimated time as the API returns it, e.g. "PT8H", or as
+// activities show it, e.g. "8.00 h" or "8h".
+func parseHours(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return 0, nil
+	case strings.HasPrefix(value, "P"):
+		duration, err := crawltime.ParseDuration(value)
+		return duration.Hours(), err
+	}
+	for _, unit := range []string{"hours", "hour", "h"} {
+		if number, ok := strings.CutSuffix(value, unit); ok {
+			value = strings.TrimSpace(number)
+			break
+		}
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
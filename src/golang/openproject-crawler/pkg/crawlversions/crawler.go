@@ -0,0 +1,96 @@
+package crawlversions
+
+import (
+	"context"
+	"fmt"
+	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const versionsPath = "/versions"
+
+type CrawlVersions struct {
+	*httpclient.APIClient
+}
+
+func NewCrawlVersions(apiURL, authToken string) (*CrawlVersions, error) {
+	apiClient, err := httpclient.NewAPIClient(apiURL, authToken)
+	if err != nil {
+		return nil, err
+	}
+	apiClient.SetURIPath(versionsPath)
+	return &CrawlVersions{APIClient: apiClient}, nil
+}
+
+// GetVersions fetches the versions available in projectIDs, including the
+// ones shared from other projects, or every visible version if none are
+// given. A version shared with several of the projects is returned once;
+// versions are ordered by ID.
+func (c *CrawlVersions) GetVersions(ctx context.Context, projectIDs ...int) ([]model.Version, error) {
+	if len(projectIDs) == 0 {
+		versions, _, err := httpclient.GetElements[model.Version](ctx, c.APIClient, versionsPath, nil)
+		return versions, err
+	}
+	byID := make(map[int]model.Version)
+	for _, projectID := range projectIDs {
+		customURI := fmt.Sprintf("/projects/%d/versions", projectID)
+		versions, _, err := httpclient.GetElements[model.Version](ctx, c.APIClient, customURI, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch versions of project %d: %w", projectID, err)
+		}
+		for _, version := range versions {
+			byID[version.ID] = version
+		}
+	}
+	versions := make([]model.Version, 0, len(byID))
+	for _, version := range byID {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID < versions[j].ID
+	})
+	return versions, nil
+}
+
+func (c *CrawlVersions) GetVersion(ctx context.Context, id int) (model.Version, error) {
+	version, err := httpclient.GetResource[model.Version](ctx, c.APIClient, fmt.Sprintf("%s/%d", versionsPath, id))
+	if err != nil {
+		return version, fmt.Errorf("failed to fetch version %d: %w", id, err)
+	}
+	return version, nil
+}
+
+// LookupVersion finds a version by ID, or by name (case-insensitive) among
+// the versions available in projectIDs.
+func (c *CrawlVersions) LookupVersion(ctx context.Context, ref string, projectIDs ...int) (model.Version, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return c.GetVersion(ctx, id)
+	}
+	if len(projectIDs) == 0 {
+		return model.Version{}, fmt.Errorf("version %q is not an ID; a project is needed to look it up by name", ref)
+	}
+	versions, err := c.GetVersions(ctx, projectIDs...)
+	if err != nil {
+		return model.Version{}, err
+	}
+	var matches []model.Version
+	for _, version := range versions {
+		if strings.EqualFold(version.Name, ref) {
+			matches = append(matches, version)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return model.Version{}, fmt.Errorf("version %q not found", ref)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, version := range matches {
+		ids[i] = fmt.Sprintf("%d (%s)", version.ID, version.ProjectName())
+	}
+	return model.Version{}, fmt.Errorf("version name %q is ambiguous; use one of the IDs %s", ref, strings.Join(ids, ", "))
+}
@@ -0,0 +1,226 @@
+package crawlversions
+
+import (
+	"context"
+	"encoding/json"
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/internal/fakeapi/fakeapitest"
+	"openproject-crawler/pkg/model"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetVersions(t *testing.T) {
+	fixtures := fakeapi.DefaultFixtures()
+	fixtures.Versions = append(fixtures.Versions, json.RawMessage(`{"_type": "Version", "id": 4, "name": "sprint 2", "status": "open", "sharing": "none",
+		"_links": {"self": {"href": "/api/v3/versions/4"}, "definingProject": {"href": "/api/v3/projects/3", "title": "Operations"}}}`))
+	c := fakeapitest.Crawler(t, fakeapitest.New(t, fixtures), NewCrawlVersions)
+	ctx := context.Background()
+
+	names := func(versions []model.Version) []string {
+		var names []string
+		for _, version := range versions {
+			names = append(names, version.Name)
+		}
+		return names
+	}
+	all, err := c.GetVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Sprint 1", "Sprint 2", "Ops backlog", "sprint 2"}; !reflect.DeepEqual(names(all), want) {
+		t.Errorf("versions = %v, want %v", names(all), want)
+	}
+	// The subproject sees the sprints its parent shares.
+	shared, err := c.GetVersions(ctx, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Sprint 1", "Sprint 2", "Ops backlog", "sprint 2"}; !reflect.DeepEqual(names(shared), want) {
+		t.Errorf("versions of projects 2 and 3 = %v, want %v", names(shared), want)
+	}
+
+	tests := []struct {
+		ref     string
+		project []int
+		want    int
+		err     string
+	}{
+		{"2", nil, 2, ""},
+		{"sprint 1", []int{2}, 1, ""},
+		{"Ops backlog", []int{1}, 0, "not found"},
+		{"Sprint 1", nil, 0, "a project is needed"},
+		{"9", nil, 0, "failed to fetch version 9"},
+		{"SPRINT 2", []int{1, 3}, 0, `version name "SPRINT 2" is ambiguous; use one of the IDs 2 (Demo project), 4 (Operations)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			version, err := c.LookupVersion(ctx, tt.ref, tt.project...)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version.ID != tt.want {
+				t.Errorf("version = %d, want %d", version.ID, tt.want)
+			}
+		})
+	}
+}
+
+func fixtureItems(t *testing.T) []Item {
+	t.Helper()
+	fixtures := fakeapi.DefaultFixtures()
+	var items []Item
+	for _, raw := range fixtures.WorkPackages {
+		var item Item
+		if err := json.Unmarshal(raw, &item.WorkPackage); err != nil {
+			t.Fatal(err)
+		}
+		for _, raw := range fixtures.Activities[item.WorkPackage.ID] {
+			var activity model.Activity
+			if err := json.Unmarshal(raw, &activity); err != nil {
+				t.Fatal(err)
+			}
+			item.Activities = append(item.Activities, activity)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestBuildBurndown(t *testing.T) {
+	sprint := model.Version{ID: 1, Name: "Sprint 1", StartDate: "2024-03-04", EndDate: "2024-03-15"}
+	burndown, err := BuildBurndown(sprint, fixtureItems(t), []string{"Closed", "Rejected"}, time.Time{}, time.Time{}, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burndown.From != "2024-03-04" || burndown.To != "2024-03-15" || len(burndown.Days) != 12 {
+		t.Fatalf("burndown covers %s to %s in %d days", burndown.From, burndown.To, len(burndown.Days))
+	}
+	// 103 joins on the 11th with 2 points, is re-estimated to 3 on the 12th
+	// and moves to Sprint 2 on the 13th.
+	if want := []int{101, 102, 103}; !reflect.DeepEqual(burndown.WorkPackages, want) {
+		t.Errorf("work packages = %v, want %v", burndown.WorkPackages, want)
+	}
+
+	tests := []struct {
+		date      string
+		scope     Amount
+		remaining Amount
+		ideal     float64
+	}{
+		{"2024-03-04", Amount{1, 3, 8}, Amount{1, 3, 8}, 3},
+		{"2024-03-05", Amount{2, 8, 24}, Amount{2, 8, 24}, 2.73},
+		{"2024-03-08", Amount{2, 8, 24}, Amount{1, 5, 16}, 1.91},
+		{"2024-03-11", Amount{3, 10, 28}, Amount{2, 7, 20}, 1.09},
+		{"2024-03-12", Amount{3, 11, 28}, Amount{2, 8, 20}, 0.82},
+		{"2024-03-13", Amount{2, 8, 24}, Amount{1, 5, 16}, 0.55},
+		{"2024-03-15", Amount{2, 8, 24}, Amount{1, 5, 16}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			var day *Day
+			for i := range burndown.Days {
+				if burndown.Days[i].Date == tt.date {
+					day = &burndown.Days[i]
+				}
+			}
+			if day == nil {
+				t.Fatalf("no day %s", tt.date)
+			}
+			if day.Scope == nil || *day.Scope != tt.scope || *day.Remaining != tt.remaining {
+				t.Fatalf("scope = %+v, remaining = %+v, want %+v and %+v", day.Scope, day.Remaining, tt.scope, tt.remaining)
+			}
+			if done := day.Scope.sub(*day.Remaining); *day.Done != done {
+				t.Errorf("done = %+v, want %+v", *day.Done, done)
+			}
+			if day.Ideal.Points != tt.ideal {
+				t.Errorf("ideal points = %v, want %v", day.Ideal.Points, tt.ideal)
+			}
+		})
+	}
+}
+
+func TestBuildBurndownInProgress(t *testing.T) {
+	sprint := model.Version{ID: 1, Name: "Sprint 1", StartDate: "2024-03-04", EndDate: "2024-03-15"}
+	asOf := time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)
+	burndown, err := BuildBurndown(sprint, fixtureItems(t), []string{"Closed"}, time.Time{}, time.Time{}, asOf, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(burndown.Days) != 12 {
+		t.Fatalf("days = %d, want 12", len(burndown.Days))
+	}
+	if want := []int{101, 102}; !reflect.DeepEqual(burndown.WorkPackages, want) {
+		t.Errorf("work packages = %v, want %v", burndown.WorkPackages, want)
+	}
+	for _, day := range burndown.Days {
+		if future := day.Date > "2024-03-08"; future != (day.Remaining == nil) {
+			t.Errorf("%s has remaining %+v", day.Date, day.Remaining)
+		}
+	}
+	// The ideal line still ends on the version's end date.
+	if ideal := burndown.Days[1].Ideal.Points; ideal != 2.73 {
+		t.Errorf("ideal points on the second day = %v, want 2.73", ideal)
+	}
+	if last := burndown.Days[11]; last.Ideal.Points != 0 || last.Scope != nil {
+		t.Errorf("last day = %+v", last)
+	}
+}
+
+func TestBuildBurndownOneDay(t *testing.T) {
+	sprint := model.Version{ID: 1, Name: "Sprint 1", StartDate: "2024-03-04", EndDate: "2024-03-15"}
+	day := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	burndown, err := BuildBurndown(sprint, fixtureItems(t), []string{"Closed"}, day, day, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(burndown.Days) != 1 {
+		t.Fatalf("days = %d, want 1", len(burndown.Days))
+	}
+	if got := burndown.Days[0]; got.Remaining == nil || got.Ideal != *got.Remaining || got.Ideal.Points != 8 {
+		t.Errorf("day = %+v, want the ideal at the remaining 8 points", got)
+	}
+}
+
+func TestBuildBurndownPeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  model.Version
+		from, to string
+		err      string
+	}{
+		{"no dates", model.Version{Name: "Backlog"}, "", "", `version "Backlog" has no start date`},
+		{"given dates", model.Version{Name: "Backlog"}, "2024-03-01", "2024-03-02", ""},
+		{"open end", model.Version{Name: "Sprint", StartDate: "2024-03-04"}, "", "", `version "Sprint" has no end date`},
+		{"reversed", model.Version{Name: "Sprint", StartDate: "2024-03-04"}, "", "2024-03-01", "ends on 2024-03-01, before it starts on 2024-03-04"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from, to time.Time
+			if tt.from != "" {
+				from, _ = time.Parse(dateLayout, tt.from)
+			}
+			if tt.to != "" {
+				to, _ = time.Parse(dateLayout, tt.to)
+			}
+			_, err := BuildBurndown(tt.version, nil, nil, from, to, time.Time{}, time.UTC)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,17 +1,21 @@
 package crawlwp
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"openproject-crawler/internal/httpclient"
+	"openproject-crawler/pkg/model"
 	"sync"
 )
 
+const unsetAttribute = "(none)"
+
 type CrawlWorkPackages struct {
 	*httpclient.APIClient
 	projectName string
 	params      map[string]interface{}
-	data        []map[string]interface{}
+	data        []model.WorkPackage
 	mu          sync.Mutex
 	wg          sync.WaitGroup
 }
@@ -25,7 +29,7 @@ func NewCrawlWorkPackages(apiURL, authToken, projectName string) (*CrawlWorkPack
 		APIClient:   apiClient,
 		projectName: projectName,
 		params:      make(map[string]interface{}),
-		data:        []map[string]interface{}{},
+		data:        []model.WorkPackage{},
 	}
 	apiClient.SetURIPath(c.getUriPath())
 	return c, nil
@@ -56,100 +60,110 @@ func (c *CrawlWorkPackages) GetParams() map[string]interface{} {
 	return c.params
 }
 
-func (c *CrawlWorkPackages) fetchData(ch chan<- map[string]interface{}) {
-	defer c.wg.Done()
-	params := c.params
-	response, err := c.GetRequest("", params)
-	if err != nil {
-		ch <- nil
-		fmt.Printf("failed to get data: %v\n", err)
-		return
-	}
-	var result map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		ch <- nil
-		fmt.Printf("failed to parse JSON response: %v\n", err)
-		return
+// fetchData sends the work packages on ch, including the pages fetched
+// before a failure, and reports a failure or truncation on errCh.
+func (c *CrawlWorkPackages) fetchData(ctx context.Context, ch chan<- model.WorkPackage, errCh chan<- error) {
+	workPackages, collection, err := httpclient.GetElements[model.WorkPackage](ctx, c.APIClient, "", c.params)
+	for _, data := range workPackages {
+		ch <- data
 	}
-	elements, ok := result["_embedded"].(map[string]interface{})["elements"].([]interface{})
-	if !ok {
-		ch <- nil
-		fmt.Printf("unexpected JSON structure\n")
-		return
-	}
-	for _, element := range elements {
-		if data, ok := element.(map[string]interface{}); ok {
-			ch <- data
-		}
+	switch {
+	case err != nil && errors.Is(err, ctx.Err()):
+		errCh <- fmt.Errorf("work package crawl interrupted: %w", err)
+	case err != nil:
+		errCh <- fmt.Errorf("failed to fetch work packages: %w", err)
+	case collection.Truncated:
+		errCh <- fmt.Errorf("work packages truncated after %d pages (%d of %d)", collection.Pages, len(workPackages), collection.Total)
 	}
 }
 
-func (c *CrawlWorkPackages) FetchDataAsync() error {
-	ch := make(chan map[string]interface{})
+func (c *CrawlWorkPackages) FetchDataAsync(ctx context.Context) error {
+	ch := make(chan model.WorkPackage)
+	errCh := make(chan error, 1)
+	// Collection pages follow each other's next links, so they are fetched
+	// one by one rather than through a worker pool.
 	c.wg.Add(1)
-	go c.fetchData(ch)
+	go func() {
+		defer c.wg.Done()
+		c.fetchData(ctx, ch, errCh)
+	}()
 
 	go func() {
 		c.wg.Wait()
 		close(ch)
 	}()
 
-	var data []map[string]interface{}
-	for marshaledData := range ch {
-		if marshaledData == nil {
-			continue
-		}
-		data = append(data, marshaledData)
+	var data []model.WorkPackage
+	for workPackage := range ch {
+		data = append(data, workPackage)
 	}
 
 	c.mu.Lock()
 	c.data = data
 	c.mu.Unlock()
 
-	return nil
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
+	}
 }
 
-func (c *CrawlWorkPackages) GetTasksID() ([]int, error) {
-	if err := c.FetchDataAsync(); err != nil {
-		return nil, err
-	}
+func (c *CrawlWorkPackages) GetWorkPackages(ctx context.Context) ([]model.WorkPackage, error) {
+	fetchErr := c.FetchDataAsync(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	workPackages := make([]model.WorkPackage, len(c.data))
+	copy(workPackages, c.data)
+	return workPackages, fetchErr
+}
+
+func (c *CrawlWorkPackages) GetWorkPackagesData() []model.WorkPackage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	workPackages := make([]model.WorkPackage, len(c.data))
+	copy(workPackages, c.data)
+	return workPackages
+}
+
+func (c *CrawlWorkPackages) GetTasksID(ctx context.Context) ([]int, error) {
+	fetchErr := c.FetchDataAsync(ctx)
 
 	var ids []int
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	for _, val := range c.data {
-		if id, ok := val["id"].(float64); ok {
-			ids = append(ids, int(id))
-		}
+		ids = append(ids, val.ID)
 	}
-	return ids, nil
+	return ids, fetchErr
 }
 
-func (c *CrawlWorkPackages) GetTasksAttr() ([]map[string]interface{}, error) {
-	if err := c.FetchDataAsync(); err != nil {
+func (c *CrawlWorkPackages) GetTasksAttr(ctx context.Context) ([]model.TaskAttr, error) {
+	if err := c.FetchDataAsync(ctx); err != nil {
 		return nil, err
 	}
 
-	var result []map[string]interface{}
+	var result []model.TaskAttr
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	for _, val := range c.data {
-		valChild := val["_links"].(map[string]interface{})
-		result = append(result, map[string]interface{}{
-			"taskName": val["subject"],
-			"taskAttr": map[string]interface{}{
-				"id":       val["id"],
-				"type":     valChild["type"].(map[string]interface{})["title"].(string),
-				"priority": valChild["priority"].(map[string]interface{})["title"].(string),
-				"status":   valChild["status"].(map[string]interface{})["title"].(string),
+		result = append(result, model.TaskAttr{
+			TaskName: val.Subject,
+			TaskAttr: model.TaskAttributes{
+				ID:       val.ID,
+				Type:     val.TypeName(),
+				Priority: val.PriorityName(),
+				Status:   val.StatusName(),
 			},
 		})
 	}
 	return result, nil
 }
 
-func (c *CrawlWorkPackages) sumTasks(attribute string) (map[string]int, error) {
-	if err := c.FetchDataAsync(); err != nil {
+func (c *CrawlWorkPackages) sumTasks(ctx context.Context, attribute func(model.WorkPackage) string) (map[string]int, error) {
+	if err := c.FetchDataAsync(ctx); err != nil {
 		return nil, err
 	}
 
@@ -157,21 +171,23 @@ func (c *CrawlWorkPackages) sumTasks(attribute string) (map[string]int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	for _, val := range c.data {
-		valChild := val["_links"].(map[string]interface{})
-		attrValue := valChild[attribute].(map[string]interface{})["title"].(string)
+		attrValue := attribute(val)
+		if attrValue == "" {
+			attrValue = unsetAttribute
+		}
 		counts[attrValue]++
 	}
 	return counts, nil
 }
 
-func (c *CrawlWorkPackages) SumTasksType() (map[string]int, error) {
-	return c.sumTasks("type")
+func (c *CrawlWorkPackages) SumTasksType(ctx context.Context) (map[string]int, error) {
+	return c.sumTasks(ctx, model.WorkPackage.TypeName)
 }
 
-func (c *CrawlWorkPackages) SumTasksPriority() (map[string]int, error) {
-	return c.sumTasks("priority")
+func (c *CrawlWorkPackages) SumTasksPriority(ctx context.Context) (map[string]int, error) {
+	return c.sumTasks(ctx, model.WorkPackage.PriorityName)
 }
 
-func (c *CrawlWorkPackages) SumTasksStatus() (map[string]int, error) {
-	return c.sumTasks("status")
+func (c *CrawlWorkPackages) SumTasksStatus(ctx context.Context) (map[string]int, error) {
+	return c.sumTasks(ctx, model.WorkPackage.StatusName)
 }
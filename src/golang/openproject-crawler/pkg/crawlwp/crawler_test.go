@@ -0,0 +1,107 @@
+package crawlwp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/internal/httpclient"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestCrawler(t *testing.T, projectName string, params map[string]interface{}) *CrawlWorkPackages {
+	t.Helper()
+	c, _ := newTestCrawlerWithServer(t, projectName, params)
+	return c
+}
+
+func newTestCrawlerWithServer(t *testing.T, projectName string, params map[string]interface{}) (*CrawlWorkPackages, *fakeapi.Server) {
+	t.Helper()
+	srv := fakeapi.New(nil)
+	t.Cleanup(srv.Close)
+	c, err := NewCrawlWorkPackages(srv.APIURL(), "", projectName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRateLimiter(nil)
+	if err := c.SetRetryPolicy(httpclient.RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	c.SetPageSize(2)
+	c.SetParams(params)
+	return c, srv
+}
+
+func TestGetTasksID(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		params  map[string]interface{}
+		want    []int
+	}{
+		{"project, all statuses", "demo", map[string]interface{}{"filters": "[]"}, []int{101, 102, 103}},
+		{"project, default filters", "demo", map[string]interface{}{}, []int{102, 103}},
+		{"every project", "", map[string]interface{}{"filters": "[]"}, []int{101, 102, 103, 201, 301}},
+		{"closed", "", map[string]interface{}{"filters": `[{"status":{"operator":"c","values":[]}}]`}, []int{101, 201}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCrawler(t, tt.project, tt.params)
+			ids, err := c.GetTasksID(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumTasks(t *testing.T) {
+	c := newTestCrawler(t, "", map[string]interface{}{"filters": "[]"})
+	ctx := context.Background()
+
+	statuses, err := c.SumTasksStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"New": 2, "In progress": 1, "Closed": 2}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("SumTasksStatus = %v, want %v", statuses, want)
+	}
+
+	types, err := c.SumTasksType(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"Task": 3, "Bug": 1, "Feature": 1}; !reflect.DeepEqual(types, want) {
+		t.Errorf("SumTasksType = %v, want %v", types, want)
+	}
+}
+
+func TestGetTasksIDError(t *testing.T) {
+	c, srv := newTestCrawlerWithServer(t, "", map[string]interface{}{"filters": "[]"})
+	srv.Inject(fakeapi.Fault{Path: "/work_packages", Status: http.StatusInternalServerError})
+	ids, err := c.GetTasksID(context.Background())
+	var status *httpclient.StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("err = %v, want a 500 status error", err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("ids = %v, want none", ids)
+	}
+}
+
+func TestGetTasksIDTruncated(t *testing.T) {
+	c := newTestCrawler(t, "", map[string]interface{}{"filters": "[]"})
+	c.SetMaxPages(1)
+	ids, err := c.GetTasksID(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "truncated after 1 pages (2 of 5)") {
+		t.Fatalf("err = %v, want a truncation error", err)
+	}
+	if want := []int{101, 102}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+}
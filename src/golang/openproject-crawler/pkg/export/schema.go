@@ -0,0 +1,220 @@
+package export
+
+import (
+	"math"
+	"openproject-crawler/pkg/crawltime"
+	"openproject-crawler/pkg/crawlversions"
+	"openproject-crawler/pkg/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskRecord is the taskInfo of a merged task together with its name.
+type TaskRecord struct {
+	TaskName string `json:"taskName"`
+	model.TaskInfo
+}
+
+// ActivityRow is one field change of a task activity. Activities without
+// parsed changes, such as comments, produce a single row carrying the raw
+// action text instead.
+type ActivityRow struct {
+	TaskID      int              `json:"taskId"`
+	TaskName    string           `json:"taskName"`
+	ActivityID  int              `json:"activityId"`
+	DateTime    string           `json:"dateTime"`
+	Author      string           `json:"author,omitempty"`
+	AuthorLogin string           `json:"authorLogin,omitempty"`
+	Field       string           `json:"field,omitempty"`
+	Label       string           `json:"label,omitempty"`
+	From        string           `json:"from,omitempty"`
+	To          string           `json:"to,omitempty"`
+	Kind        model.ChangeKind `json:"kind,omitempty"`
+	Custom      bool             `json:"custom,omitempty"`
+	Action      string           `json:"action,omitempty"`
+}
+
+func TaskRecords(tasks []model.Task) []TaskRecord {
+	records := make([]TaskRecord, len(tasks))
+	for i, task := range tasks {
+		records[i] = TaskRecord{TaskName: task.TaskName, TaskInfo: task.TaskInfo}
+	}
+	return records
+}
+
+func FlattenActivities(tasks []model.Task) []ActivityRow {
+	var rows []ActivityRow
+	for _, task := range tasks {
+		for _, activity := range task.TaskActivities {
+			row := ActivityRow{
+				TaskID:     task.TaskInfo.ID,
+				TaskName:   task.TaskName,
+				ActivityID: activity.ID,
+				DateTime:   activity.DateTime,
+			}
+			if activity.Author != nil {
+				row.Author = activity.Author.Name
+				row.AuthorLogin = activity.Author.Login
+			}
+			if len(activity.Changes) == 0 {
+				row.Action = strings.Join(activity.Action, "; ")
+				rows = append(rows, row)
+				continue
+			}
+			for _, change := range activity.Changes {
+				changeRow := row
+				changeRow.Field = change.Field
+				changeRow.Label = change.Label
+				changeRow.From = change.From
+				changeRow.To = change.To
+				changeRow.Kind = change.Kind
+				changeRow.Custom = change.Custom
+				rows = append(rows, changeRow)
+			}
+		}
+	}
+	return rows
+}
+
+var WorkPackageColumns = Schema[model.WorkPackage]{
+	{"id", func(wp model.WorkPackage) string { return strconv.Itoa(wp.ID) }},
+	{"subject", func(wp model.WorkPackage) string { return wp.Subject }},
+	{"project", func(wp model.WorkPackage) string { return wp.ProjectName() }},
+	{"type", func(wp model.WorkPackage) string { return wp.TypeName() }},
+	{"status", func(wp model.WorkPackage) string { return wp.StatusName() }},
+	{"priority", func(wp model.WorkPackage) string { return wp.PriorityName() }},
+	{"author", func(wp model.WorkPackage) string { return wp.Links.Author.Title }},
+	{"assignee", func(wp model.WorkPackage) string { return wp.Links.Assignee.Title }},
+	{"responsible", func(wp model.WorkPackage) string { return wp.Links.Responsible.Title }},
+	{"version", func(wp model.WorkPackage) string { return wp.Links.Version.Title }},
+	{"category", func(wp model.WorkPackage) string { return wp.Links.Category.Title }},
+	{"parentId", func(wp model.WorkPackage) string { return linkID(wp.Links.Parent) }},
+	{"startDate", func(wp model.WorkPackage) string { return wp.StartDate }},
+	{"dueDate", func(wp model.WorkPackage) string { return wp.DueDate }},
+	{"estimatedTime", func(wp model.WorkPackage) string { return wp.EstimatedTime }},
+	{"remainingTime", func(wp model.WorkPackage) string { return wp.RemainingTime }},
+	{"storyPoints", func(wp model.WorkPackage) string { return strconv.FormatFloat(wp.StoryPoints, 'f', -1, 64) }},
+	{"spentTime", func(wp model.WorkPackage) string { return wp.SpentTime }},
+	{"percentageDone", func(wp model.WorkPackage) string { return strconv.Itoa(wp.PercentageDone) }},
+	{"createdAt", func(wp model.WorkPackage) string { return formatTime(wp.CreatedAt) }},
+	{"updatedAt", func(wp model.WorkPackage) string { return formatTime(wp.UpdatedAt) }},
+}
+
+var TaskColumns = Schema[TaskRecord]{
+	{"id", func(r TaskRecord) string { return strconv.Itoa(r.ID) }},
+	{"taskName", func(r TaskRecord) string { return r.TaskName }},
+	{"project", func(r TaskRecord) string { return r.Project }},
+	{"type", func(r TaskRecord) string { return r.Type }},
+	{"priority", func(r TaskRecord) string { return r.Priority }},
+	{"createdDate", func(r TaskRecord) string { return r.CreatedDate }},
+	{"closedDate", func(r TaskRecord) string { return r.ClosedDate }},
+	{"closedStatus", func(r TaskRecord) string { return r.ClosedStatus }},
+	{"closeRule", func(r TaskRecord) string { return r.CloseRule }},
+	{"reopenCount", func(r TaskRecord) string { return strconv.Itoa(r.ReopenCount) }},
+	{"duration", func(r TaskRecord) string { return r.Duration }},
+	durationColumn("calendarDays", func(d model.Durations) float64 { return d.CalendarDays }),
+	durationColumn("calendarHours", func(d model.Durations) float64 { return d.CalendarHours }),
+	durationColumn("businessDays", func(d model.Durations) float64 { return d.BusinessDays }),
+	durationColumn("businessHours", func(d model.Durations) float64 { return d.BusinessHours }),
+	{"retries", func(r TaskRecord) string { return strconv.Itoa(r.Retries) }},
+}
+
+var ActivityColumns = Schema[ActivityRow]{
+	{"taskId", func(r ActivityRow) string { return strconv.Itoa(r.TaskID) }},
+	{"taskName", func(r ActivityRow) string { return r.TaskName }},
+	{"activityId", func(r ActivityRow) string { return strconv.Itoa(r.ActivityID) }},
+	{"dateTime", func(r ActivityRow) string { return r.DateTime }},
+	{"author", func(r ActivityRow) string { return r.Author }},
+	{"authorLogin", func(r ActivityRow) string { return r.AuthorLogin }},
+	{"field", func(r ActivityRow) string { return r.Field }},
+	{"label", func(r ActivityRow) string { return r.Label }},
+	{"from", func(r ActivityRow) string { return r.From }},
+	{"to", func(r ActivityRow) string { return r.To }},
+	{"kind", func(r ActivityRow) string { return string(r.Kind) }},
+	{"custom", func(r ActivityRow) string { return strconv.FormatBool(r.Custom) }},
+	{"action", func(r ActivityRow) string { return r.Action }},
+}
+
+var TimeEntryColumns = Schema[crawltime.Entry]{
+	{"id", func(e crawltime.Entry) string { return strconv.Itoa(e.ID) }},
+	{"spentOn", func(e crawltime.Entry) string { return e.SpentOn }},
+	{"week", func(e crawltime.Entry) string { return e.Week }},
+	{"hours", func(e crawltime.Entry) string { return strconv.FormatFloat(math.Round(e.Hours*100)/100, 'f', -1, 64) }},
+	{"user", func(e crawltime.Entry) string { return e.User }},
+	{"project", func(e crawltime.Entry) string { return e.Project }},
+	{"workPackageId", func(e crawltime.Entry) string { return optionalID(e.WorkPackageID) }},
+	{"workPackage", func(e crawltime.Entry) string { return e.WorkPackage }},
+	{"activity", func(e crawltime.Entry) string { return e.Activity }},
+	{"comment", func(e crawltime.Entry) string { return e.Comment }},
+}
+
+// BurndownColumns has the date and a count, points and hours column for each
+// series, e.g. remainingCount, remainingPoints and remainingHours. Days still
+// to come only fill in the ideal columns.
+var BurndownColumns = burndownColumns()
+
+func burndownColumns() Schema[crawlversions.Day] {
+	series := []struct {
+		name   string
+		amount func(d crawlversions.Day) *crawlversions.Amount
+	}{
+		{"scope", func(d crawlversions.Day) *crawlversions.Amount { return d.Scope }},
+		{"done", func(d crawlversions.Day) *crawlversions.Amount { return d.Done }},
+		{"remaining", func(d crawlversions.Day) *crawlversions.Amount { return d.Remaining }},
+		{"ideal", func(d crawlversions.Day) *crawlversions.Amount { return &d.Ideal }},
+	}
+	units := []struct {
+		name  string
+		value func(a crawlversions.Amount) float64
+	}{
+		{"Count", func(a crawlversions.Amount) float64 { return a.Count }},
+		{"Points", func(a crawlversions.Amount) float64 { return a.Points }},
+		{"Hours", func(a crawlversions.Amount) float64 { return a.Hours }},
+	}
+	schema := Schema[crawlversions.Day]{
+		{"date", func(d crawlversions.Day) string { return d.Date }},
+	}
+	for _, s := range series {
+		for _, u := range units {
+			amount, value := s.amount, u.value
+			schema = append(schema, Column[crawlversions.Day]{s.name + u.name, func(d crawlversions.Day) string {
+				if a := amount(d); a != nil {
+					return strconv.FormatFloat(value(*a), 'f', -1, 64)
+				}
+				return ""
+			}})
+		}
+	}
+	return schema
+}
+
+func optionalID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+func linkID(link model.Link) string {
+	if id, ok := link.ID(); ok {
+		return strconv.Itoa(id)
+	}
+	return ""
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func durationColumn(name string, value func(model.Durations) float64) Column[TaskRecord] {
+	return Column[TaskRecord]{name, func(r TaskRecord) string {
+		if r.Durations == nil {
+			return ""
+		}
+		return strconv.FormatFloat(value(*r.Durations), 'f', -1, 64)
+	}}
+}
@@ -0,0 +1,137 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Column renders one CSV cell of a row.
+type Column[T any] struct {
+	Name  string
+	Value func(row T) string
+}
+
+// Schema lists every column available for a row type, in default order.
+type Schema[T any] []Column[T]
+
+func (s Schema[T]) Names() []string {
+	names := make([]string, len(s))
+	for i, column := range s {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// Select returns the named columns in the given order, or every column when
+// names is empty.
+func (s Schema[T]) Select(names []string) (Schema[T], error) {
+	if len(names) == 0 {
+		return s, nil
+	}
+	selected := make(Schema[T], 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, column := range s {
+			if column.Name == name {
+				selected = append(selected, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q; available columns: %s", name, strings.Join(s.Names(), ", "))
+		}
+	}
+	return selected, nil
+}
+
+// CSVWriter streams rows as CSV, writing the header before the first row.
+type CSVWriter[T any] struct {
+	w       *csv.Writer
+	columns Schema[T]
+	header  bool
+}
+
+func NewCSVWriter[T any](w io.Writer, schema Schema[T], columns []string) (*CSVWriter[T], error) {
+	selected, err := schema.Select(columns)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVWriter[T]{w: csv.NewWriter(w), columns: selected}, nil
+}
+
+func (cw *CSVWriter[T]) WriteHeader() error {
+	if cw.header {
+		return nil
+	}
+	cw.header = true
+	if err := cw.w.Write(cw.columns.Names()); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return nil
+}
+
+func (cw *CSVWriter[T]) Write(row T) error {
+	if err := cw.WriteHeader(); err != nil {
+		return err
+	}
+	record := make([]string, len(cw.columns))
+	for i, column := range cw.columns {
+		record[i] = column.Value(row)
+	}
+	if err := cw.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
+	}
+	return nil
+}
+
+// Flush writes buffered rows, and the header if no row was written.
+func (cw *CSVWriter[T]) Flush() error {
+	if err := cw.WriteHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// JSONLinesWriter streams one JSON document per line.
+type JSONLinesWriter[T any] struct {
+	encoder *json.Encoder
+}
+
+func NewJSONLinesWriter[T any](w io.Writer) *JSONLinesWriter[T] {
+	return &JSONLinesWriter[T]{encoder: json.NewEncoder(w)}
+}
+
+func (jw *JSONLinesWriter[T]) Write(row T) error {
+	if err := jw.encoder.Encode(row); err != nil {
+		return fmt.Errorf("failed to write JSON line: %w", err)
+	}
+	return nil
+}
+
+func WriteCSV[T any](w io.Writer, schema Schema[T], columns []string, rows []T) error {
+	cw, err := NewCSVWriter(w, schema, columns)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return cw.Flush()
+}
+
+func WriteJSONLines[T any](w io.Writer, rows []T) error {
+	jw := NewJSONLinesWriter[T](w)
+	for _, row := range rows {
+		if err := jw.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"openproject-crawler/pkg/crawlversions"
+	"openproject-crawler/pkg/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testTasks = []model.Task{
+	{
+		TaskName: "Login fails",
+		TaskInfo: model.TaskInfo{
+			ID: 101, Project: "Demo project", Type: "Bug", CreatedDate: "2024-03-04 09:00:00",
+			ClosedDate: "2024-03-08 15:00:00", ClosedStatus: "Closed", Duration: "4.25 days",
+			Durations: &model.Durations{CalendarDays: 4.25, CalendarHours: 102, BusinessDays: 4.75, BusinessHours: 38},
+		},
+		TaskActivities: []model.TaskActivity{
+			{
+				ID: 1002, DateTime: "2024-03-05 10:00:00", Author: &model.Author{ID: 2, Name: "Bob Builder", Login: "bob"},
+				Action: []string{"Status changed from New to In progress", "Assignee set to Bob Builder"},
+				Changes: []model.FieldChange{
+					{Field: "status", Label: "Status", From: "New", To: "In progress", Kind: model.ChangeChanged},
+					{Field: "assignee", Label: "Assignee", To: "Bob Builder", Kind: model.ChangeSet},
+				},
+			},
+			{ID: 1004, DateTime: "2024-03-08 15:05:00", Action: []string{"Fixed in 1.2.", "Thanks"}},
+		},
+	},
+	{TaskName: "Write docs, part 1", TaskInfo: model.TaskInfo{ID: 102, Type: "Task", CreatedDate: "2024-03-05 09:00:00"}},
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		want    []string
+		err     string
+	}{
+		{"all", nil, TaskColumns.Names(), ""},
+		{"given order", []string{"closedDate", "id", "taskName"}, []string{"closedDate", "id", "taskName"}, ""},
+		{"repeated", []string{"id", "id"}, []string{"id", "id"}, ""},
+		{"unknown", []string{"id", "name"}, nil, `unknown column "name"; available columns: id, taskName, project`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := TaskColumns.Select(tt.columns)
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := selected.Names(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("columns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		want  string
+	}{
+		{
+			"tasks",
+			func(b *bytes.Buffer) error {
+				return WriteCSV(b, TaskColumns, []string{"id", "taskName", "closedDate", "calendarDays", "businessHours"}, TaskRecords(testTasks))
+			},
+			"id,taskName,closedDate,calendarDays,businessHours\n" +
+				"101,Login fails,2024-03-08 15:00:00,4.25,38\n" +
+				"102,\"Write docs, part 1\",,,\n",
+		},
+		{
+			"no tasks",
+			func(b *bytes.Buffer) error {
+				return WriteCSV(b, TaskColumns, []string{"id", "taskName"}, nil)
+			},
+			"id,taskName\n",
+		},
+		{
+			"activities",
+			func(b *bytes.Buffer) error {
+				return WriteCSV(b, ActivityColumns, []string{"taskId", "activityId", "author", "field", "from", "to", "kind", "action"}, FlattenActivities(testTasks))
+			},
+			"taskId,activityId,author,field,from,to,kind,action\n" +
+				"101,1002,Bob Builder,status,New,In progress,changed,\n" +
+				"101,1002,Bob Builder,assignee,,Bob Builder,set,\n" +
+				"101,1004,,,,,,Fixed in 1.2.; Thanks\n",
+		},
+		{
+			"burndown",
+			func(b *bytes.Buffer) error {
+				days := []crawlversions.Day{
+					{Date: "2024-03-04", Scope: &crawlversions.Amount{Count: 2}, Done: &crawlversions.Amount{}, Remaining: &crawlversions.Amount{Count: 2}, Ideal: crawlversions.Amount{Count: 2}},
+					{Date: "2024-03-05", Ideal: crawlversions.Amount{Count: 1}},
+				}
+				return WriteCSV(b, BurndownColumns, []string{"date", "remainingCount", "idealCount"}, days)
+			},
+			"date,remainingCount,idealCount\n" +
+				"2024-03-04,2,2\n" +
+				"2024-03-05,,1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.write(&b); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("CSV =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if err := WriteCSV(&bytes.Buffer{}, ActivityColumns, []string{"taskID"}, nil); err == nil {
+		t.Error("unknown column accepted")
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJSONLines(&b, FlattenActivities(testTasks)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %d, want 3:\n%s", len(lines), b.String())
+	}
+	var row ActivityRow
+	if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+		t.Fatal(err)
+	}
+	if row.TaskID != 101 || row.Field != "status" || row.AuthorLogin != "bob" {
+		t.Errorf("first row = %+v", row)
+	}
+
+	b.Reset()
+	if err := WriteJSONLines(&b, TaskRecords(testTasks)); err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d, want 2:\n%s", len(lines), b.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	// The task info is flattened next to the name, and unset durations are left out.
+	if record["taskName"] != "Write docs, part 1" || record["id"] != float64(102) || record["durations"] != nil {
+		t.Errorf("second record = %v", record)
+	}
+
+	b.Reset()
+	if err := WriteJSONLines[TaskRecord](&b, nil); err != nil || b.Len() != 0 {
+		t.Errorf("no rows wrote %q, %v", b.String(), err)
+	}
+}
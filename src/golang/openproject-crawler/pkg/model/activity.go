@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	ActivityType        = "Activity"
+	ActivityCommentType = "Activity::Comment"
+)
+
+type Activity struct {
+	Type      string        `json:"_type"`
+	ID        int           `json:"id"`
+	Version   int           `json:"version"`
+	Comment   Formattable   `json:"comment"`
+	Details   []Formattable `json:"details"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	Links     ActivityLinks `json:"_links"`
+}
+
+type ActivityLinks struct {
+	Self        Link `json:"self"`
+	WorkPackage Link `json:"workPackage"`
+	User        Link `json:"user"`
+}
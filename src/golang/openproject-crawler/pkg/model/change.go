@@ -0,0 +1,21 @@
+package model
+
+type ChangeKind string
+
+const (
+	ChangeSet     ChangeKind = "set"
+	ChangeChanged ChangeKind = "changed"
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// FieldChange is one activity detail line, e.g. "Status changed from New to
+// In progress", broken into its parts. Field holds a stable key such as
+// "status" or "dueDate"; for custom fields it holds the field's label.
+type FieldChange struct {
+	Field  string     `json:"field"`
+	Label  string     `json:"label"`
+	From   string     `json:"from,omitempty"`
+	To     string     `json:"to,omitempty"`
+	Kind   ChangeKind `json:"kind"`
+	Custom bool       `json:"custom,omitempty"`
+}
@@ -0,0 +1,25 @@
+package model
+
+type Collection[T any] struct {
+	Type     string `json:"_type"`
+	Total    int    `json:"total"`
+	Count    int    `json:"count"`
+	PageSize int    `json:"pageSize"`
+	Offset   int    `json:"offset"`
+	Embedded struct {
+		Elements []T `json:"elements"`
+	} `json:"_embedded"`
+	Links CollectionLinks `json:"_links"`
+}
+
+type CollectionLinks struct {
+	Self             Link `json:"self"`
+	JumpTo           Link `json:"jumpTo"`
+	ChangeSize       Link `json:"changeSize"`
+	NextByOffset     Link `json:"nextByOffset"`
+	PreviousByOffset Link `json:"previousByOffset"`
+}
+
+func (c *Collection[T]) Elements() []T {
+	return c.Embedded.Elements
+}
@@ -0,0 +1,23 @@
+package model
+
+type WeekDay struct {
+	Type    string `json:"_type"`
+	Day     int    `json:"day"`
+	Name    string `json:"name"`
+	Working bool   `json:"working"`
+}
+
+type NonWorkingDay struct {
+	Type string `json:"_type"`
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// Durations reports the time between two instants both as elapsed calendar
+// time and as working time according to a working calendar.
+type Durations struct {
+	CalendarDays  float64 `json:"calendarDays"`
+	CalendarHours float64 `json:"calendarHours"`
+	BusinessDays  float64 `json:"businessDays"`
+	BusinessHours float64 `json:"businessHours"`
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strconv"
+)
+
+type Link struct {
+	Href      string `json:"href"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+	Method    string `json:"method,omitempty"`
+}
+
+// UnmarshalJSON accepts null links and links whose href is null, both of
+// which OpenProject sends for unset associations such as an empty assignee.
+func (l *Link) UnmarshalJSON(data []byte) error {
+	*l = Link{}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	var raw struct {
+		Href      *string `json:"href"`
+		Title     *string `json:"title"`
+		Templated bool    `json:"templated"`
+		Method    string  `json:"method"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Href != nil {
+		l.Href = *raw.Href
+	}
+	if raw.Title != nil {
+		l.Title = *raw.Title
+	}
+	l.Templated = raw.Templated
+	l.Method = raw.Method
+	return nil
+}
+
+func (l Link) IsZero() bool {
+	return l.Href == ""
+}
+
+// ID returns the numeric ID at the end of the href, e.g. 42 for
+// "/api/v3/work_packages/42".
+func (l Link) ID() (int, bool) {
+	if l.Href == "" {
+		return 0, false
+	}
+	id, err := strconv.Atoi(path.Base(l.Href))
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+type Formattable struct {
+	Format string `json:"format,omitempty"`
+	Raw    string `json:"raw"`
+	HTML   string `json:"html,omitempty"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Membership gives a principal roles in a project.
+type Membership struct {
+	Type      string          `json:"_type"`
+	ID        int             `json:"id"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	Links     MembershipLinks `json:"_links"`
+}
+
+type MembershipLinks struct {
+	Self      Link   `json:"self"`
+	Project   Link   `json:"project"`
+	Principal Link   `json:"principal"`
+	Roles     []Link `json:"roles"`
+}
+
+func (m Membership) ProjectName() string {
+	return m.Links.Project.Title
+}
+
+func (m Membership) PrincipalName() string {
+	return m.Links.Principal.Title
+}
+
+func (m Membership) RoleNames() []string {
+	names := make([]string, len(m.Links.Roles))
+	for i, role := range m.Links.Roles {
+		names[i] = role.Title
+	}
+	return names
+}
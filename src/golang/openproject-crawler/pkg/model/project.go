@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+type Project struct {
+	Type              string       `json:"_type"`
+	ID                int          `json:"id"`
+	Identifier        string       `json:"identifier"`
+	Name              string       `json:"name"`
+	Active            bool         `json:"active"`
+	Public            bool         `json:"public"`
+	Description       Formattable  `json:"description"`
+	StatusExplanation Formattable  `json:"statusExplanation"`
+	CreatedAt         time.Time    `json:"createdAt"`
+	UpdatedAt         time.Time    `json:"updatedAt"`
+	Links             ProjectLinks `json:"_links"`
+}
+
+type ProjectLinks struct {
+	Self   Link `json:"self"`
+	Parent Link `json:"parent"`
+	Status Link `json:"status"`
+}
+
+func (p Project) ParentID() (int, bool) {
+	return p.Links.Parent.ID()
+}
+
+// StatusName is the project status, e.g. "On track", not the work package status.
+func (p Project) StatusName() string {
+	return p.Links.Status.Title
+}
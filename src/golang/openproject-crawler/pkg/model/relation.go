@@ -0,0 +1,30 @@
+package model
+
+// Relation links two work packages, e.g. "from follows to". OpenProject
+// stores each relation once and reports it from both work packages.
+type Relation struct {
+	Type         string        `json:"_type"`
+	ID           int           `json:"id"`
+	Name         string        `json:"name"`
+	RelationType string        `json:"type"`
+	ReverseType  string        `json:"reverseType"`
+	Description  string        `json:"description,omitempty"`
+	Lag          int           `json:"lag,omitempty"`
+	Delay        int           `json:"delay,omitempty"`
+	Links        RelationLinks `json:"_links"`
+}
+
+type RelationLinks struct {
+	Self Link `json:"self"`
+	From Link `json:"from"`
+	To   Link `json:"to"`
+}
+
+// LagDays is the number of days a following work package starts after the
+// one it follows. Older OpenProject versions call it delay.
+func (r Relation) LagDays() int {
+	if r.Lag != 0 {
+		return r.Lag
+	}
+	return r.Delay
+}
@@ -0,0 +1,31 @@
+package model
+
+type Status struct {
+	Type             string `json:"_type"`
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	Position         int    `json:"position"`
+	Color            string `json:"color,omitempty"`
+	IsDefault        bool   `json:"isDefault"`
+	IsClosed         bool   `json:"isClosed"`
+	IsReadonly       bool   `json:"isReadonly"`
+	DefaultDoneRatio int    `json:"defaultDoneRatio"`
+}
+
+type Type struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"`
+	Position    int    `json:"position"`
+	IsDefault   bool   `json:"isDefault"`
+	IsMilestone bool   `json:"isMilestone"`
+}
+
+type Priority struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color,omitempty"`
+	Position  int    `json:"position"`
+	IsDefault bool   `json:"isDefault"`
+	IsActive  bool   `json:"isActive"`
+}
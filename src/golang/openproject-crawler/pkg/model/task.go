@@ -0,0 +1,81 @@
+package model
+
+import "time"
+
+// DateTimeLayout is the local time format used by TaskInfo and TaskActivity.
+const DateTimeLayout = "2006-01-02 15:04:05"
+
+// Task is the merged view of one work package built from its activities.
+type Task struct {
+	TaskName       string           `json:"taskName"`
+	TaskInfo       TaskInfo         `json:"taskInfo"`
+	TaskActivities []TaskActivity   `json:"taskActivities"`
+	StatusTimeline []StatusInterval `json:"statusTimeline"`
+}
+
+type TaskInfo struct {
+	ID           int        `json:"id"`
+	Project      string     `json:"project"`
+	Type         string     `json:"type"`
+	Priority     string     `json:"priority"`
+	CreatedDate  string     `json:"createdDate"`
+	ClosedDate   string     `json:"closedDate,omitempty"`
+	ClosedStatus string     `json:"closedStatus,omitempty"`
+	CloseRule    string     `json:"closeRule,omitempty"`
+	ReopenCount  int        `json:"reopenCount"`
+	Duration     string     `json:"duration,omitempty"`
+	Durations    *Durations `json:"durations,omitempty"`
+	Retries      int        `json:"retries"`
+}
+
+// CreatedTime parses CreatedDate, which is written in location; a nil
+// location means time.Local.
+func (i TaskInfo) CreatedTime(location *time.Location) (time.Time, bool) {
+	return parseDateTime(i.CreatedDate, location)
+}
+
+func (i TaskInfo) ClosedTime(location *time.Location) (time.Time, bool) {
+	return parseDateTime(i.ClosedDate, location)
+}
+
+func parseDateTime(value string, location *time.Location) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if location == nil {
+		location = time.Local
+	}
+	t, err := time.ParseInLocation(DateTimeLayout, value, location)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+type TaskActivity struct {
+	ID       int           `json:"id"`
+	DateTime string        `json:"dateTime"`
+	Author   *Author       `json:"author,omitempty"`
+	Action   []string      `json:"action"`
+	Changes  []FieldChange `json:"changes"`
+}
+
+// Author is the user who made an activity. Login is only known when the
+// user could be looked up; Name otherwise comes from the activity's link.
+type Author struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Login string `json:"login,omitempty"`
+}
+
+type TaskAttr struct {
+	TaskName string         `json:"taskName"`
+	TaskAttr TaskAttributes `json:"taskAttr"`
+}
+
+type TaskAttributes struct {
+	ID       int    `json:"id"`
+	Type     string `json:"type"`
+	Priority string `json:"priority"`
+	Status   string `json:"status"`
+}
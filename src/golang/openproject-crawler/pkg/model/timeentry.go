@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+type TimeEntry struct {
+	Type      string         `json:"_type"`
+	ID        int            `json:"id"`
+	Comment   Formattable    `json:"comment"`
+	SpentOn   string         `json:"spentOn"`
+	Hours     string         `json:"hours"`
+	Ongoing   bool           `json:"ongoing"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	Links     TimeEntryLinks `json:"_links"`
+}
+
+type TimeEntryLinks struct {
+	Self        Link `json:"self"`
+	Project     Link `json:"project"`
+	WorkPackage Link `json:"workPackage"`
+	User        Link `json:"user"`
+	Activity    Link `json:"activity"`
+}
+
+func (te TimeEntry) UserName() string {
+	return te.Links.User.Title
+}
+
+// ActivityName is the time entry activity, e.g. "Development", not a work
+// package activity.
+func (te TimeEntry) ActivityName() string {
+	return te.Links.Activity.Title
+}
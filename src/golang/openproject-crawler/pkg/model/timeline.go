@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// StatusInterval is one stretch of time a work package spent in a status.
+// LeftAt is nil for the status the work package is still in.
+type StatusInterval struct {
+	Status          string     `json:"status"`
+	EnteredAt       time.Time  `json:"enteredAt"`
+	LeftAt          *time.Time `json:"leftAt,omitempty"`
+	DurationSeconds int64      `json:"durationSeconds"`
+}
+
+func (i StatusInterval) Duration() time.Duration {
+	return time.Duration(i.DurationSeconds) * time.Second
+}
+
+// TimeInStatus sums every interval the task spent in status, so a task that
+// bounced in and out of "In review" reports the total.
+func (t Task) TimeInStatus(status string) time.Duration {
+	var total time.Duration
+	for _, interval := range t.StatusTimeline {
+		if interval.Status == status {
+			total += interval.Duration()
+		}
+	}
+	return total
+}
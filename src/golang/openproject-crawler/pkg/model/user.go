@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+type User struct {
+	Type      string    `json:"_type"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Login     string    `json:"login,omitempty"`
+	FirstName string    `json:"firstName,omitempty"`
+	LastName  string    `json:"lastName,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	Admin     bool      `json:"admin,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	Avatar    string    `json:"avatar,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Links     UserLinks `json:"_links"`
+}
+
+type UserLinks struct {
+	Self Link `json:"self"`
+}
+
+// Principal types returned by /principals.
+const (
+	PrincipalUser        = "User"
+	PrincipalGroup       = "Group"
+	PrincipalPlaceholder = "PlaceholderUser"
+)
+
+type Group struct {
+	Type      string     `json:"_type"`
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	Links     GroupLinks `json:"_links"`
+}
+
+type GroupLinks struct {
+	Self    Link   `json:"self"`
+	Members []Link `json:"members"`
+}
+
+func (g Group) MemberIDs() []int {
+	ids := make([]int, 0, len(g.Links.Members))
+	for _, member := range g.Links.Members {
+		if id, ok := member.ID(); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Principal is a user, group or placeholder user that can be a project
+// member. Login, email and status are only set for users.
+type Principal struct {
+	Type      string    `json:"_type"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Login     string    `json:"login,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Links     UserLinks `json:"_links"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Version is a project version, which teams also use as a sprint. Its dates
+// are optional.
+type Version struct {
+	Type        string       `json:"_type"`
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	Description Formattable  `json:"description"`
+	StartDate   string       `json:"startDate,omitempty"`
+	EndDate     string       `json:"endDate,omitempty"`
+	Status      string       `json:"status"`
+	Sharing     string       `json:"sharing"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+	Links       VersionLinks `json:"_links"`
+}
+
+type VersionLinks struct {
+	Self            Link `json:"self"`
+	DefiningProject Link `json:"definingProject"`
+}
+
+func (v Version) ProjectName() string {
+	return v.Links.DefiningProject.Title
+}
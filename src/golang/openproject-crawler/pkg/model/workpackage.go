@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+type WorkPackage struct {
+	Type           string           `json:"_type"`
+	ID             int              `json:"id"`
+	LockVersion    int              `json:"lockVersion"`
+	Subject        string           `json:"subject"`
+	Description    Formattable      `json:"description"`
+	StartDate      string           `json:"startDate,omitempty"`
+	DueDate        string           `json:"dueDate,omitempty"`
+	Date           string           `json:"date,omitempty"`
+	EstimatedTime  string           `json:"estimatedTime,omitempty"`
+	RemainingTime  string           `json:"remainingTime,omitempty"`
+	StoryPoints    float64          `json:"storyPoints,omitempty"`
+	SpentTime      string           `json:"spentTime,omitempty"`
+	PercentageDone int              `json:"percentageDone"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	UpdatedAt      time.Time        `json:"updatedAt"`
+	Links          WorkPackageLinks `json:"_links"`
+}
+
+type WorkPackageLinks struct {
+	Self        Link   `json:"self"`
+	Project     Link   `json:"project"`
+	Type        Link   `json:"type"`
+	Priority    Link   `json:"priority"`
+	Status      Link   `json:"status"`
+	Author      Link   `json:"author"`
+	Assignee    Link   `json:"assignee"`
+	Responsible Link   `json:"responsible"`
+	Version     Link   `json:"version"`
+	Category    Link   `json:"category"`
+	Parent      Link   `json:"parent"`
+	Children    []Link `json:"children,omitempty"`
+}
+
+func (wp WorkPackage) ProjectName() string {
+	return wp.Links.Project.Title
+}
+
+func (wp WorkPackage) TypeName() string {
+	return wp.Links.Type.Title
+}
+
+func (wp WorkPackage) PriorityName() string {
+	return wp.Links.Priority.Title
+}
+
+func (wp WorkPackage) StatusName() string {
+	return wp.Links.Status.Title
+}
@@ -0,0 +1,87 @@
+package sqlitesink
+
+// schema is applied on every Open; each statement must be idempotent.
+// Foreign keys document the relations but are not enforced, so the tables
+// can be filled in any order and partial crawls still load.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS projects (
+		id INTEGER PRIMARY KEY,
+		identifier TEXT NOT NULL,
+		name TEXT NOT NULL,
+		active INTEGER NOT NULL,
+		public INTEGER NOT NULL,
+		description TEXT,
+		parent_id INTEGER REFERENCES projects(id),
+		created_at TEXT,
+		updated_at TEXT
+	)`,
+	`CREATE TABLE IF NOT EXISTS statuses (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		color TEXT,
+		is_default INTEGER NOT NULL,
+		is_closed INTEGER NOT NULL,
+		is_readonly INTEGER NOT NULL,
+		default_done_ratio INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		login TEXT,
+		first_name TEXT,
+		last_name TEXT,
+		email TEXT,
+		admin INTEGER,
+		status TEXT,
+		created_at TEXT,
+		updated_at TEXT
+	)`,
+	`CREATE TABLE IF NOT EXISTS work_packages (
+		id INTEGER PRIMARY KEY,
+		lock_version INTEGER NOT NULL,
+		project_id INTEGER REFERENCES projects(id),
+		subject TEXT NOT NULL,
+		type TEXT,
+		status_id INTEGER REFERENCES statuses(id),
+		priority TEXT,
+		author_id INTEGER REFERENCES users(id),
+		assignee_id INTEGER REFERENCES users(id),
+		responsible_id INTEGER REFERENCES users(id),
+		version TEXT,
+		parent_id INTEGER REFERENCES work_packages(id),
+		start_date TEXT,
+		due_date TEXT,
+		estimated_time TEXT,
+		remaining_time TEXT,
+		spent_time TEXT,
+		percentage_done INTEGER NOT NULL,
+		created_at TEXT,
+		updated_at TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS work_packages_project_id ON work_packages(project_id)`,
+	`CREATE TABLE IF NOT EXISTS activities (
+		id INTEGER PRIMARY KEY,
+		work_package_id INTEGER NOT NULL REFERENCES work_packages(id),
+		user_id INTEGER REFERENCES users(id),
+		type TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		comment TEXT,
+		created_at TEXT,
+		updated_at TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS activities_work_package_id ON activities(work_package_id)`,
+	`CREATE TABLE IF NOT EXISTS activity_changes (
+		activity_id INTEGER NOT NULL REFERENCES activities(id),
+		position INTEGER NOT NULL,
+		field TEXT,
+		label TEXT,
+		from_value TEXT,
+		to_value TEXT,
+		kind TEXT,
+		custom INTEGER NOT NULL,
+		raw TEXT NOT NULL,
+		PRIMARY KEY (activity_id, position)
+	)`,
+	`CREATE INDEX IF NOT EXISTS activity_changes_field ON activity_changes(field)`,
+}
@@ -0,0 +1,281 @@
+package sqlitesink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"openproject-crawler/internal/core"
+	"openproject-crawler/pkg/model"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Sink persists crawl results into a SQLite database. Rows are keyed by
+// their OpenProject IDs, so saving the same records again updates them.
+type Sink struct {
+	db *sql.DB
+}
+
+// Open creates the database file if needed and applies the schema.
+func Open(path string) (*Sink, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer; one connection avoids "database is locked".
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range append([]string{"PRAGMA journal_mode = WAL", "PRAGMA busy_timeout = 5000"}, schema...) {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize database %s: %w", path, err)
+		}
+	}
+	return &Sink{db: db}, nil
+}
+
+func (s *Sink) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Sink) Close() error {
+	return s.db.Close()
+}
+
+func (s *Sink) SaveProjects(ctx context.Context, projects []model.Project) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, upsertSQL("projects",
+			[]string{"id", "identifier", "name", "active", "public", "description", "parent_id", "created_at", "updated_at"}, "id"))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, p := range projects {
+			if _, err := stmt.ExecContext(ctx, p.ID, p.Identifier, p.Name, p.Active, p.Public,
+				nullString(p.Description.Raw), linkID(p.Links.Parent), timestamp(p.CreatedAt), timestamp(p.UpdatedAt)); err != nil {
+				return fmt.Errorf("failed to save project %d: %w", p.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Sink) SaveStatuses(ctx context.Context, statuses []model.Status) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, upsertSQL("statuses",
+			[]string{"id", "name", "position", "color", "is_default", "is_closed", "is_readonly", "default_done_ratio"}, "id"))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, st := range statuses {
+			if _, err := stmt.ExecContext(ctx, st.ID, st.Name, st.Position, nullString(st.Color),
+				st.IsDefault, st.IsClosed, st.IsReadonly, st.DefaultDoneRatio); err != nil {
+				return fmt.Errorf("failed to save status %d: %w", st.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Sink) SaveUsers(ctx context.Context, users []model.User) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, upsertSQL("users",
+			[]string{"id", "name", "login", "first_name", "last_name", "email", "admin", "status", "created_at", "updated_at"}, "id"))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, u := range users {
+			if _, err := stmt.ExecContext(ctx, u.ID, u.Name, nullString(u.Login), nullString(u.FirstName), nullString(u.LastName),
+				nullString(u.Email), u.Admin, nullString(u.Status), timestamp(u.CreatedAt), timestamp(u.UpdatedAt)); err != nil {
+				return fmt.Errorf("failed to save user %d: %w", u.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// SaveWorkPackages also records the author, assignee and responsible users
+// by ID and name, without touching details saved by SaveUsers.
+func (s *Sink) SaveWorkPackages(ctx context.Context, workPackages []model.WorkPackage) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, upsertSQL("work_packages", []string{
+			"id", "lock_version", "project_id", "subject", "type", "status_id", "priority",
+			"author_id", "assignee_id", "responsible_id", "version", "parent_id",
+			"start_date", "due_date", "estimated_time", "remaining_time", "spent_time", "percentage_done",
+			"created_at", "updated_at",
+		}, "id"))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		users, err := prepareUserLinks(ctx, tx)
+		if err != nil {
+			return err
+		}
+		defer users.Close()
+
+		for _, wp := range workPackages {
+			if _, err := stmt.ExecContext(ctx, wp.ID, wp.LockVersion, linkID(wp.Links.Project), wp.Subject,
+				nullString(wp.TypeName()), linkID(wp.Links.Status), nullString(wp.PriorityName()),
+				linkID(wp.Links.Author), linkID(wp.Links.Assignee), linkID(wp.Links.Responsible),
+				nullString(wp.Links.Version.Title), linkID(wp.Links.Parent),
+				nullString(wp.StartDate), nullString(wp.DueDate), nullString(wp.EstimatedTime),
+				nullString(wp.RemainingTime), nullString(wp.SpentTime), wp.PercentageDone,
+				timestamp(wp.CreatedAt), timestamp(wp.UpdatedAt)); err != nil {
+				return fmt.Errorf("failed to save work package %d: %w", wp.ID, err)
+			}
+			for _, link := range []model.Link{wp.Links.Author, wp.Links.Assignee, wp.Links.Responsible} {
+				if err := saveUserLink(ctx, users, link); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// SaveActivities takes the raw activities of each work package, as returned
+// by CrawlActivities.GetTasksData. Every detail line becomes an
+// activity_changes row; lines that cannot be parsed keep only their raw text.
+// An activity's earlier changes are replaced, so details removed since the
+// last save do not linger.
+func (s *Sink) SaveActivities(ctx context.Context, tasksData [][]model.Activity) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		activities, err := tx.PrepareContext(ctx, upsertSQL("activities",
+			[]string{"id", "work_package_id", "user_id", "type", "version", "comment", "created_at", "updated_at"}, "id"))
+		if err != nil {
+			return err
+		}
+		defer activities.Close()
+		clearChanges, err := tx.PrepareContext(ctx, "DELETE FROM activity_changes WHERE activity_id = ?")
+		if err != nil {
+			return err
+		}
+		defer clearChanges.Close()
+		changes, err := tx.PrepareContext(ctx, upsertSQL("activity_changes",
+			[]string{"activity_id", "position", "field", "label", "from_value", "to_value", "kind", "custom", "raw"}, "activity_id", "position"))
+		if err != nil {
+			return err
+		}
+		defer changes.Close()
+		users, err := prepareUserLinks(ctx, tx)
+		if err != nil {
+			return err
+		}
+		defer users.Close()
+
+		for _, task := range tasksData {
+			for _, a := range task {
+				workPackageID := linkID(a.Links.WorkPackage)
+				if workPackageID == nil {
+					return fmt.Errorf("activity %d has no work package", a.ID)
+				}
+				if _, err := activities.ExecContext(ctx, a.ID, workPackageID, linkID(a.Links.User), a.Type, a.Version,
+					nullString(a.Comment.Raw), timestamp(a.CreatedAt), timestamp(a.UpdatedAt)); err != nil {
+					return fmt.Errorf("failed to save activity %d: %w", a.ID, err)
+				}
+				if err := saveUserLink(ctx, users, a.Links.User); err != nil {
+					return err
+				}
+
+				if _, err := clearChanges.ExecContext(ctx, a.ID); err != nil {
+					return fmt.Errorf("failed to clear changes of activity %d: %w", a.ID, err)
+				}
+				for position, detail := range a.Details {
+					raw := detail.Raw
+					if raw == "" {
+						raw = detail.HTML
+					}
+					var change model.FieldChange
+					if parsed, ok := core.ParseDetail(detail); ok {
+						change = parsed
+					}
+					if _, err := changes.ExecContext(ctx, a.ID, position, nullString(change.Field), nullString(change.Label),
+						nullString(change.From), nullString(change.To), nullString(string(change.Kind)), change.Custom, raw); err != nil {
+						return fmt.Errorf("failed to save change %d of activity %d: %w", position, a.ID, err)
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Sink) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func prepareUserLinks(ctx context.Context, tx *sql.Tx) (*sql.Stmt, error) {
+	return tx.PrepareContext(ctx, upsertSQL("users", []string{"id", "name"}, "id"))
+}
+
+func saveUserLink(ctx context.Context, stmt *sql.Stmt, link model.Link) error {
+	id, ok := link.ID()
+	if !ok || link.Title == "" {
+		return nil
+	}
+	if _, err := stmt.ExecContext(ctx, id, link.Title); err != nil {
+		return fmt.Errorf("failed to save user %d: %w", id, err)
+	}
+	return nil
+}
+
+// upsertSQL builds an INSERT that updates every non-key column when a row
+// with the same keys exists.
+func upsertSQL(table string, columns []string, keys ...string) string {
+	placeholders := make([]string, len(columns))
+	var updates []string
+	for i, column := range columns {
+		placeholders[i] = "?"
+		isKey := false
+		for _, key := range keys {
+			if key == column {
+				isKey = true
+			}
+		}
+		if !isKey {
+			updates = append(updates, column+" = excluded."+column)
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "), strings.Join(keys, ", "), strings.Join(updates, ", "))
+}
+
+func linkID(link model.Link) interface{} {
+	if id, ok := link.ID(); ok {
+		return id
+	}
+	return nil
+}
+
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func timestamp(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,100 @@
+package sqlitesink
+
+import (
+	"context"
+	"encoding/json"
+	"openproject-crawler/internal/fakeapi"
+	"openproject-crawler/pkg/model"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func fixtureTasksData(t *testing.T) [][]model.Activity {
+	t.Helper()
+	fixtures := fakeapi.DefaultFixtures()
+	ids := make([]int, 0, len(fixtures.Activities))
+	for id := range fixtures.Activities {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	var tasksData [][]model.Activity
+	for _, id := range ids {
+		var activities []model.Activity
+		for _, raw := range fixtures.Activities[id] {
+			var activity model.Activity
+			if err := json.Unmarshal(raw, &activity); err != nil {
+				t.Fatal(err)
+			}
+			activities = append(activities, activity)
+		}
+		tasksData = append(tasksData, activities)
+	}
+	return tasksData
+}
+
+func countRows(t *testing.T, sink *Sink, query string, args ...interface{}) int {
+	t.Helper()
+	var count int
+	if err := sink.DB().QueryRow(query, args...).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestSaveActivitiesTwice(t *testing.T) {
+	sink, err := Open(filepath.Join(t.TempDir(), "crawl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	ctx := context.Background()
+	tasksData := fixtureTasksData(t)
+
+	var activities, details int
+	for _, task := range tasksData {
+		for _, activity := range task {
+			activities++
+			details += len(activity.Details)
+		}
+	}
+	for run := 1; run <= 2; run++ {
+		if err := sink.SaveActivities(ctx, tasksData); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if got := countRows(t, sink, "SELECT COUNT(*) FROM activities"); got != activities {
+			t.Errorf("run %d: activities = %d, want %d", run, got, activities)
+		}
+		if got := countRows(t, sink, "SELECT COUNT(*) FROM activity_changes"); got != details {
+			t.Errorf("run %d: activity changes = %d, want %d", run, got, details)
+		}
+	}
+
+	// An activity saved again with fewer details loses the removed ones and
+	// keeps its updated comment.
+	var edited model.Activity
+	for _, task := range tasksData {
+		for _, activity := range task {
+			if len(activity.Details) > 1 {
+				edited = activity
+			}
+		}
+	}
+	if edited.ID == 0 {
+		t.Fatal("no fixture activity with several details")
+	}
+	edited.Details = edited.Details[:1]
+	edited.Comment.Raw = "Edited"
+	if err := sink.SaveActivities(ctx, [][]model.Activity{{edited}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := countRows(t, sink, "SELECT COUNT(*) FROM activity_changes WHERE activity_id = ?", edited.ID); got != 1 {
+		t.Errorf("changes of activity %d = %d, want 1", edited.ID, got)
+	}
+	if got := countRows(t, sink, "SELECT COUNT(*) FROM activities WHERE id = ? AND comment = 'Edited'", edited.ID); got != 1 {
+		t.Errorf("activity %d was not updated", edited.ID)
+	}
+	if got := countRows(t, sink, "SELECT COUNT(*) FROM activities"); got != activities {
+		t.Errorf("activities = %d, want %d", got, activities)
+	}
+}
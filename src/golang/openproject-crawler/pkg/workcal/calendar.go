@@ -0,0 +1,168 @@
+package workcal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Calendar describes when work happens: which weekdays are working days,
+// the working hours within a day, and holidays that are skipped entirely.
+type Calendar struct {
+	workingDays map[time.Weekday]bool
+	dayStart    time.Duration
+	dayEnd      time.Duration
+	holidays    map[string]string
+	location    *time.Location
+}
+
+// NewCalendar returns a Monday to Friday, 09:00 to 17:00 calendar in the
+// local time zone with no holidays.
+func NewCalendar() *Calendar {
+	return &Calendar{
+		workingDays: map[time.Weekday]bool{
+			time.Monday:    true,
+			time.Tuesday:   true,
+			time.Wednesday: true,
+			time.Thursday:  true,
+			time.Friday:    true,
+		},
+		dayStart: 9 * time.Hour,
+		dayEnd:   17 * time.Hour,
+		holidays: make(map[string]string),
+		location: time.Local,
+	}
+}
+
+func (c *Calendar) SetWorkingDays(days ...time.Weekday) {
+	c.workingDays = make(map[time.Weekday]bool, len(days))
+	for _, day := range days {
+		c.workingDays[day] = true
+	}
+}
+
+func (c *Calendar) GetWorkingDays() []time.Weekday {
+	var days []time.Weekday
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if c.workingDays[day] {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// SetWorkingHours sets the working window as offsets from midnight, e.g.
+// 9*time.Hour and 17*time.Hour.
+func (c *Calendar) SetWorkingHours(start, end time.Duration) error {
+	if start < 0 || end > 24*time.Hour || start >= end {
+		return fmt.Errorf("invalid working hours %v to %v", start, end)
+	}
+	c.dayStart = start
+	c.dayEnd = end
+	return nil
+}
+
+func (c *Calendar) HoursPerDay() float64 {
+	return (c.dayEnd - c.dayStart).Hours()
+}
+
+func (c *Calendar) GetLocation() *time.Location {
+	return c.location
+}
+
+func (c *Calendar) SetLocation(location *time.Location) {
+	if location == nil {
+		location = time.Local
+	}
+	c.location = location
+}
+
+func (c *Calendar) AddHoliday(date time.Time, name string) {
+	c.holidays[date.Format(dateLayout)] = name
+}
+
+func (c *Calendar) Holidays() map[string]string {
+	holidays := make(map[string]string, len(c.holidays))
+	for date, name := range c.holidays {
+		holidays[date] = name
+	}
+	return holidays
+}
+
+func (c *Calendar) IsWorkingDay(t time.Time) bool {
+	t = t.In(c.location)
+	if !c.workingDays[t.Weekday()] {
+		return false
+	}
+	_, holiday := c.holidays[t.Format(dateLayout)]
+	return !holiday
+}
+
+// WorkingDuration returns how much of [start, end) falls inside working hours
+// on working days.
+func (c *Calendar) WorkingDuration(start, end time.Time) time.Duration {
+	if !end.After(start) {
+		return 0
+	}
+	start = start.In(c.location)
+	end = end.In(c.location)
+
+	var total time.Duration
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, c.location)
+	for !day.After(end) {
+		if c.IsWorkingDay(day) {
+			from := maxTime(start, c.clock(day, c.dayStart))
+			to := minTime(end, c.clock(day, c.dayEnd))
+			if to.After(from) {
+				total += to.Sub(from)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return total
+}
+
+// clock is the wall-clock time offset after midnight of day, so the working
+// day starts at the same hour on days that change to or from daylight saving.
+func (c *Calendar) clock(day time.Time, offset time.Duration) time.Time {
+	year, month, date := day.Date()
+	return time.Date(year, month, date, int(offset/time.Hour), int(offset%time.Hour/time.Minute), int(offset%time.Minute/time.Second), 0, c.location)
+}
+
+// BusinessDays expresses WorkingDuration in working days, so half a working
+// day counts as 0.5.
+func (c *Calendar) BusinessDays(start, end time.Time) float64 {
+	hours := c.HoursPerDay()
+	if hours == 0 {
+		return 0
+	}
+	return c.WorkingDuration(start, end).Hours() / hours
+}
+
+// ParseWeekday reads an English weekday name, e.g. "Monday" or "mon".
+func ParseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,159 @@
+package workcal
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestWorkingDuration(t *testing.T) {
+	calendar := NewCalendar()
+	calendar.SetLocation(time.UTC)
+	// Friday 2024-03-29 is a holiday.
+	calendar.AddHoliday(time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC), "Good Friday")
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		hours      float64
+		days       float64
+	}{
+		{"same working day", at(25, 10, 0), at(25, 14, 30), 4.5, 0.5625},
+		{"whole working day", at(25, 0, 0), at(26, 0, 0), 8, 1},
+		{"before and after hours", at(25, 6, 0), at(25, 20, 0), 8, 1},
+		{"outside working hours", at(25, 17, 0), at(26, 9, 0), 0, 0},
+		{"overnight", at(25, 16, 0), at(26, 10, 0), 2, 0.25},
+		{"partial first and last day", at(25, 13, 0), at(27, 11, 0), 14, 1.75},
+		{"weekend only", at(23, 9, 0), at(24, 17, 0), 0, 0},
+		{"across a weekend", at(22, 15, 0), at(25, 11, 0), 4, 0.5},
+		{"across a holiday and weekend", at(28, 9, 0), time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), 8, 1},
+		{"holiday only", at(29, 9, 0), at(29, 17, 0), 0, 0},
+		{"week with holiday", at(25, 0, 0), at(30, 0, 0), 32, 4},
+		{"reversed", at(26, 9, 0), at(25, 9, 0), 0, 0},
+		{"empty", at(25, 9, 0), at(25, 9, 0), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.WorkingDuration(tt.start, tt.end).Hours(); got != tt.hours {
+				t.Errorf("WorkingDuration = %vh, want %vh", got, tt.hours)
+			}
+			if got := calendar.BusinessDays(tt.start, tt.end); got != tt.days {
+				t.Errorf("BusinessDays = %v, want %v", got, tt.days)
+			}
+		})
+	}
+}
+
+func TestCustomCalendar(t *testing.T) {
+	calendar := NewCalendar()
+	calendar.SetLocation(time.UTC)
+	// A Sunday to Thursday week of 07:30 to 13:30.
+	calendar.SetWorkingDays(time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday)
+	if err := calendar.SetWorkingHours(7*time.Hour+30*time.Minute, 13*time.Hour+30*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got := calendar.HoursPerDay(); got != 6 {
+		t.Errorf("HoursPerDay = %v, want 6", got)
+	}
+	// Thursday noon to Sunday noon: Friday and Saturday are off.
+	start := time.Date(2024, 3, 28, 12, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	if got := calendar.WorkingDuration(start, end).Hours(); got != 6 {
+		t.Errorf("WorkingDuration = %vh, want 6h", got)
+	}
+	if got := calendar.BusinessDays(start, end); got != 1 {
+		t.Errorf("BusinessDays = %v, want 1", got)
+	}
+}
+
+func TestWorkingDurationInLocation(t *testing.T) {
+	calendar := NewCalendar()
+	calendar.SetLocation(time.FixedZone("JST", 9*60*60))
+	// 00:00 to 08:00 UTC on Monday is 09:00 to 17:00 in Tokyo.
+	start := time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)
+	if got := calendar.WorkingDuration(start, start.Add(8*time.Hour)).Hours(); got != 8 {
+		t.Errorf("WorkingDuration = %vh, want 8h", got)
+	}
+	// Sunday 23:00 UTC is already Monday morning in Tokyo.
+	if !calendar.IsWorkingDay(time.Date(2024, 3, 24, 23, 0, 0, 0, time.UTC)) {
+		t.Error("Sunday 23:00 UTC is not a working day in Tokyo")
+	}
+}
+
+func TestSetWorkingHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end time.Duration
+		ok         bool
+	}{
+		{"office hours", 9 * time.Hour, 17 * time.Hour, true},
+		{"whole day", 0, 24 * time.Hour, true},
+		{"reversed", 17 * time.Hour, 9 * time.Hour, false},
+		{"empty", 9 * time.Hour, 9 * time.Hour, false},
+		{"past midnight", 20 * time.Hour, 26 * time.Hour, false},
+		{"negative", -time.Hour, 8 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewCalendar().SetWorkingHours(tt.start, tt.end); (err == nil) != tt.ok {
+				t.Errorf("SetWorkingHours(%v, %v) = %v", tt.start, tt.end, err)
+			}
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		name string
+		want time.Weekday
+		ok   bool
+	}{
+		{"Monday", time.Monday, true},
+		{"sun", time.Sunday, true},
+		{" FRIDAY ", time.Friday, true},
+		{"fr", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		day, err := ParseWeekday(tt.name)
+		if (err == nil) != tt.ok || day != tt.want {
+			t.Errorf("ParseWeekday(%q) = %v, %v", tt.name, day, err)
+		}
+	}
+}
+
+func TestWorkingDurationAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	calendar := NewCalendar()
+	calendar.SetLocation(berlin)
+	calendar.SetWorkingDays(time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		hours      float64
+	}{
+		// Clocks went forward at 02:00 on 2024-03-31 and back at 03:00 on 2024-10-27.
+		{"first hour, spring", at(3, 31, 9, 0), at(3, 31, 10, 0), 1},
+		{"last half hour, spring", at(3, 31, 16, 30), at(3, 31, 17, 30), 0.5},
+		{"first hour, autumn", at(10, 27, 8, 0), at(10, 27, 10, 0), 1},
+		{"last half hour, autumn", at(10, 27, 16, 30), at(10, 27, 17, 30), 0.5},
+		{"across the change", at(3, 30, 0, 0), at(4, 1, 0, 0), 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.WorkingDuration(tt.start, tt.end).Hours(); got != tt.hours {
+				t.Errorf("WorkingDuration = %vh, want %vh", got, tt.hours)
+			}
+		})
+	}
+}
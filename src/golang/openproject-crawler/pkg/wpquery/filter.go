@@ -0,0 +1,215 @@
+package wpquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter is one work package filter, e.g. status "o" or type "=" [1, 2].
+type Filter struct {
+	Name     string
+	Operator Operator
+	Values   []string
+}
+
+// filterKind groups the filters that accept the same operators and values.
+type filterKind struct {
+	operators []Operator
+	values    func(op Operator, value string) error
+}
+
+var (
+	dateOperators = []Operator{BetweenDates, OnDate, Today, ThisWeek, MoreThanDaysAgo, LessThanDaysAgo, DaysAgo, LessThanDaysIn, MoreThanDaysIn, DaysIn, All, None}
+
+	idFilter        = filterKind{[]Operator{Equals, NotEquals}, idValue}
+	optionalID      = filterKind{[]Operator{Equals, NotEquals, All, None}, idValue}
+	principalFilter = filterKind{[]Operator{Equals, NotEquals}, principalValue}
+	optionalUser    = filterKind{[]Operator{Equals, NotEquals, All, None}, principalValue}
+	statusFilter    = filterKind{[]Operator{Equals, NotEquals, Open, Closed, All}, idValue}
+	textFilter      = filterKind{[]Operator{Contains, NotContains}, nil}
+	searchFilter    = filterKind{[]Operator{Search}, nil}
+	dateFilter      = filterKind{dateOperators, nil}
+	numberFilter    = filterKind{[]Operator{Equals, NotEquals, GreaterOrEqual, LessOrEqual, All, None}, numberValue}
+	customField     = filterKind{nil, nil}
+)
+
+var filters = map[string]filterKind{
+	"id":              idFilter,
+	"project":         idFilter,
+	"subprojectId":    optionalID,
+	"status":          statusFilter,
+	"type":            idFilter,
+	"priority":        idFilter,
+	"category":        optionalID,
+	"version":         optionalID,
+	"parent":          optionalID,
+	"author":          principalFilter,
+	"watcher":         principalFilter,
+	"assignee":        optionalUser,
+	"responsible":     optionalUser,
+	"assigneeOrGroup": optionalUser,
+	"subject":         textFilter,
+	"description":     textFilter,
+	"search":          searchFilter,
+	"createdAt":       dateFilter,
+	"updatedAt":       dateFilter,
+	"startDate":       dateFilter,
+	"dueDate":         dateFilter,
+	"estimatedTime":   numberFilter,
+	"percentageDone":  numberFilter,
+}
+
+var customFieldName = regexp.MustCompile(`^customField\d+$`)
+
+func lookupFilter(name string) (filterKind, bool) {
+	if customFieldName.MatchString(name) {
+		return customField, true
+	}
+	kind, ok := filters[name]
+	return kind, ok
+}
+
+// FilterNames lists the standard filters; custom fields are named
+// "customField<id>".
+func FilterNames() []string {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that the filter exists, takes the operator, and that the
+// values fit the operator and the filter.
+func (f Filter) Validate() error {
+	kind, ok := lookupFilter(f.Name)
+	if !ok {
+		return fmt.Errorf("unknown filter %q; known filters: %s, customField<id>", f.Name, strings.Join(FilterNames(), ", "))
+	}
+	if kind.operators != nil && !hasOperator(kind.operators, f.Operator) {
+		ops := make([]string, len(kind.operators))
+		for i, op := range kind.operators {
+			ops[i] = string(op)
+		}
+		return fmt.Errorf("filter %q: operator %q not supported; use one of %s", f.Name, f.Operator, strings.Join(ops, " "))
+	}
+	if err := f.Operator.validateValues(f.Values); err != nil {
+		return fmt.Errorf("filter %q: %w", f.Name, err)
+	}
+	if kind.values != nil && operatorValues[f.Operator] == listValues {
+		for _, value := range f.Values {
+			if err := kind.values(f.Operator, value); err != nil {
+				return fmt.Errorf("filter %q: %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f Filter) MarshalJSON() ([]byte, error) {
+	values := f.Values
+	if values == nil {
+		values = []string{}
+	}
+	return marshal(map[string]interface{}{
+		f.Name: map[string]interface{}{
+			"operator": f.Operator,
+			"values":   values,
+		},
+	})
+}
+
+// UnmarshalJSON reads the API's {"name": {"operator": ..., "values": [...]}}
+// form. Numeric values are kept as their decimal text.
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	var raw map[string]struct {
+		Operator Operator          `json:"operator"`
+		Values   []json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 1 {
+		return fmt.Errorf("a filter must have exactly one name, got %d", len(raw))
+	}
+	for name, spec := range raw {
+		f.Name = name
+		f.Operator = spec.Operator
+		f.Values = make([]string, 0, len(spec.Values))
+		for _, value := range spec.Values {
+			var text string
+			if err := json.Unmarshal(value, &text); err != nil {
+				var number json.Number
+				if err := json.Unmarshal(value, &number); err != nil {
+					return fmt.Errorf("filter %q: values must be strings or numbers", name)
+				}
+				text = number.String()
+			}
+			f.Values = append(f.Values, text)
+		}
+	}
+	return nil
+}
+
+// ParseFilters reads and validates filters in the API's JSON array form.
+func ParseFilters(data string) ([]Filter, error) {
+	var parsed []Filter
+	if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid filters: %w", err)
+	}
+	for _, filter := range parsed {
+		if err := filter.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// marshal keeps operators such as "<>d" readable instead of escaping them
+// for HTML.
+func marshal(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func hasOperator(operators []Operator, op Operator) bool {
+	for _, candidate := range operators {
+		if candidate == op {
+			return true
+		}
+	}
+	return false
+}
+
+func idValue(op Operator, value string) error {
+	if id, err := strconv.Atoi(value); err != nil || id < 1 {
+		return fmt.Errorf("operator %q needs IDs, got %q", op, value)
+	}
+	return nil
+}
+
+// principalValue also accepts "me", the user the credential belongs to.
+func principalValue(op Operator, value string) error {
+	if value == "me" {
+		return nil
+	}
+	return idValue(op, value)
+}
+
+func numberValue(op Operator, value string) error {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return fmt.Errorf("operator %q needs numbers, got %q", op, value)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package wpquery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		err    string
+	}{
+		{"open status", Filter{"status", Open, nil}, ""},
+		{"status IDs", Filter{"status", Equals, []string{"1", "2"}}, ""},
+		{"unknown filter", Filter{"colour", Equals, []string{"1"}}, `unknown filter "colour"`},
+		{"unsupported operator", Filter{"type", Open, nil}, `filter "type": operator "o" not supported; use one of = !`},
+		{"unknown operator", Filter{"subject", "~~", []string{"x"}}, `operator "~~" not supported`},
+		{"values for no-value operator", Filter{"status", Closed, []string{"1"}}, `operator "c" takes no values, got 1`},
+		{"missing list values", Filter{"type", Equals, nil}, `operator "=" needs at least one value`},
+		{"ID not a number", Filter{"type", Equals, []string{"bug"}}, `operator "=" needs IDs, got "bug"`},
+		{"ID not positive", Filter{"project", Equals, []string{"0"}}, `needs IDs, got "0"`},
+		{"author me", Filter{"author", Equals, []string{"me"}}, ""},
+		{"assignee me and ID", Filter{"assignee", NotEquals, []string{"me", "7"}}, ""},
+		{"assignee none", Filter{"assignee", None, nil}, ""},
+		{"principal name", Filter{"watcher", Equals, []string{"alice"}}, `needs IDs, got "alice"`},
+		{"me is no project", Filter{"project", Equals, []string{"me"}}, `needs IDs, got "me"`},
+		{"between dates", Filter{"updatedAt", BetweenDates, []string{"2024-03-01", "2024-03-31"}}, ""},
+		{"open start", Filter{"updatedAt", BetweenDates, []string{"", "2024-03-31"}}, ""},
+		{"open end", Filter{"createdAt", BetweenDates, []string{"2024-03-01T09:00:00Z", ""}}, ""},
+		{"both bounds open", Filter{"createdAt", BetweenDates, []string{"", ""}}, `operator "<>d" needs a from or a to date`},
+		{"one bound", Filter{"createdAt", BetweenDates, []string{"2024-03-01"}}, `operator "<>d" takes a from and a to date, got 1 values`},
+		{"bad date", Filter{"dueDate", BetweenDates, []string{"01.03.2024", ""}}, `operator "<>d" needs dates, got "01.03.2024"`},
+		{"on date", Filter{"startDate", OnDate, []string{"2024-03-04"}}, ""},
+		{"days ago", Filter{"updatedAt", LessThanDaysAgo, []string{"30"}}, ""},
+		{"negative days", Filter{"updatedAt", LessThanDaysAgo, []string{"-1"}}, `needs a number of days, got "-1"`},
+		{"empty text", Filter{"subject", Contains, []string{""}}, `operator "~" needs a non-empty value`},
+		{"number", Filter{"estimatedTime", GreaterOrEqual, []string{"1.5"}}, ""},
+		{"not a number", Filter{"percentageDone", Equals, []string{"half"}}, `needs numbers, got "half"`},
+		{"custom field", Filter{"customField12", Equals, []string{"anything"}}, ""},
+		{"custom field operator", Filter{"customField3", Contains, []string{"text"}}, ""},
+		{"custom field values", Filter{"customField3", Open, []string{"1"}}, `filter "customField3": operator "o" takes no values`},
+		{"custom field without ID", Filter{"customField", Equals, []string{"1"}}, `unknown filter "customField"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Filter
+		err  string
+	}{
+		{"empty", `[]`, []Filter{}, ""},
+		{
+			"numbers and strings",
+			`[{"status":{"operator":"o","values":[]}},{"type":{"operator":"=","values":[1,"2"]}}]`,
+			[]Filter{{"status", Open, []string{}}, {"type", Equals, []string{"1", "2"}}},
+			"",
+		},
+		{
+			"open date bound",
+			`[{"updatedAt":{"operator":"<>d","values":["2024-03-01",""]}}]`,
+			[]Filter{{"updatedAt", BetweenDates, []string{"2024-03-01", ""}}},
+			"",
+		},
+		{
+			"me and custom field",
+			`[{"assignee":{"operator":"=","values":["me"]}},{"customField4":{"operator":"!*","values":[]}}]`,
+			[]Filter{{"assignee", Equals, []string{"me"}}, {"customField4", None, []string{}}},
+			"",
+		},
+		{"not JSON", `status=o`, nil, "invalid filters"},
+		{"not an array", `{"status":{"operator":"o","values":[]}}`, nil, "invalid filters"},
+		{"two names", `[{"status":{"operator":"o"},"type":{"operator":"o"}}]`, nil, "exactly one name, got 2"},
+		{"object value", `[{"type":{"operator":"=","values":[{"id":1}]}}]`, nil, `filter "type": values must be strings or numbers`},
+		{"invalid filter", `[{"type":{"operator":"o","values":[]}}]`, nil, `operator "o" not supported`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := ParseFilters(tt.data)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(filters, tt.want) {
+				t.Errorf("filters = %+v, want %+v", filters, tt.want)
+			}
+		})
+	}
+}
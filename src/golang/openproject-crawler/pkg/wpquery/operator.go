@@ -0,0 +1,132 @@
+package wpquery
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!"
+	Open         Operator = "o"
+	Closed       Operator = "c"
+	All          Operator = "*"
+	None         Operator = "!*"
+	Contains     Operator = "~"
+	NotContains  Operator = "!~"
+	Search       Operator = "**"
+	BetweenDates Operator = "<>d"
+	OnDate       Operator = "=d"
+	Today        Operator = "t"
+	ThisWeek     Operator = "w"
+	// Relative to today in days: "more than n days ago" etc.
+	MoreThanDaysAgo Operator = "<t-"
+	LessThanDaysAgo Operator = ">t-"
+	DaysAgo         Operator = "t-"
+	LessThanDaysIn  Operator = "<t+"
+	MoreThanDaysIn  Operator = ">t+"
+	DaysIn          Operator = "t+"
+	GreaterOrEqual  Operator = ">="
+	LessOrEqual     Operator = "<="
+)
+
+// valueKind is the shape of the values an operator takes.
+type valueKind int
+
+const (
+	noValues valueKind = iota
+	listValues
+	oneText
+	oneInteger
+	oneNumber
+	oneDate
+	dateRange
+)
+
+var operatorValues = map[Operator]valueKind{
+	Equals:          listValues,
+	NotEquals:       listValues,
+	Open:            noValues,
+	Closed:          noValues,
+	All:             noValues,
+	None:            noValues,
+	Contains:        oneText,
+	NotContains:     oneText,
+	Search:          oneText,
+	BetweenDates:    dateRange,
+	OnDate:          oneDate,
+	Today:           noValues,
+	ThisWeek:        noValues,
+	MoreThanDaysAgo: oneInteger,
+	LessThanDaysAgo: oneInteger,
+	DaysAgo:         oneInteger,
+	LessThanDaysIn:  oneInteger,
+	MoreThanDaysIn:  oneInteger,
+	DaysIn:          oneInteger,
+	GreaterOrEqual:  oneNumber,
+	LessOrEqual:     oneNumber,
+}
+
+func (op Operator) validateValues(values []string) error {
+	kind, ok := operatorValues[op]
+	if !ok {
+		return fmt.Errorf("unknown operator %q", op)
+	}
+	switch kind {
+	case noValues:
+		if len(values) > 0 {
+			return fmt.Errorf("operator %q takes no values, got %d", op, len(values))
+		}
+	case listValues:
+		if len(values) == 0 {
+			return fmt.Errorf("operator %q needs at least one value", op)
+		}
+	case oneText, oneInteger, oneNumber, oneDate:
+		if len(values) != 1 {
+			return fmt.Errorf("operator %q takes exactly one value, got %d", op, len(values))
+		}
+		switch kind {
+		case oneText:
+			if values[0] == "" {
+				return fmt.Errorf("operator %q needs a non-empty value", op)
+			}
+		case oneInteger:
+			if days, err := strconv.Atoi(values[0]); err != nil || days < 0 {
+				return fmt.Errorf("operator %q needs a number of days, got %q", op, values[0])
+			}
+		case oneNumber:
+			if _, err := strconv.ParseFloat(values[0], 64); err != nil {
+				return fmt.Errorf("operator %q needs a number, got %q", op, values[0])
+			}
+		case oneDate:
+			if !isDate(values[0]) {
+				return fmt.Errorf("operator %q needs a date, got %q", op, values[0])
+			}
+		}
+	case dateRange:
+		if len(values) != 2 {
+			return fmt.Errorf("operator %q takes a from and a to date, got %d values", op, len(values))
+		}
+		if values[0] == "" && values[1] == "" {
+			return fmt.Errorf("operator %q needs a from or a to date", op)
+		}
+		for _, value := range values {
+			if value != "" && !isDate(value) {
+				return fmt.Errorf("operator %q needs dates, got %q", op, value)
+			}
+		}
+	}
+	return nil
+}
+
+// isDate accepts the ISO 8601 dates and date-times OpenProject understands.
+func isDate(value string) bool {
+	if _, err := time.Parse(time.DateOnly, value); err == nil {
+		return true
+	}
+	_, err := time.Parse(time.RFC3339, value)
+	return err == nil
+}
@@ -0,0 +1,208 @@
+package wpquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paginationFields are always selected, since paging through a collection
+// depends on them.
+var paginationFields = []string{"total", "count", "pageSize", "offset"}
+
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// Query builds the filters, sortBy, groupBy and select parameters of a
+// /work_packages request. Methods add to the query and return it for
+// chaining; Params validates the result before it is sent.
+type Query struct {
+	filters []Filter
+	sortBy  []sortKey
+	groupBy string
+	selects []string
+}
+
+func New() *Query {
+	return &Query{}
+}
+
+func (q *Query) Clone() *Query {
+	return &Query{
+		filters: append([]Filter(nil), q.filters...),
+		sortBy:  append([]sortKey(nil), q.sortBy...),
+		groupBy: q.groupBy,
+		selects: append([]string(nil), q.selects...),
+	}
+}
+
+func (q *Query) Filter(name string, op Operator, values ...string) *Query {
+	q.filters = append(q.filters, Filter{Name: name, Operator: op, Values: values})
+	return q
+}
+
+func (q *Query) AddFilters(filters ...Filter) *Query {
+	q.filters = append(q.filters, filters...)
+	return q
+}
+
+func (q *Query) GetFilters() []Filter {
+	return append([]Filter(nil), q.filters...)
+}
+
+func (q *Query) Project(ids ...int) *Query {
+	return q.Filter("project", Equals, itoa(ids)...)
+}
+
+func (q *Query) Status(op Operator, ids ...int) *Query {
+	return q.Filter("status", op, itoa(ids)...)
+}
+
+func (q *Query) Type(op Operator, ids ...int) *Query {
+	return q.Filter("type", op, itoa(ids)...)
+}
+
+func (q *Query) Version(op Operator, ids ...int) *Query {
+	return q.Filter("version", op, itoa(ids)...)
+}
+
+func (q *Query) Parent(op Operator, ids ...int) *Query {
+	return q.Filter("parent", op, itoa(ids)...)
+}
+
+// Assignee and Author take user IDs or "me".
+func (q *Query) Assignee(op Operator, users ...string) *Query {
+	return q.Filter("assignee", op, users...)
+}
+
+func (q *Query) Author(op Operator, users ...string) *Query {
+	return q.Filter("author", op, users...)
+}
+
+func (q *Query) SubjectContains(text string) *Query {
+	return q.Filter("subject", Contains, text)
+}
+
+// Search matches text in the subject, description and comments.
+func (q *Query) Search(text string) *Query {
+	return q.Filter("search", Search, text)
+}
+
+// CreatedBetween and UpdatedBetween leave a bound open when it is zero.
+func (q *Query) CreatedBetween(from, to time.Time) *Query {
+	return q.Filter("createdAt", BetweenDates, dateValue(from), dateValue(to))
+}
+
+func (q *Query) UpdatedBetween(from, to time.Time) *Query {
+	return q.Filter("updatedAt", BetweenDates, dateValue(from), dateValue(to))
+}
+
+// UpdatedWithinDays selects work packages updated less than days ago.
+func (q *Query) UpdatedWithinDays(days int) *Query {
+	return q.Filter("updatedAt", LessThanDaysAgo, strconv.Itoa(days))
+}
+
+func (q *Query) CustomField(id int, op Operator, values ...string) *Query {
+	return q.Filter(fmt.Sprintf("customField%d", id), op, values...)
+}
+
+func (q *Query) SortBy(field string, desc bool) *Query {
+	q.sortBy = append(q.sortBy, sortKey{field: field, desc: desc})
+	return q
+}
+
+func (q *Query) GroupBy(field string) *Query {
+	q.groupBy = field
+	return q
+}
+
+// Select limits the returned properties of each work package, e.g. "id" or
+// "subject". Paths containing "/" are passed through unchanged.
+func (q *Query) Select(fields ...string) *Query {
+	q.selects = append(q.selects, fields...)
+	return q
+}
+
+func (q *Query) Validate() error {
+	for _, filter := range q.filters {
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, key := range q.sortBy {
+		if key.field == "" {
+			return errors.New("sortBy needs a field")
+		}
+	}
+	for _, field := range q.selects {
+		if field == "" {
+			return errors.New("select needs a field")
+		}
+	}
+	return nil
+}
+
+// Params validates the query and returns it as request parameters.
+func (q *Query) Params() (map[string]interface{}, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]interface{})
+	if len(q.filters) > 0 {
+		filtersJSON, err := marshal(q.filters)
+		if err != nil {
+			return nil, err
+		}
+		params["filters"] = string(filtersJSON)
+	}
+	if len(q.sortBy) > 0 {
+		sortBy := make([][2]string, len(q.sortBy))
+		for i, key := range q.sortBy {
+			direction := "asc"
+			if key.desc {
+				direction = "desc"
+			}
+			sortBy[i] = [2]string{key.field, direction}
+		}
+		sortJSON, err := json.Marshal(sortBy)
+		if err != nil {
+			return nil, err
+		}
+		params["sortBy"] = string(sortJSON)
+	}
+	if q.groupBy != "" {
+		params["groupBy"] = q.groupBy
+	}
+	if len(q.selects) > 0 {
+		selects := append([]string(nil), paginationFields...)
+		for _, field := range q.selects {
+			if !strings.Contains(field, "/") {
+				field = "elements/" + field
+			}
+			selects = append(selects, field)
+		}
+		params["select"] = strings.Join(selects, ",")
+	}
+	return params, nil
+}
+
+func itoa(ids []int) []string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	return values
+}
+
+func dateValue(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,90 @@
+package wpquery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParams(t *testing.T) {
+	march := time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("CET", 60*60))
+	tests := []struct {
+		name  string
+		query *Query
+		want  map[string]interface{}
+		err   string
+	}{
+		{"empty", New(), map[string]interface{}{}, ""},
+		{
+			"filters",
+			New().Project(1, 2).Status(Open).Assignee(Equals, "me"),
+			map[string]interface{}{"filters": `[{"project":{"operator":"=","values":["1","2"]}},{"status":{"operator":"o","values":[]}},{"assignee":{"operator":"=","values":["me"]}}]`},
+			"",
+		},
+		{
+			"open date bound",
+			New().UpdatedBetween(march, time.Time{}),
+			map[string]interface{}{"filters": `[{"updatedAt":{"operator":"<>d","values":["2024-03-01T09:00:00Z",""]}}]`},
+			"",
+		},
+		{
+			"custom field",
+			New().CustomField(7, Equals, "blue"),
+			map[string]interface{}{"filters": `[{"customField7":{"operator":"=","values":["blue"]}}]`},
+			"",
+		},
+		{
+			"sort and group",
+			New().SortBy("updatedAt", true).SortBy("id", false).GroupBy("status"),
+			map[string]interface{}{"sortBy": `[["updatedAt","desc"],["id","asc"]]`, "groupBy": "status"},
+			"",
+		},
+		{
+			"select",
+			New().Select("id", "subject", "_links/status"),
+			map[string]interface{}{"select": "total,count,pageSize,offset,elements/id,elements/subject,_links/status"},
+			"",
+		},
+		{"select with elements prefix", New().Select("elements/id"), map[string]interface{}{"select": "total,count,pageSize,offset,elements/id"}, ""},
+		{"invalid filter", New().Status(Equals), nil, `filter "status": operator "=" needs at least one value`},
+		{"both bounds open", New().CreatedBetween(time.Time{}, time.Time{}), nil, "needs a from or a to date"},
+		{"empty sort field", New().SortBy("", false), nil, "sortBy needs a field"},
+		{"empty select field", New().Select(""), nil, "select needs a field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := tt.query.Params()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(params, tt.want) {
+				t.Errorf("params = %v, want %v", params, tt.want)
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	base := New().Status(Open).Select("id")
+	clone := base.Clone().Type(Equals, 1).Select("subject")
+	if got := len(base.GetFilters()); got != 1 {
+		t.Errorf("base has %d filters after cloning, want 1", got)
+	}
+	if got := len(clone.GetFilters()); got != 2 {
+		t.Errorf("clone has %d filters, want 2", got)
+	}
+	params, err := base.Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "total,count,pageSize,offset,elements/id"; params["select"] != want {
+		t.Errorf("base select = %v, want %s", params["select"], want)
+	}
+}